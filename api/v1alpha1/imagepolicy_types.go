@@ -0,0 +1,170 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionTrustMaterialLoaded reports whether keys, certificates and URLs of the policy could be loaded
+	ConditionTrustMaterialLoaded = "TrustMaterialLoaded"
+
+	ReasonTrustMaterialLoaded     = "Loaded"
+	ReasonTrustMaterialLoadFailed = "LoadFailed"
+)
+
+// NotaryVerification configures verification with the Notary server
+type NotaryVerification struct {
+	// URL of the Notary server
+	URL string `json:"url"`
+}
+
+// CosignVerification configures verification of cosign signatures stored in the image registry
+type CosignVerification struct {
+	// PublicKeys contains PEM-encoded public keys, the signature has to match one of them
+	PublicKeys string `json:"publicKeys"`
+}
+
+// NotationTrustStore contains certificates referenced by the notation trust policy as <type>:<name>
+type NotationTrustStore struct {
+	// +kubebuilder:validation:Enum=ca;signingAuthority
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Certificates contains PEM-encoded certificates
+	Certificates string `json:"certificates"`
+}
+
+// NotationVerification configures verification of Notary Project signatures stored in the image registry
+type NotationVerification struct {
+	// TrustPolicy is a notation trust policy document in JSON format,
+	// the trust policy from the Warden configuration is used when it's empty
+	// +optional
+	TrustPolicy string `json:"trustPolicy,omitempty"`
+	// +optional
+	TrustStores []NotationTrustStore `json:"trustStores,omitempty"`
+}
+
+// ImagePolicySpec defines how images are verified
+type ImagePolicySpec struct {
+	// Images contains glob patterns of images the policy applies to, the policy applies to all images when it's empty.
+	// Images and patterns are normalized, so `nginx` matches `docker.io/library/nginx`.
+	// The `*` matches any sequence of characters except `/`, the `**` matches any sequence of characters
+	// +optional
+	Images []string `json:"images,omitempty"`
+
+	// Verifier used for image verification
+	// +kubebuilder:validation:Enum=notary;cosign;notation
+	Verifier string `json:"verifier"`
+
+	// +optional
+	Notary *NotaryVerification `json:"notary,omitempty"`
+	// +optional
+	Cosign *CosignVerification `json:"cosign,omitempty"`
+	// +optional
+	Notation *NotationVerification `json:"notation,omitempty"`
+
+	// AllowedRegistries contains registries, repositories or patterns of images which are not verified
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// DeniedImages contains registries, repositories, patterns or digests of images which are rejected
+	// without the verification, even if they are signed
+	// +optional
+	DeniedImages []string `json:"deniedImages,omitempty"`
+
+	// Timeout for the connection with the Notary server or the image registry
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// StrictMode defines if images are rejected when the verification service is unavailable,
+	// the namespace or system configuration is used when it's not set
+	// +optional
+	StrictMode *bool `json:"strictMode,omitempty"`
+}
+
+// ClusterImagePolicySpec defines how images are verified in selected namespaces
+type ClusterImagePolicySpec struct {
+	// NamespaceSelector selects namespaces the policy applies to, the policy applies to all namespaces when it's not set.
+	// Images are verified only in namespaces with enabled validation
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	ImagePolicySpec `json:",inline"`
+}
+
+// ImagePolicyStatus defines the observed state of the policy
+type ImagePolicyStatus struct {
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Verifier",type=string,JSONPath=`.spec.verifier`
+//+kubebuilder:printcolumn:name="Loaded",type=string,JSONPath=`.status.conditions[?(@.type=="TrustMaterialLoaded")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ImagePolicy defines how images are verified in its namespace,
+// it takes precedence over ClusterImagePolicy
+type ImagePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ImagePolicySpec   `json:"spec,omitempty"`
+	Status ImagePolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ImagePolicyList contains a list of ImagePolicy
+type ImagePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ImagePolicy `json:"items"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Verifier",type=string,JSONPath=`.spec.verifier`
+//+kubebuilder:printcolumn:name="Loaded",type=string,JSONPath=`.status.conditions[?(@.type=="TrustMaterialLoaded")].status`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterImagePolicy defines how images are verified in selected namespaces
+type ClusterImagePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterImagePolicySpec `json:"spec,omitempty"`
+	Status ImagePolicyStatus      `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterImagePolicyList contains a list of ClusterImagePolicy
+type ClusterImagePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterImagePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ImagePolicy{}, &ImagePolicyList{}, &ClusterImagePolicy{}, &ClusterImagePolicyList{})
+}
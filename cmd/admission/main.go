@@ -8,7 +8,10 @@ import (
 	"os"
 
 	"github.com/kyma-project/warden/internal/env"
+	"github.com/kyma-project/warden/internal/helpers"
 	"github.com/kyma-project/warden/internal/logging"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/policy"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/internal/webhook"
 	"go.uber.org/zap/zapcore"
@@ -17,11 +20,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
 	"github.com/go-logr/zapr"
+	"github.com/kyma-project/warden/api/v1alpha1"
 	"github.com/kyma-project/warden/internal/admission"
 	"github.com/kyma-project/warden/internal/config"
 	"github.com/kyma-project/warden/internal/webhook/certs"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -40,6 +47,9 @@ var (
 func init() {
 	_ = admissionregistrationv1.AddToScheme(scheme)
 	_ = corev1.AddToScheme(scheme)
+	_ = appsv1.AddToScheme(scheme)
+	_ = batchv1.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
@@ -49,7 +59,9 @@ var (
 
 func main() {
 	var configPath string
+	var printConfig bool
 	flag.StringVar(&configPath, "config-path", "./hack/config.yaml", "The path to the configuration file.")
+	flag.BoolVar(&printConfig, "print-config", false, "Print the effective configuration with secrets redacted and exit.")
 	flag.Parse()
 
 	appConfig, err := config.Load(configPath)
@@ -57,6 +69,15 @@ func main() {
 		setupLog.Error(err, fmt.Sprintf("unable to load configuration from path '%s'", configPath))
 		os.Exit(1)
 	}
+	configDump, err := appConfig.Dump()
+	if err != nil {
+		setupLog.Error(err, "unable to print configuration")
+		os.Exit(1)
+	}
+	if printConfig {
+		fmt.Print(configDump)
+		os.Exit(0)
+	}
 
 	atomic := zap.NewAtomicLevel()
 	parsedLevel, err := zapcore.ParseLevel(appConfig.Logging.Level)
@@ -72,15 +93,29 @@ func main() {
 		os.Exit(10)
 	}
 	logger := l.WithContext()
-
-	if err := config.Watch(configPath, logger.Named("config watcher")); err != nil {
-		setupLog.Error(err, "while setup file watcher")
-		os.Exit(2)
-	}
+	logger.Infow("effective configuration", "config", configDump)
 
 	logrZap := zapr.NewLogger(logger.Desugar())
 	ctrl.SetLogger(logrZap)
 
+	if appConfig.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+			ServiceName:   "warden-admission",
+			Endpoint:      appConfig.Tracing.Endpoint,
+			Insecure:      appConfig.Tracing.Insecure,
+			SamplingRatio: appConfig.Tracing.SamplingRatio,
+		})
+		if err != nil {
+			setupLog.Error(err, "while configuring tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				setupLog.Error(err, "while shutting down tracing")
+			}
+		}()
+	}
+
 	deployName := env.Get("ADMISSION_DEPLOYMENT_NAME")
 	addOwnerRef, err := env.GetBool("ADDMISSION_ADD_CERT_OWNER_REF")
 	if err != nil {
@@ -157,25 +192,98 @@ func main() {
 		os.Exit(5)
 	}
 
-	validatorSvc := validate.NewValidatorSvcFactory().NewValidatorSvc(
-		appConfig.Notary.URL, appConfig.Notary.AllowedRegistries, appConfig.Notary.Timeout)
+	var notaryRepoPool *validate.NotaryRepoPool
+	if appConfig.Notary.TrustCache.Enabled {
+		notaryRepoPool = validate.NewNotaryRepoPool(validate.NotaryRepoPoolConfig{
+			MaxSize:         appConfig.Notary.TrustCache.MaxSize,
+			TrustDir:        appConfig.Notary.TrustCache.Dir,
+			RefreshInterval: appConfig.Notary.TrustCache.RefreshInterval,
+		})
+	}
+
+	var verificationCache *validate.VerificationCache
+	if appConfig.Cache.Enabled {
+		verificationCache = validate.NewVerificationCache(validate.CacheConfig{
+			PositiveTTL: appConfig.Cache.PositiveTTL,
+			NegativeTTL: appConfig.Cache.NegativeTTL,
+			TagTTL:      appConfig.Cache.TagTTL,
+			MaxSize:     appConfig.Cache.MaxSize,
+		})
+	}
+
+	systemValidator, userValidatorFactory, err := appConfig.NewValidators(notaryRepoPool, verificationCache)
+	if err != nil {
+		logger.Error("failed to create validators ", err.Error())
+		os.Exit(1)
+	}
+	// validators and settings are replaced when the configuration is reloaded
+	validatorSvc := validate.NewReloadablePodValidator(systemValidator)
+	userValidationSvcFactory := validate.NewReloadableValidatorSvcFactory(userValidatorFactory)
+
+	digestPinning, err := helpers.ParseDigestPinningMode(appConfig.Admission.DigestPinning)
+	if err != nil {
+		logger.Error("invalid admission configuration ", err.Error())
+		os.Exit(1)
+	}
 
 	logger.Info("setting up webhook server")
 	// webhook server setup
 	whs := mgr.GetWebhookServer()
 	decoder := ctrladmission.NewDecoder(mgr.GetScheme())
-	whs.Register(admission.ValidationPath, &ctrlwebhook.Admission{
+	whs.Register(admission.ValidationPath, tracing.NewTracingMiddleware((&ctrlwebhook.Admission{
 		Handler: admission.NewValidationWebhook(logger.With("webhook", "validation"), &decoder),
-	})
+	}).ServeHTTP))
 
-	predefinedUserAllowedRegistries := validate.ParseAllowedRegistries(appConfig.Notary.PredefinedUserAllowedRegistries)
-	whs.Register(admission.DefaultingPath, &ctrlwebhook.Admission{
-		Handler: admission.NewDefaultingWebhook(mgr.GetClient(),
-			mgr.GetAPIReader(),
-			validatorSvc, validate.NewValidatorSvcFactory(predefinedUserAllowedRegistries...),
-			appConfig.Admission.Timeout, appConfig.Admission.StrictMode,
-			&decoder, logger.With("webhook", "defaulting")),
-	})
+	policyResolver := policy.NewResolver(mgr.GetClient(), userValidationSvcFactory)
+	defaultingWebhook := admission.NewDefaultingWebhook(mgr.GetClient(),
+		mgr.GetAPIReader(),
+		validatorSvc, userValidationSvcFactory,
+		appConfig.Admission.Timeout, appConfig.Admission.StrictMode,
+		&decoder, logger.With("webhook", "defaulting")).
+		WithPolicyResolver(policyResolver).
+		WithDigestPinning(digestPinning)
+	whs.Register(admission.DefaultingPath, tracing.NewTracingMiddleware((&ctrlwebhook.Admission{
+		Handler: defaultingWebhook,
+	}).ServeHTTP))
+
+	workloadWebhook := admission.NewWorkloadValidationWebhook(mgr.GetClient(),
+		mgr.GetAPIReader(),
+		validatorSvc, userValidationSvcFactory,
+		appConfig.Admission.Timeout, appConfig.Admission.StrictMode,
+		&decoder, logger.With("webhook", "workloads")).
+		WithPolicyResolver(policyResolver)
+	whs.Register(admission.WorkloadValidationPath, tracing.NewTracingMiddleware((&ctrlwebhook.Admission{
+		Handler: workloadWebhook,
+	}).ServeHTTP))
+
+	if err := config.Watch(configPath, appConfig, func(cfg *config.Config) error {
+		level, err := zapcore.ParseLevel(cfg.Logging.Level)
+		if err != nil {
+			return errors.Wrap(err, "while parsing logger level")
+		}
+		digestPinning, err := helpers.ParseDigestPinningMode(cfg.Admission.DigestPinning)
+		if err != nil {
+			return errors.Wrap(err, "while parsing digest pinning mode")
+		}
+		systemValidator, userValidatorFactory, err := cfg.NewValidators(notaryRepoPool, verificationCache)
+		if err != nil {
+			return err
+		}
+		atomic.SetLevel(level)
+		validatorSvc.Reload(systemValidator)
+		userValidationSvcFactory.Reload(userValidatorFactory)
+		settings := admission.Settings{
+			Timeout:       cfg.Admission.Timeout,
+			StrictMode:    cfg.Admission.StrictMode,
+			DigestPinning: digestPinning,
+		}
+		defaultingWebhook.Reload(settings)
+		workloadWebhook.Reload(settings)
+		return nil
+	}, logger.Named("config watcher")); err != nil {
+		logger.Error("while setup file watcher ", err.Error())
+		os.Exit(2)
+	}
 
 	logger.Info("starting the controller-manager")
 
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -24,7 +25,10 @@ import (
 	"crypto/tls"
 
 	"github.com/go-logr/zapr"
+	"github.com/kyma-project/warden/api/v1alpha1"
 	"github.com/kyma-project/warden/internal/logging"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -32,6 +36,8 @@ import (
 	"github.com/kyma-project/warden/internal/config"
 	"github.com/kyma-project/warden/internal/controllers"
 	"github.com/kyma-project/warden/internal/controllers/namespace"
+	policycontroller "github.com/kyma-project/warden/internal/controllers/policy"
+	"github.com/kyma-project/warden/internal/policy"
 	"github.com/kyma-project/warden/internal/validate"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
@@ -59,11 +65,14 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 }
 
 func main() {
 	var configPath string
+	var printConfig bool
 	flag.StringVar(&configPath, "config-path", "./hack/config.yaml", "The path to the configuration file.")
+	flag.BoolVar(&printConfig, "print-config", false, "Print the effective configuration with secrets redacted and exit.")
 	opts := zapk8s.Options{
 		Development: true,
 	}
@@ -75,6 +84,15 @@ func main() {
 		setupLog.Error(err, fmt.Sprintf("unable to load configuration from path '%s'", configPath))
 		os.Exit(1)
 	}
+	configDump, err := appConfig.Dump()
+	if err != nil {
+		setupLog.Error(err, "unable to print configuration")
+		os.Exit(1)
+	}
+	if printConfig {
+		fmt.Print(configDump)
+		os.Exit(0)
+	}
 
 	atomic := zap.NewAtomicLevel()
 	parsedLevel, err := zapcore.ParseLevel(appConfig.Logging.Level)
@@ -90,14 +108,28 @@ func main() {
 		os.Exit(10)
 	}
 	logger := l.WithContext()
-	if err := config.Watch(configPath, logger.Named("config watcher")); err != nil {
-		setupLog.Error(err, "while setup file watcher")
-		os.Exit(2)
-	}
-
+	logger.Infow("effective configuration", "config", configDump)
 	logrZap := zapr.NewLogger(logger.Desugar())
 	ctrl.SetLogger(logrZap)
 
+	if appConfig.Tracing.Enabled {
+		shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+			ServiceName:   "warden-operator",
+			Endpoint:      appConfig.Tracing.Endpoint,
+			Insecure:      appConfig.Tracing.Insecure,
+			SamplingRatio: appConfig.Tracing.SamplingRatio,
+		})
+		if err != nil {
+			setupLog.Error(err, "while configuring tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				setupLog.Error(err, "while shutting down tracing")
+			}
+		}()
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: ctrlmetrics.Options{
@@ -127,38 +159,79 @@ func main() {
 		os.Exit(1)
 	}
 
-	repoFactory := validate.NotaryRepoFactory{Timeout: appConfig.Notary.Timeout}
-	allowedRegistries := validate.ParseAllowedRegistries(appConfig.Notary.AllowedRegistries)
-	predefinedUserAllowedRegistries := validate.ParseAllowedRegistries(appConfig.Notary.PredefinedUserAllowedRegistries)
+	var notaryRepoPool *validate.NotaryRepoPool
+	if appConfig.Notary.TrustCache.Enabled {
+		notaryRepoPool = validate.NewNotaryRepoPool(validate.NotaryRepoPoolConfig{
+			MaxSize:         appConfig.Notary.TrustCache.MaxSize,
+			TrustDir:        appConfig.Notary.TrustCache.Dir,
+			RefreshInterval: appConfig.Notary.TrustCache.RefreshInterval,
+		})
+	}
 
-	notaryConfig := &validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: appConfig.Notary.URL}, AllowedRegistries: allowedRegistries}
+	var verificationCache *validate.VerificationCache
+	if appConfig.Cache.Enabled {
+		verificationCache = validate.NewVerificationCache(validate.CacheConfig{
+			PositiveTTL: appConfig.Cache.PositiveTTL,
+			NegativeTTL: appConfig.Cache.NegativeTTL,
+			TagTTL:      appConfig.Cache.TagTTL,
+			MaxSize:     appConfig.Cache.MaxSize,
+		})
+	}
 
-	imageValidator := validate.NewImageValidator(notaryConfig, repoFactory)
-	podValidator := validate.NewPodValidator(imageValidator)
+	systemValidator, userValidatorFactory, err := appConfig.NewValidators(notaryRepoPool, verificationCache)
+	if err != nil {
+		logger.Error(err, "unable to create validators")
+		os.Exit(1)
+	}
+	// validators and settings are replaced when the configuration is reloaded
+	podValidator := validate.NewReloadablePodValidator(systemValidator)
+	userValidationSvcFactory := validate.NewReloadableValidatorSvcFactory(userValidatorFactory)
 
-	if err = (controllers.NewPodReconciler(
+	podReconciler := controllers.NewPodReconciler(
 		mgr.GetClient(),
 		mgr.GetAPIReader(),
 		mgr.GetScheme(),
 		podValidator,
-		validate.NewValidatorSvcFactory(predefinedUserAllowedRegistries...),
-		controllers.PodReconcilerConfig{RequeueAfter: appConfig.Operator.PodReconcilerRequeueAfter},
+		userValidationSvcFactory,
+		podReconcilerConfig(appConfig),
 		logger.Named("pod-controller"),
-	)).SetupWithManager(mgr); err != nil {
+	)
+	if err = podReconciler.WithPolicyResolver(policy.NewResolver(mgr.GetClient(), userValidationSvcFactory)).
+		WithEventRecorder(mgr.GetEventRecorderFor("warden-operator")).
+		SetupWithManager(mgr); err != nil {
 		logger.Error(err, "unable to create controller", "controller", "Pod")
 		os.Exit(1)
 	}
 
 	// add namespace controller
 	if err = (&namespace.Reconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    logger.Named("namespace-controller"),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Log:      logger.Named("namespace-controller"),
+		Recorder: mgr.GetEventRecorderFor("warden-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Namespace")
 		os.Exit(1)
 	}
 
+	// add image policy status controllers
+	if err = (&policycontroller.ImagePolicyReconciler{
+		Client:              mgr.GetClient(),
+		ValidatorSvcFactory: userValidationSvcFactory,
+		Log:                 logger.Named("image-policy-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ImagePolicy")
+		os.Exit(1)
+	}
+	if err = (&policycontroller.ClusterImagePolicyReconciler{
+		Client:              mgr.GetClient(),
+		ValidatorSvcFactory: userValidationSvcFactory,
+		Log:                 logger.Named("cluster-image-policy-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterImagePolicy")
+		os.Exit(1)
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		logger.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -168,10 +241,59 @@ func main() {
 		os.Exit(1)
 	}
 
+	ctx := ctrl.SetupSignalHandler()
+	trustConfig := appConfig
+	if err := config.Watch(configPath, appConfig, func(cfg *config.Config) error {
+		level, err := zapcore.ParseLevel(cfg.Logging.Level)
+		if err != nil {
+			return errors.Wrap(err, "while parsing logger level")
+		}
+		systemValidator, userValidatorFactory, err := cfg.NewValidators(notaryRepoPool, verificationCache)
+		if err != nil {
+			return err
+		}
+		atomic.SetLevel(level)
+		podValidator.Reload(systemValidator)
+		userValidationSvcFactory.Reload(userValidatorFactory)
+		podReconciler.Reload(podReconcilerConfig(cfg))
+		if cfg.TrustChanged(trustConfig) {
+			trustConfig = cfg
+			go revalidatePods(ctx, mgr, podReconciler, logger)
+		}
+		return nil
+	}, logger.Named("config watcher")); err != nil {
+		setupLog.Error(err, "while setup file watcher")
+		os.Exit(2)
+	}
+
 	logger.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		logger.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 
 }
+
+func podReconcilerConfig(cfg *config.Config) controllers.PodReconcilerConfig {
+	return controllers.PodReconcilerConfig{
+		RequeueAfter:          cfg.Operator.PodReconcilerRequeueAfter,
+		RevalidationInterval:  cfg.Operator.Revalidation.Interval,
+		RevalidationJitter:    cfg.Operator.Revalidation.Jitter,
+		RevalidationRateLimit: cfg.Operator.Revalidation.RateLimit,
+		RevalidationAction:    cfg.Operator.Revalidation.Action,
+	}
+}
+
+// revalidatePods revalidates all pods after the trust configuration changed, pods are revalidated only by the leader,
+// the replica elected later validates all pods when its controllers start
+func revalidatePods(ctx context.Context, mgr ctrl.Manager, podReconciler *controllers.PodReconciler, logger *zap.SugaredLogger) {
+	select {
+	case <-mgr.Elected():
+	default:
+		return
+	}
+	logger.Info("trust configuration changed, revalidating pods")
+	if err := podReconciler.RevalidateAll(ctx); err != nil {
+		logger.Errorf("while revalidating pods: %s", err.Error())
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/warden/internal/cli"
+	"github.com/kyma-project/warden/internal/config"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/policy"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	exitPassed = 0
+	exitFailed = 1
+	exitError  = 2
+)
+
+// repeatedFlag collects values of the flag given multiple times
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == scanCommand {
+		os.Exit(scan(os.Args[2:]))
+	}
+	os.Exit(verify(os.Args[1:]))
+}
+
+func verify(args []string) int {
+	var configPath, namespace, output string
+	var timeout time.Duration
+	var verbose bool
+	var manifests, annotations repeatedFlag
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	flags.StringVar(&configPath, "config-path", "", "The path to the Warden configuration file used for the system validation.")
+	flags.Var(&annotations, "annotation", "The namespace annotation in the key=value form used for the user validation, can be given multiple times.")
+	flags.Var(&manifests, "f", "The manifest with pods or workloads to verify, '-' reads it from stdin, can be given multiple times.")
+	flags.StringVar(&namespace, "namespace", "default", "The name of the namespace in which the pods are validated.")
+	flags.StringVar(&output, "output", cli.OutputText, "The output format, one of: text, json.")
+	flags.DurationVar(&timeout, "timeout", 5*time.Minute, "The timeout of the whole verification.")
+	flags.BoolVar(&verbose, "verbose", false, "Log the validation details to stderr.")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s [flags] [image...]\n", os.Args[0])
+		fmt.Fprintf(flags.Output(), "       %s %s [flags]\n\n", os.Args[0], scanCommand)
+		fmt.Fprintln(flags.Output(), "Verifies images and manifests like Warden does before the pods are admitted.")
+		fmt.Fprintf(flags.Output(), "The %s command verifies images of all running pods in the cluster.\n", scanCommand)
+		fmt.Fprintln(flags.Output(), "Exits with 1 when any workload would be rejected by the admission and with 2 on errors.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+
+	log, err := newLogger(verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create logger: %s\n", err.Error())
+		return exitError
+	}
+	ctx, cancel := context.WithTimeout(helpers.LoggerToContext(context.Background(), log), timeout)
+	defer cancel()
+
+	if output != cli.OutputText && output != cli.OutputJSON {
+		fmt.Fprintf(os.Stderr, "unsupported output format: %s\n", output)
+		return exitError
+	}
+
+	workloads, err := readWorkloads(manifests, flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	if len(workloads) == 0 {
+		fmt.Fprintln(os.Stderr, "nothing to verify, give images or manifests with the -f flag")
+		return exitError
+	}
+
+	ns, validator, strictMode, err := newValidator(ctx, configPath, namespace, annotations)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+
+	results, err := cli.Verify(ctx, validator, ns, strictMode, workloads)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	if err := cli.Print(os.Stdout, output, results); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	if !cli.Passed(results) {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+func parseErrorExitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitPassed
+	}
+	return exitError
+}
+
+// newLogger returns logger writing to stderr, so it doesn't mix with the printed results
+func newLogger(verbose bool) (*zap.SugaredLogger, error) {
+	if !verbose {
+		return zap.NewNop().Sugar(), nil
+	}
+	zapLogger, err := zap.NewDevelopment()
+	if err != nil {
+		return nil, err
+	}
+	return zapLogger.Sugar(), nil
+}
+
+func readWorkloads(manifests, images []string) ([]cli.Workload, error) {
+	var workloads []cli.Workload
+	if len(images) != 0 {
+		workloads = append(workloads, cli.ImageWorkload(images))
+	}
+	for _, manifest := range manifests {
+		var r io.Reader = os.Stdin
+		if manifest != "-" {
+			file, err := os.Open(manifest)
+			if err != nil {
+				return nil, errors.Wrap(err, "while opening manifest")
+			}
+			defer file.Close()
+			r = file
+		}
+		manifestWorkloads, err := cli.ReadManifest(r)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while reading manifest %s", manifest)
+		}
+		workloads = append(workloads, manifestWorkloads...)
+	}
+	return workloads, nil
+}
+
+// newValidator returns validator of the user namespace when annotations are given, otherwise the system validator
+// of the configuration is used. The strict mode of the admission configuration is returned with the validator
+func newValidator(ctx context.Context, configPath, namespace string, annotations []string) (*corev1.Namespace, validate.PodValidator, bool, error) {
+	if configPath == "" && len(annotations) == 0 {
+		return nil, nil, false, errors.New("either the configuration file or namespace annotations are required")
+	}
+
+	// repositories are kept in memory, so the CLI doesn't leave the notary trust data on the disk
+	notaryRepoPool := validate.NewNotaryRepoPool(validate.NotaryRepoPoolConfig{MaxSize: 100})
+	var systemValidator validate.PodValidator
+	strictMode := false
+	userValidatorFactory := validate.NewValidatorSvcFactoryWithConfig(validate.ValidatorSvcFactoryConfig{
+		NotaryRepoPool: notaryRepoPool,
+	})
+	if configPath != "" {
+		appConfig, err := config.Load(configPath)
+		if err != nil {
+			return nil, nil, false, errors.Wrapf(err, "unable to load configuration from path '%s'", configPath)
+		}
+		systemValidator, userValidatorFactory, err = appConfig.NewValidators(notaryRepoPool, nil)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		strictMode = appConfig.Admission.StrictMode
+	}
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        namespace,
+			Labels:      map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationSystem},
+			Annotations: map[string]string{},
+		},
+	}
+	fallback := func() (validate.PodValidator, error) {
+		return systemValidator, nil
+	}
+	if len(annotations) != 0 {
+		ns.Labels[pkg.NamespaceValidationLabel] = pkg.NamespaceValidationUser
+		for _, annotation := range annotations {
+			key, value, found := strings.Cut(annotation, "=")
+			if !found {
+				return nil, nil, false, errors.Errorf("invalid annotation %s, expected key=value", annotation)
+			}
+			ns.Annotations[key] = value
+		}
+		fallback = func() (validate.PodValidator, error) {
+			return validate.NewUserValidationSvc(ns, userValidatorFactory)
+		}
+	}
+
+	validator, err := policy.NewPodValidator(ctx, nil, ns, fallback)
+	if err != nil {
+		return nil, nil, false, errors.Wrap(err, "while creating validator")
+	}
+	return ns, validator, strictMode, nil
+}
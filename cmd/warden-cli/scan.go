@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/cli"
+	"github.com/kyma-project/warden/internal/config"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/policy"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	scanCommand = "scan"
+	// scanCacheSize bounds the number of verification results kept during the scan
+	scanCacheSize = 10000
+)
+
+func scan(args []string) int {
+	var configPath, kubeconfig, output string
+	var timeout time.Duration
+	var verbose bool
+	flags := flag.NewFlagSet(fmt.Sprintf("%s %s", os.Args[0], scanCommand), flag.ContinueOnError)
+	flags.StringVar(&configPath, "config-path", "", "The path to the Warden configuration file used for the system validation.")
+	flags.StringVar(&kubeconfig, "kubeconfig", "", "The path to the kubeconfig file, KUBECONFIG or the in-cluster configuration is used when it's empty.")
+	flags.StringVar(&output, "output", cli.OutputJSON, "The report format, one of: json, csv, markdown.")
+	flags.DurationVar(&timeout, "timeout", 30*time.Minute, "The timeout of the whole scan.")
+	flags.BoolVar(&verbose, "verbose", false, "Log the validation details to stderr.")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s [flags]\n\n", os.Args[0], scanCommand)
+		fmt.Fprintln(flags.Output(), "Verifies images of all running pods in namespaces with enabled Warden validation and prints the compliance report.")
+		fmt.Fprintln(flags.Output(), "Exits with 1 when any image fails the verification and with 2 on errors.")
+		fmt.Fprintln(flags.Output())
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return parseErrorExitCode(err)
+	}
+	if output != cli.OutputJSON && output != cli.OutputCSV && output != cli.OutputMarkdown {
+		fmt.Fprintf(os.Stderr, "unsupported output format: %s\n", output)
+		return exitError
+	}
+	if configPath == "" {
+		fmt.Fprintln(os.Stderr, "the configuration file is required to validate namespaces with the system validation")
+		return exitError
+	}
+
+	log, err := newLogger(verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to create logger: %s\n", err.Error())
+		return exitError
+	}
+	ctx, cancel := context.WithTimeout(helpers.LoggerToContext(context.Background(), log), timeout)
+	defer cancel()
+
+	scanner, err := newScanner(configPath, kubeconfig, timeout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	report, err := scanner.Scan(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	if err := report.Write(os.Stdout, output); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitError
+	}
+	if !report.Passed() {
+		return exitFailed
+	}
+	return exitPassed
+}
+
+func newScanner(configPath, kubeconfig string, timeout time.Duration) (*cli.Scanner, error) {
+	appConfig, err := config.Load(configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to load configuration from path '%s'", configPath)
+	}
+
+	restConfig, err := newRestConfig(kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get kubeconfig")
+	}
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		corev1.AddToScheme, appsv1.AddToScheme, batchv1.AddToScheme, v1alpha1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			return nil, err
+		}
+	}
+	client, err := k8sclient.New(restConfig, k8sclient.Options{Scheme: scheme})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create client")
+	}
+
+	// results are kept for the whole scan, so images with the same digest are verified only once
+	verificationCache := validate.NewVerificationCache(validate.CacheConfig{
+		PositiveTTL: timeout,
+		NegativeTTL: timeout,
+		TagTTL:      timeout,
+		MaxSize:     scanCacheSize,
+	})
+	notaryRepoPool := validate.NewNotaryRepoPool(validate.NotaryRepoPoolConfig{MaxSize: 100})
+	systemValidator, userValidatorFactory, err := appConfig.NewValidators(notaryRepoPool, verificationCache)
+	if err != nil {
+		return nil, err
+	}
+	return cli.NewScanner(client, systemValidator, userValidatorFactory).
+		WithPolicyResolver(policy.NewResolver(client, userValidatorFactory)), nil
+}
+
+func newRestConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		return ctrl.GetConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
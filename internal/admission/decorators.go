@@ -3,6 +3,7 @@ package admission
 import (
 	"context"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	"go.uber.org/zap"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 	"time"
@@ -15,6 +16,9 @@ func HandleWithLogger(baseLogger *zap.SugaredLogger, handler Handler) Handler {
 		loggerWithReqId := baseLogger.With("req-id", req.UID).
 			With("namespace", req.Namespace).
 			With("name", req.Name)
+		for key, val := range tracing.GetMetadata(ctx) {
+			loggerWithReqId = loggerWithReqId.With(key, val)
+		}
 		ctxLogger := helpers.LoggerToContext(ctx, loggerWithReqId)
 
 		resp := handler(ctxLogger, req)
@@ -55,6 +59,7 @@ func HandleWithTimeout(timeout time.Duration, handler Handler, timeoutHandler Ti
 		case <-done:
 		case <-ctxTimeout.Done():
 			if err := ctxTimeout.Err(); err != nil {
+				admissionTimeouts.WithLabelValues(string(req.Operation)).Inc()
 				return timeoutHandler(ctxTimeout, err, req)
 			}
 		}
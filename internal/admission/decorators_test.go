@@ -0,0 +1,34 @@
+package admission
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestHandleWithTimeout(t *testing.T) {
+	//GIVEN
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: admissionv1.Connect}}
+	slowHandler := func(ctx context.Context, _ admission.Request) admission.Response {
+		<-ctx.Done()
+		return admission.Allowed("")
+	}
+	timeoutHandler := func(_ context.Context, err error, _ admission.Request) admission.Response {
+		return admission.Errored(http.StatusRequestTimeout, err)
+	}
+	timeoutsBefore := testutil.ToFloat64(admissionTimeouts.WithLabelValues(string(admissionv1.Connect)))
+
+	//WHEN
+	resp := HandleWithTimeout(10*time.Millisecond, slowHandler, timeoutHandler)(context.TODO(), req)
+
+	//THEN
+	require.False(t, resp.Allowed)
+	require.Equal(t, int32(http.StatusRequestTimeout), resp.Result.Code)
+	require.Equal(t, timeoutsBefore+1, testutil.ToFloat64(admissionTimeouts.WithLabelValues(string(admissionv1.Connect))))
+}
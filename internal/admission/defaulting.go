@@ -10,6 +10,7 @@ import (
 
 	"github.com/kyma-project/warden/internal/annotations"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/policy"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
@@ -27,15 +28,18 @@ const (
 
 const PodType = "Pod"
 
+// EphemeralContainersSubResource is used by `kubectl debug` to add ephemeral containers to the running pod
+const EphemeralContainersSubResource = "ephemeralcontainers"
+
 type DefaultingWebHook struct {
 	systemValidator          validate.PodValidator
 	userValidationSvcFactory validate.ValidatorSvcFactory
-	timeout                  time.Duration
 	client                   k8sclient.Client
 	reader                   k8sclient.Reader
 	decoder                  *admission.Decoder
 	baseLogger               *zap.SugaredLogger
-	strictMode               bool
+	policyResolver           policy.Resolver
+	settings                 reloadableSettings
 }
 
 func NewDefaultingWebhook(client k8sclient.Client, reader k8sclient.Reader,
@@ -48,16 +52,38 @@ func NewDefaultingWebhook(client k8sclient.Client, reader k8sclient.Reader,
 		systemValidator:          systemValidator,
 		userValidationSvcFactory: userValidationSvcFactory,
 		baseLogger:               logger,
-		timeout:                  timeout,
-		strictMode:               strictMode,
 		decoder:                  decoder,
+		settings: reloadableSettings{settings: Settings{
+			Timeout:       timeout,
+			StrictMode:    strictMode,
+			DigestPinning: pkg.DigestPinningDisabled,
+		}},
 	}
 }
 
+// WithPolicyResolver enables validation with ImagePolicies and ClusterImagePolicies
+func (w *DefaultingWebHook) WithPolicyResolver(resolver policy.Resolver) *DefaultingWebHook {
+	w.policyResolver = resolver
+	return w
+}
+
+// WithDigestPinning sets the digest pinning mode used in namespaces without the digest pinning annotation
+func (w *DefaultingWebHook) WithDigestPinning(mode string) *DefaultingWebHook {
+	settings := w.settings.get()
+	settings.DigestPinning = mode
+	w.settings.set(settings)
+	return w
+}
+
+// Reload replaces the settings used by the next admission requests
+func (w *DefaultingWebHook) Reload(settings Settings) {
+	w.settings.set(settings)
+}
+
 func (w *DefaultingWebHook) Handle(ctx context.Context, req admission.Request) admission.Response {
 	return HandleWithLogger(w.baseLogger,
 		HandlerWithTimeMeasure(
-			HandleWithTimeout(w.timeout, w.handle, w.handleTimeout)))(ctx, req)
+			HandleWithTimeout(w.settings.get().Timeout, w.handle, w.handleTimeout)))(ctx, req)
 }
 
 func (w *DefaultingWebHook) handle(ctx context.Context, req admission.Request) admission.Response {
@@ -79,18 +105,19 @@ func (w *DefaultingWebHook) handle(ctx context.Context, req admission.Request) a
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	if !isValidationNeeded(ctx, pod, ns, req.Operation) {
+	if !isValidationNeeded(ctx, pod, ns, req) {
 		result := cleanAnnotationIfNeeded(ctx, pod, ns, req)
 		return result
 	}
 
-	validator := w.systemValidator
-	if validate.IsUserValidationForNS(ns) {
-		var err error
-		validator, err = validate.NewUserValidationSvc(ns, w.userValidationSvcFactory)
-		if err != nil {
-			return admission.Errored(http.StatusInternalServerError, err)
+	validator, err := policy.NewPodValidator(ctx, w.policyResolver, ns, func() (validate.PodValidator, error) {
+		if validate.IsUserValidationForNS(ns) {
+			return validate.NewUserValidationSvc(ns, w.userValidationSvcFactory)
 		}
+		return w.systemValidator, nil
+	})
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
 	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, w.reader, pod)
@@ -123,13 +150,13 @@ func cleanAnnotationIfNeeded(ctx context.Context, pod *corev1.Pod, ns *corev1.Na
 	return admission.Allowed("validation is not needed for pod")
 }
 
-func (w DefaultingWebHook) handleTimeout(ctx context.Context, timeoutErr error, req admission.Request) admission.Response {
+func (w *DefaultingWebHook) handleTimeout(ctx context.Context, timeoutErr error, req admission.Request) admission.Response {
 	pod := &corev1.Pod{}
 	if err := (*w.decoder).Decode(req, pod); err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
-	msg := fmt.Sprintf("request exceeded desired timeout: %s, reason: %s", w.timeout.String(), timeoutErr.Error())
+	msg := fmt.Sprintf("request exceeded desired timeout: %s, reason: %s", w.settings.get().Timeout.String(), timeoutErr.Error())
 	logger := helpers.LoggerFromCtx(ctx)
 	logger.Info(msg)
 
@@ -139,6 +166,10 @@ func (w DefaultingWebHook) handleTimeout(ctx context.Context, timeoutErr error,
 	}
 
 	res := w.createResponse(ctx, req, validate.ValidationResult{Status: validate.ServiceUnavailable}, pod, ns, logger)
+	if !res.Allowed {
+		res.Result.Message = msg
+		return res
+	}
 	res.Result = &metav1.Status{Message: msg}
 	return res
 }
@@ -147,32 +178,50 @@ func (w *DefaultingWebHook) createResponse(ctx context.Context,
 	req admission.Request, result validate.ValidationResult,
 	pod *corev1.Pod, ns *corev1.Namespace, logger *zap.SugaredLogger) admission.Response {
 
-	strictMode := w.strictMode
-	if validate.IsUserValidationForNS(ns) {
-		var err error
-		strictMode, err = helpers.GetUserValidationStrictMode(ns)
+	settings := w.settings.get()
+	strictMode, err := validate.StrictModeForNS(ns, settings.StrictMode, result)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	auditMode, err := helpers.IsAuditEnforcementMode(ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if isEphemeralContainersRequest(req) {
+		return ephemeralContainersResponse(ctx, result, strictMode, auditMode)
+	}
+
+	markedPod := markPod(ctx, result, pod, strictMode, auditMode)
+	if result.Status == validate.Valid {
+		digestPinning, err := helpers.GetDigestPinningMode(ns, settings.DigestPinning)
 		if err != nil {
 			return admission.Errored(http.StatusInternalServerError, err)
 		}
+		markedPod = pinPodImages(ctx, markedPod, result.Images, digestPinning)
 	}
-
-	markedPod := markPod(ctx, result, pod, strictMode)
 	fBytes, err := json.Marshal(markedPod)
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
 	logger.Infow("pod was validated", "result", result)
-	return admission.PatchResponseFromRaw(req.Object.Raw, fBytes)
+	return admission.PatchResponseFromRaw(req.Object.Raw, fBytes).
+		WithWarnings(responseWarnings(result, strictMode, auditMode)...)
 }
 
-func isValidationNeeded(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, operation admissionv1.Operation) bool {
+func isValidationNeeded(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, req admission.Request) bool {
 	logger := helpers.LoggerFromCtx(ctx)
 	if enabled := validate.IsValidationEnabledForNS(ns); !enabled {
 		logger.Debugw("pod validation skipped because validation for namespace is not enabled")
 		return false
 	}
-	if needed := IsValidationNeededForOperation(operation); needed {
+	if needed := IsValidationNeededForOperation(req.Operation); needed {
+		return true
+	}
+	// ephemeral containers have to be validated even if the pod itself was already marked
+	if isEphemeralContainersRequest(req) {
 		return true
 	}
 	if enabled := isValidationEnabledForPodValidationLabel(pod); !enabled {
@@ -186,6 +235,10 @@ func IsValidationNeededForOperation(operation admissionv1.Operation) bool {
 	return operation == admissionv1.Create
 }
 
+func isEphemeralContainersRequest(req admission.Request) bool {
+	return req.SubResource == EphemeralContainersSubResource
+}
+
 func isValidationEnabledForPodValidationLabel(pod *corev1.Pod) bool {
 	validationLabelValue := getPodValidationLabelValue(pod)
 	if validationLabelValue == pkg.ValidationStatusFailed || validationLabelValue == pkg.ValidationStatusPending {
@@ -205,8 +258,12 @@ func getPodValidationLabelValue(pod *corev1.Pod) string {
 	return validationLabelValue
 }
 
-func markPod(ctx context.Context, result validate.ValidationResult, pod *corev1.Pod, strictMode bool) *corev1.Pod {
+func markPod(ctx context.Context, result validate.ValidationResult, pod *corev1.Pod, strictMode, auditMode bool) *corev1.Pod {
 	label, annotation := podMarkersForValidationResult(result.Status, strictMode)
+	if auditMode && annotation == annotations.ValidationReject {
+		helpers.LoggerFromCtx(ctx).Info("pod would be rejected, but it's admitted in the audit mode")
+		annotation = ""
+	}
 	helpers.LoggerFromCtx(ctx).Infof("pod was labeled: `%s` and annotated: `%s`", label, annotation)
 	if label == "" && annotation == "" {
 		return pod
@@ -234,6 +291,58 @@ func markPod(ctx context.Context, result validate.ValidationResult, pod *corev1.
 	return markedPod
 }
 
+// ephemeralContainersResponse denies the request instead of marking the pod,
+// because pod labels and annotations can't be changed through the ephemeralcontainers subresource
+func ephemeralContainersResponse(ctx context.Context, result validate.ValidationResult, strictMode, auditMode bool) admission.Response {
+	_, annotation := podMarkersForValidationResult(result.Status, strictMode)
+	helpers.LoggerFromCtx(ctx).Infow("ephemeral containers were validated", "result", result)
+	if annotation != annotations.ValidationReject {
+		return admission.Allowed("ephemeral containers validation passed").
+			WithWarnings(responseWarnings(result, strictMode, auditMode)...)
+	}
+	if auditMode {
+		return admission.Allowed("ephemeral containers are admitted in the audit mode").
+			WithWarnings(responseWarnings(result, strictMode, auditMode)...)
+	}
+	if len(result.InvalidImages) != 0 {
+		return admission.Denied(fmt.Sprintf("Pod images %s validation failed", strings.Join(result.InvalidImages, ", ")))
+	}
+	return admission.Denied("Pod images validation failed")
+}
+
+// responseWarnings returns warnings printed by kubectl for admitted pods,
+// rejected pods get only the rejection message
+func responseWarnings(result validate.ValidationResult, strictMode, auditMode bool) []string {
+	_, annotation := podMarkersForValidationResult(result.Status, strictMode)
+	if annotation != annotations.ValidationReject {
+		return result.Warnings
+	}
+	if !auditMode {
+		return nil
+	}
+	return append(auditWarnings(result, strictMode), result.Warnings...)
+}
+
+// auditWarnings returns warnings listing images which would be rejected if the namespace wasn't in the audit mode
+func auditWarnings(result validate.ValidationResult, strictMode bool) []string {
+	_, annotation := podMarkersForValidationResult(result.Status, strictMode)
+	if annotation != annotations.ValidationReject {
+		return nil
+	}
+	reason := "image validation failed"
+	if result.Status == validate.ServiceUnavailable {
+		reason = "image verification service is unavailable"
+	}
+	if len(result.InvalidImages) == 0 {
+		return []string{fmt.Sprintf("audit mode: images would be rejected: %s", reason)}
+	}
+	warnings := make([]string, 0, len(result.InvalidImages))
+	for _, image := range result.InvalidImages {
+		warnings = append(warnings, fmt.Sprintf("audit mode: image %s would be rejected: %s", image, reason))
+	}
+	return warnings
+}
+
 func podMarkersForValidationResult(result validate.ValidationStatus, strictMode bool) (label string, annotation string) {
 	switch result {
 	case validate.NoAction:
@@ -244,7 +353,7 @@ func podMarkersForValidationResult(result validate.ValidationStatus, strictMode
 		return pkg.ValidationStatusSuccess, ""
 	case validate.ServiceUnavailable:
 		annotation = ""
-		if strictMode {
+		if validate.IsRejected(result, strictMode) {
 			annotation = annotations.ValidationReject
 		}
 		return pkg.ValidationStatusPending, annotation
@@ -247,6 +247,83 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		require.ElementsMatch(t, withAddRejectAndImagesAnnotation(patchWithAddLabel(pkg.ValidationStatusFailed)), res.Patches)
 	})
 
+	t.Run("when invalid image in audit mode should return failed without annotation reject and with warnings", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels:      map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled},
+			Annotations: map[string]string{pkg.NamespaceEnforcementModeAnnotation: pkg.EnforcementModeAudit}}}
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("validation failed")))
+		mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, false, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		mockImageValidator.AssertNumberOfCalls(t, "Validate", 1)
+		require.True(t, res.AdmissionResponse.Allowed)
+		require.ElementsMatch(t, patchWithAddLabel(pkg.ValidationStatusFailed), res.Patches)
+		require.Equal(t, []string{"audit mode: image test:test would be rejected: image validation failed"}, res.Warnings)
+	})
+
+	t.Run("when service unavailable and strict mode on in audit mode should return pending with warnings", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels:      map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled},
+			Annotations: map[string]string{pkg.NamespaceEnforcementModeAnnotation: pkg.EnforcementModeAudit}}}
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).
+			Return(pkg.NewUnknownResultErr(errors.New("notary is not available")))
+		mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, StrictModeOn, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, res.AdmissionResponse.Allowed)
+		require.ElementsMatch(t, patchWithAddLabel(pkg.ValidationStatusPending), res.Patches)
+		require.Equal(t, []string{
+			"audit mode: image test:test would be rejected: image verification service is unavailable",
+			"image test:test: verification is pending: notary service unknown error: notary is not available",
+		}, []string(res.Warnings))
+	})
+
+	t.Run("when enforcement mode is not supported should return error", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels:      map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled},
+			Annotations: map[string]string{pkg.NamespaceEnforcementModeAnnotation: "dry-run"}}}
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).Return(nil)
+		mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, false, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.False(t, res.AdmissionResponse.Allowed)
+		require.Equal(t, int32(http.StatusInternalServerError), res.Result.Code)
+	})
+
 	t.Run("when service unavailable and strict mode on should return pending and annotation reject", func(t *testing.T) {
 		//GIVEN
 		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
@@ -297,6 +374,84 @@ func TestFlow_OutputStatuses_ForPodValidationResult(t *testing.T) {
 		require.ElementsMatch(t, patchWithAddLabel(pkg.ValidationStatusPending), res.Patches)
 	})
 
+	t.Run("when service unavailable and strict mode off should return warnings", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+		mockPodValidator := mocks.NewPodValidator(t)
+		mockPodValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validate.ValidationResult{
+				Status:        validate.ServiceUnavailable,
+				InvalidImages: []string{"test:test"},
+				Warnings:      []string{"image test:test: verification is pending: notary is not available"},
+			}, nil)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, res.AdmissionResponse.Allowed)
+		require.Equal(t, []string{"image test:test: verification is pending: notary is not available"}, []string(res.Warnings))
+	})
+
+	t.Run("when service unavailable and strict mode on should not return warnings", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+		mockPodValidator := mocks.NewPodValidator(t)
+		mockPodValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validate.ValidationResult{
+				Status:        validate.ServiceUnavailable,
+				InvalidImages: []string{"test:test"},
+				Warnings:      []string{"image test:test: verification is pending: notary is not available"},
+			}, nil)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, StrictModeOn, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, res.AdmissionResponse.Allowed)
+		require.Empty(t, res.Warnings)
+	})
+
+	t.Run("when deprecated manifest hash was used should return success with warnings", func(t *testing.T) {
+		//GIVEN
+		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+			Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).
+			Run(func(args mock.Arguments) {
+				validate.AddWarning(args.Get(0).(context.Context), "deprecated: manifest hash was used for verification")
+			}).Return(nil)
+		mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+		pod := newPodFix(nsName, nil)
+		req := newRequestFix(t, pod, admissionv1.Create)
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+		webhook := NewDefaultingWebhook(client, client,
+			mockPodValidator, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.True(t, res.AdmissionResponse.Allowed)
+		require.ElementsMatch(t, patchWithAddSuccessLabel(), res.Patches)
+		require.Equal(t, []string{"image test:test: deprecated: manifest hash was used for verification"}, []string(res.Warnings))
+	})
+
 	t.Run("when service unavailable and strict mode on for user validation should return pending and annotation reject", func(t *testing.T) {
 		//GIVEN
 		ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
@@ -1004,6 +1159,103 @@ func TestHandleTimeout(t *testing.T) {
 	}
 }
 
+func TestFlow_EphemeralContainers(t *testing.T) {
+	//GIVEN
+	logger := test_helpers.NewTestZapLogger(t)
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	decoder := admission.NewDecoder(scheme)
+	timeout := time.Second
+
+	nsName := "test-namespace"
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+
+	tests := []struct {
+		name             string
+		strictMode       bool
+		auditMode        bool
+		inputLabels      map[string]string
+		validationErr    error
+		expectedAllowed  bool
+		expectedMessage  string
+		expectedWarnings []string
+	}{
+		{
+			name:            "signed ephemeral container should be allowed",
+			inputLabels:     map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusSuccess},
+			expectedAllowed: true,
+		},
+		{
+			name:            "unsigned ephemeral container should be denied",
+			inputLabels:     map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusSuccess},
+			validationErr:   pkg.NewValidationFailedErr(errors.New("validation failed")),
+			expectedMessage: "Pod images debug:unsigned validation failed",
+		},
+		{
+			name:            "unsigned ephemeral container should be denied for pending pod",
+			inputLabels:     map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusPending},
+			validationErr:   pkg.NewValidationFailedErr(errors.New("validation failed")),
+			expectedMessage: "Pod images debug:unsigned validation failed",
+		},
+		{
+			name:             "ephemeral container should be allowed with warnings when service is unavailable and strict mode is off",
+			validationErr:    pkg.NewUnknownResultErr(errors.New("notary is not available")),
+			expectedAllowed:  true,
+			expectedWarnings: []string{"image debug:unsigned: verification is pending: notary service unknown error: notary is not available"},
+		},
+		{
+			name:            "ephemeral container should be denied when service is unavailable and strict mode is on",
+			strictMode:      StrictModeOn,
+			validationErr:   pkg.NewUnknownResultErr(errors.New("notary is not available")),
+			expectedMessage: "Pod images debug:unsigned validation failed",
+		},
+		{
+			name:             "unsigned ephemeral container should be allowed with warnings in audit mode",
+			auditMode:        true,
+			inputLabels:      map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusSuccess},
+			validationErr:    pkg.NewValidationFailedErr(errors.New("validation failed")),
+			expectedAllowed:  true,
+			expectedWarnings: []string{"audit mode: image debug:unsigned would be rejected: image validation failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			mockImageValidator := mocks.ImageValidatorService{}
+			mockImageValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).Return(nil)
+			mockImageValidator.Mock.On("Validate", mock.Anything, "debug:unsigned", mock.Anything).Return(tt.validationErr)
+			mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+			pod := newPodFix(nsName, tt.inputLabels)
+			pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{
+				{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger", Image: "debug:unsigned"}},
+			}
+			req := newRequestFix(t, pod, admissionv1.Update)
+			req.SubResource = EphemeralContainersSubResource
+			ns := ns.DeepCopy()
+			if tt.auditMode {
+				ns.Annotations = map[string]string{pkg.NamespaceEnforcementModeAnnotation: pkg.EnforcementModeAudit}
+			}
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ns).Build()
+			webhook := NewDefaultingWebhook(client, client,
+				mockPodValidator, nil, timeout, tt.strictMode, &decoder, logger.Sugar())
+
+			//WHEN
+			res := webhook.Handle(context.TODO(), req)
+
+			//THEN
+			mockImageValidator.AssertNumberOfCalls(t, "Validate", 2)
+			require.Equal(t, tt.expectedAllowed, res.Allowed)
+			require.Empty(t, res.Patches)
+			if tt.expectedMessage != "" {
+				require.Equal(t, tt.expectedMessage, res.Result.Message)
+			}
+			require.Equal(t, tt.expectedWarnings, []string(res.Warnings))
+		})
+	}
+}
+
 func setupValidatorMock() *mocks.ImageValidatorService {
 	mockValidator := mocks.ImageValidatorService{}
 	mockValidator.Mock.On("Validate", mock.Anything, "test:test", mock.Anything).
@@ -1023,6 +1275,186 @@ func newRequestFix(t *testing.T, pod corev1.Pod, operation admissionv1.Operation
 	return req
 }
 
+func TestFlow_DigestPinning(t *testing.T) {
+	logger := zap.NewNop()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	decoder := admission.NewDecoder(scheme)
+	timeout := time.Second
+
+	nsName := "test-namespace"
+	digest := "sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270"
+	image := "registry.io/app:1.0"
+
+	tests := []struct {
+		name              string
+		clusterMode       string
+		namespaceMode     string
+		verdict           validate.ImageVerdict
+		status            validate.ValidationStatus
+		expectedImage     string
+		expectedErrorCode int32
+	}{
+		{
+			name:          "pinning is disabled by default",
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierCosign},
+			status:        validate.Valid,
+			expectedImage: image,
+		},
+		{
+			name:          "cluster mode replaces tag with digest",
+			clusterMode:   pkg.DigestPinningDigest,
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierCosign},
+			status:        validate.Valid,
+			expectedImage: "registry.io/app@" + digest,
+		},
+		{
+			name:          "namespace mode overrides cluster mode",
+			clusterMode:   pkg.DigestPinningDigest,
+			namespaceMode: pkg.DigestPinningTagDigest,
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierCosign},
+			status:        validate.Valid,
+			expectedImage: image + "@" + digest,
+		},
+		{
+			name:          "namespace mode disables pinning",
+			clusterMode:   pkg.DigestPinningDigest,
+			namespaceMode: pkg.DigestPinningDisabled,
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierCosign},
+			status:        validate.Valid,
+			expectedImage: image,
+		},
+		{
+			name:          "tag of image verified with notary is kept",
+			clusterMode:   pkg.DigestPinningDigest,
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierNotary},
+			status:        validate.Valid,
+			expectedImage: image + "@" + digest,
+		},
+		{
+			name:          "allowed image is not pinned",
+			clusterMode:   pkg.DigestPinningDigest,
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonAllowed, Digest: digest, Verifier: pkg.VerifierNotation},
+			status:        validate.Valid,
+			expectedImage: image,
+		},
+		{
+			name:          "pod with pending validation is not pinned",
+			clusterMode:   pkg.DigestPinningDigest,
+			verdict:       validate.ImageVerdict{Image: image, Status: validate.ServiceUnavailable, Reason: validate.ReasonNotaryUnavailable, Verifier: pkg.VerifierCosign},
+			status:        validate.ServiceUnavailable,
+			expectedImage: image,
+		},
+		{
+			name:              "invalid namespace mode",
+			namespaceMode:     "always",
+			verdict:           validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierCosign},
+			status:            validate.Valid,
+			expectedErrorCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+				Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+			if tt.namespaceMode != "" {
+				ns.Annotations = map[string]string{pkg.NamespaceDigestPinningAnnotation: tt.namespaceMode}
+			}
+			validationSvc := mocks.NewPodValidator(t)
+			validationSvc.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(validate.ValidationResult{Status: tt.status, Images: []validate.ImageVerdict{tt.verdict}}, nil).Once()
+			pod := newPodFix(nsName, nil)
+			pod.Spec.Containers[0].Image = image
+			req := newRequestFix(t, pod, admissionv1.Create)
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&ns).Build()
+			webhook := NewDefaultingWebhook(client, client,
+				validationSvc, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+			if tt.clusterMode != "" {
+				webhook = webhook.WithDigestPinning(tt.clusterMode)
+			}
+
+			//WHEN
+			res := webhook.Handle(context.TODO(), req)
+
+			//THEN
+			require.NotNil(t, res)
+			if tt.expectedErrorCode != 0 {
+				require.False(t, res.Allowed)
+				require.Equal(t, tt.expectedErrorCode, res.Result.Code)
+				return
+			}
+			require.True(t, res.Allowed)
+			imagePatches := []jsonpatch.JsonPatchOperation{}
+			for _, patch := range res.Patches {
+				if patch.Path == "/spec/containers/0/image" {
+					imagePatches = append(imagePatches, patch)
+				}
+			}
+			if tt.expectedImage == image {
+				require.Empty(t, imagePatches)
+				return
+			}
+			require.Equal(t, []jsonpatch.JsonPatchOperation{
+				{Operation: "replace", Path: "/spec/containers/0/image", Value: tt.expectedImage},
+			}, imagePatches)
+		})
+	}
+}
+
+func Test_pinnedImage(t *testing.T) {
+	digest := "sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270"
+	tests := []struct {
+		name     string
+		image    string
+		keepTag  bool
+		want     string
+		wantPinn bool
+	}{
+		{name: "tag is replaced", image: "registry.io/app:1.0", want: "registry.io/app@" + digest, wantPinn: true},
+		{name: "tag is kept", image: "registry.io/app:1.0", keepTag: true, want: "registry.io/app:1.0@" + digest, wantPinn: true},
+		{name: "implicit latest tag", image: "nginx", want: "index.docker.io/library/nginx@" + digest, wantPinn: true},
+		{name: "implicit latest tag is kept", image: "nginx", keepTag: true, want: "index.docker.io/library/nginx:latest@" + digest, wantPinn: true},
+		{name: "registry with port", image: "localhost:5000/app", want: "localhost:5000/app@" + digest, wantPinn: true},
+		{name: "image already pinned", image: "registry.io/app@" + digest},
+		{name: "invalid image", image: "Invalid::image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, pinned := pinnedImage(tt.image, digest, tt.keepTag)
+			require.Equal(t, tt.wantPinn, pinned)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_pinPodImages(t *testing.T) {
+	digest := "sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270"
+	image := "registry.io/app:1.0"
+	pinned := "registry.io/app@" + digest
+
+	//GIVEN
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		Containers:     []corev1.Container{{Name: "app", Image: image}},
+		InitContainers: []corev1.Container{{Name: "init", Image: image}},
+		EphemeralContainers: []corev1.EphemeralContainer{{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug", Image: image},
+		}},
+	}}
+	verdicts := []validate.ImageVerdict{
+		{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified, Digest: digest, Verifier: pkg.VerifierCosign},
+	}
+
+	//WHEN
+	pinnedPod := pinPodImages(context.TODO(), pod, verdicts, pkg.DigestPinningDigest)
+
+	//THEN
+	require.Equal(t, pinned, pinnedPod.Spec.Containers[0].Image)
+	require.Equal(t, pinned, pinnedPod.Spec.InitContainers[0].Image)
+	require.Equal(t, pinned, pinnedPod.Spec.EphemeralContainers[0].Image)
+	require.Equal(t, image, pod.Spec.EphemeralContainers[0].Image)
+}
+
 func newPodFix(nsName string, labels map[string]string) corev1.Pod {
 	pod := corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,20 @@
+package admission
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	admissionTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "warden_admission_timeouts_total",
+			Help: "Number of admission requests which weren't handled before the timeout, partitioned by operation.",
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(admissionTimeouts)
+}
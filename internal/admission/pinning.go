@@ -0,0 +1,73 @@
+package admission
+
+import (
+	"context"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// pinPodImages returns copy of the pod with images of all containers pinned to their verified digests,
+// so the kubelet pulls exactly the images which were verified
+func pinPodImages(ctx context.Context, pod *corev1.Pod, verdicts []validate.ImageVerdict, mode string) *corev1.Pod {
+	if mode == pkg.DigestPinningDisabled {
+		return pod
+	}
+	pinnedImages := map[string]string{}
+	for _, verdict := range verdicts {
+		if verdict.Status != validate.Valid || verdict.Reason != validate.ReasonVerified || verdict.Digest == "" {
+			continue
+		}
+		// notary trust data is stored per tag, so the tag is needed to verify the pinned image again
+		keepTag := mode == pkg.DigestPinningTagDigest || verdict.Verifier == pkg.VerifierNotary
+		if pinned, ok := pinnedImage(verdict.Image, verdict.Digest, keepTag); ok {
+			pinnedImages[verdict.Image] = pinned
+		}
+	}
+	if len(pinnedImages) == 0 {
+		return pod
+	}
+
+	logger := helpers.LoggerFromCtx(ctx)
+	pinnedPod := pod.DeepCopy()
+	pin := func(containers []corev1.Container) {
+		for i := range containers {
+			if pinned, ok := pinnedImages[containers[i].Image]; ok {
+				logger.Infow("image pinned to the verified digest", "image", containers[i].Image, "pinned-image", pinned)
+				containers[i].Image = pinned
+			}
+		}
+	}
+	pin(pinnedPod.Spec.Containers)
+	pin(pinnedPod.Spec.InitContainers)
+	for i := range pinnedPod.Spec.EphemeralContainers {
+		container := &pinnedPod.Spec.EphemeralContainers[i]
+		if pinned, ok := pinnedImages[container.Image]; ok {
+			logger.Infow("image pinned to the verified digest", "image", container.Image, "pinned-image", pinned)
+			container.Image = pinned
+		}
+	}
+	return pinnedPod
+}
+
+// pinnedImage returns the image referenced by the digest, images already referenced by digest are not changed.
+// The result contains the fully qualified repository, so the implied registry and tag are resolved, e.g. `nginx`
+// is pinned as `index.docker.io/library/nginx@<digest>`
+func pinnedImage(image, digest string, keepTag bool) (string, bool) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", false
+	}
+	tag, ok := ref.(name.Tag)
+	if !ok {
+		return "", false
+	}
+	pinned := tag.Context().Name()
+	if keepTag {
+		pinned += ":" + tag.TagStr()
+	}
+	return pinned + "@" + digest, true
+}
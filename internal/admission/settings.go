@@ -0,0 +1,32 @@
+package admission
+
+import (
+	"sync"
+	"time"
+)
+
+// Settings contains the webhook settings which can be changed while the webhook is running
+type Settings struct {
+	Timeout    time.Duration
+	StrictMode bool
+	// DigestPinning is used only by the defaulting webhook
+	DigestPinning string
+}
+
+// reloadableSettings keeps the settings safe for concurrent use
+type reloadableSettings struct {
+	mu       sync.RWMutex
+	settings Settings
+}
+
+func (s *reloadableSettings) get() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
+
+func (s *reloadableSettings) set(settings Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = settings
+}
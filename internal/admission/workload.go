@@ -0,0 +1,259 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/warden/internal/annotations"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/policy"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	WorkloadValidationPath = "/validation/workloads"
+)
+
+const (
+	DeploymentType  = "Deployment"
+	StatefulSetType = "StatefulSet"
+	DaemonSetType   = "DaemonSet"
+	ReplicaSetType  = "ReplicaSet"
+	JobType         = "Job"
+	CronJobType     = "CronJob"
+)
+
+// WorkloadValidationWebhook validates pod templates of workload controllers,
+// so unsigned images are rejected before any pod is created
+type WorkloadValidationWebhook struct {
+	systemValidator          validate.PodValidator
+	userValidationSvcFactory validate.ValidatorSvcFactory
+	client                   k8sclient.Client
+	reader                   k8sclient.Reader
+	decoder                  *admission.Decoder
+	baseLogger               *zap.SugaredLogger
+	policyResolver           policy.Resolver
+	settings                 reloadableSettings
+}
+
+func NewWorkloadValidationWebhook(client k8sclient.Client, reader k8sclient.Reader,
+	systemValidator validate.PodValidator, userValidationSvcFactory validate.ValidatorSvcFactory,
+	timeout time.Duration, strictMode bool,
+	decoder *admission.Decoder, logger *zap.SugaredLogger) *WorkloadValidationWebhook {
+	return &WorkloadValidationWebhook{
+		client:                   client,
+		reader:                   reader,
+		systemValidator:          systemValidator,
+		userValidationSvcFactory: userValidationSvcFactory,
+		baseLogger:               logger,
+		decoder:                  decoder,
+		settings:                 reloadableSettings{settings: Settings{Timeout: timeout, StrictMode: strictMode}},
+	}
+}
+
+// WithPolicyResolver enables validation with ImagePolicies and ClusterImagePolicies
+func (w *WorkloadValidationWebhook) WithPolicyResolver(resolver policy.Resolver) *WorkloadValidationWebhook {
+	w.policyResolver = resolver
+	return w
+}
+
+// Reload replaces the settings used by the next admission requests
+func (w *WorkloadValidationWebhook) Reload(settings Settings) {
+	w.settings.set(settings)
+}
+
+func (w *WorkloadValidationWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	return HandleWithLogger(w.baseLogger.With("kind", req.Kind.Kind),
+		HandlerWithTimeMeasure(
+			HandleWithTimeout(w.settings.get().Timeout, w.handle, w.handleTimeout)))(ctx, req)
+}
+
+func (w *WorkloadValidationWebhook) handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	template, err := w.decodePodTemplate(req.Kind.Kind, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if req.Operation == admissionv1.Update {
+		oldTemplate, err := w.decodePodTemplate(req.Kind.Kind, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if !arePodTemplateImagesChanged(oldTemplate, template) {
+			return admission.Allowed("pod template images are not changed")
+		}
+	}
+
+	ns := &corev1.Namespace{}
+	if err := w.client.Get(ctx, k8sclient.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if enabled := validate.IsValidationEnabledForNS(ns); !enabled {
+		return admission.Allowed("validation is not needed for workload")
+	}
+
+	validator, err := policy.NewPodValidator(ctx, w.policyResolver, ns, func() (validate.PodValidator, error) {
+		if validate.IsUserValidationForNS(ns) {
+			return validate.NewUserValidationSvc(ns, w.userValidationSvcFactory)
+		}
+		return w.systemValidator, nil
+	})
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	pod := podFromTemplate(req.Namespace, template)
+	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, w.reader, pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	result, err := validator.ValidatePod(ctx, pod, ns, imagePullCredentials)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return w.createResponse(ctx, req, result, ns)
+}
+
+func (w *WorkloadValidationWebhook) handleTimeout(ctx context.Context, timeoutErr error, req admission.Request) admission.Response {
+	msg := fmt.Sprintf("request exceeded desired timeout: %s, reason: %s", w.settings.get().Timeout.String(), timeoutErr.Error())
+	helpers.LoggerFromCtx(ctx).Info(msg)
+
+	ns := &corev1.Namespace{}
+	if err := w.client.Get(ctx, k8sclient.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	res := w.createResponse(ctx, req, validate.ValidationResult{Status: validate.ServiceUnavailable}, ns)
+	if !res.Allowed {
+		res.Result.Message = msg
+		return res
+	}
+	res.Result = &metav1.Status{Message: msg}
+	return res
+}
+
+func (w *WorkloadValidationWebhook) createResponse(ctx context.Context, req admission.Request,
+	result validate.ValidationResult, ns *corev1.Namespace) admission.Response {
+
+	strictMode, err := validate.StrictModeForNS(ns, w.settings.get().StrictMode, result)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	auditMode, err := helpers.IsAuditEnforcementMode(ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	helpers.LoggerFromCtx(ctx).Infow("workload was validated", "result", result)
+	_, annotation := podMarkersForValidationResult(result.Status, strictMode)
+	if annotation != annotations.ValidationReject {
+		return admission.Allowed("workload validation passed").
+			WithWarnings(responseWarnings(result, strictMode, auditMode)...)
+	}
+	if auditMode {
+		return admission.Allowed("workload is admitted in the audit mode").
+			WithWarnings(responseWarnings(result, strictMode, auditMode)...)
+	}
+	if len(result.InvalidImages) != 0 {
+		return admission.Denied(fmt.Sprintf("%s pod template images %s validation failed",
+			req.Kind.Kind, strings.Join(result.InvalidImages, ", ")))
+	}
+	return admission.Denied(fmt.Sprintf("%s pod template images validation failed", req.Kind.Kind))
+}
+
+func (w *WorkloadValidationWebhook) decodePodTemplate(kind string, raw runtime.RawExtension) (*corev1.PodTemplateSpec, error) {
+	switch kind {
+	case DeploymentType:
+		obj := &appsv1.Deployment{}
+		if err := (*w.decoder).DecodeRaw(raw, obj); err != nil {
+			return nil, err
+		}
+		return &obj.Spec.Template, nil
+	case StatefulSetType:
+		obj := &appsv1.StatefulSet{}
+		if err := (*w.decoder).DecodeRaw(raw, obj); err != nil {
+			return nil, err
+		}
+		return &obj.Spec.Template, nil
+	case DaemonSetType:
+		obj := &appsv1.DaemonSet{}
+		if err := (*w.decoder).DecodeRaw(raw, obj); err != nil {
+			return nil, err
+		}
+		return &obj.Spec.Template, nil
+	case ReplicaSetType:
+		obj := &appsv1.ReplicaSet{}
+		if err := (*w.decoder).DecodeRaw(raw, obj); err != nil {
+			return nil, err
+		}
+		return &obj.Spec.Template, nil
+	case JobType:
+		obj := &batchv1.Job{}
+		if err := (*w.decoder).DecodeRaw(raw, obj); err != nil {
+			return nil, err
+		}
+		return &obj.Spec.Template, nil
+	case CronJobType:
+		obj := &batchv1.CronJob{}
+		if err := (*w.decoder).DecodeRaw(raw, obj); err != nil {
+			return nil, err
+		}
+		return &obj.Spec.JobTemplate.Spec.Template, nil
+	}
+	return nil, errors.Errorf("Invalid request kind: %s, expected one of: %s", kind,
+		strings.Join([]string{DeploymentType, StatefulSetType, DaemonSetType, ReplicaSetType, JobType, CronJobType}, ", "))
+}
+
+// podFromTemplate returns pod which would be created from the template, so it can be validated as any other pod
+func podFromTemplate(namespace string, template *corev1.PodTemplateSpec) *corev1.Pod {
+	objectMeta := template.ObjectMeta.DeepCopy()
+	objectMeta.Namespace = namespace
+	return &corev1.Pod{
+		ObjectMeta: *objectMeta,
+		Spec:       *template.Spec.DeepCopy(),
+	}
+}
+
+func arePodTemplateImagesChanged(oldTemplate, newTemplate *corev1.PodTemplateSpec) bool {
+	oldImages := podTemplateImages(oldTemplate)
+	newImages := podTemplateImages(newTemplate)
+	if len(oldImages) != len(newImages) {
+		return true
+	}
+	for i := range oldImages {
+		if oldImages[i] != newImages[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func podTemplateImages(template *corev1.PodTemplateSpec) []string {
+	var images []string
+	for _, container := range append(template.Spec.InitContainers, template.Spec.Containers...) {
+		images = append(images, container.Image)
+	}
+	sort.Strings(images)
+	return images
+}
@@ -0,0 +1,248 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/internal/test_helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestWorkloadValidationWebhook(t *testing.T) {
+	//GIVEN
+	logger := test_helpers.NewTestZapLogger(t)
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, appsv1.AddToScheme(scheme))
+	require.NoError(t, batchv1.AddToScheme(scheme))
+	decoder := admission.NewDecoder(scheme)
+	timeout := time.Second
+
+	nsName := "test-namespace"
+	enabledNs := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName,
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled}}}
+	disabledNs := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: nsName}}
+
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "test:test"}}}}
+	changedTemplate := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "test:changed"}}}}
+
+	deployment := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{Kind: DeploymentType, APIVersion: appsv1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment", Namespace: nsName},
+		Spec:       appsv1.DeploymentSpec{Template: template},
+	}
+	scaledDeployment := deployment.DeepCopy()
+	scaledDeployment.Spec.Replicas = ptr.To[int32](5)
+	changedDeployment := deployment.DeepCopy()
+	changedDeployment.Spec.Template = changedTemplate
+
+	cronJob := &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{Kind: CronJobType, APIVersion: batchv1.SchemeGroupVersion.String()},
+		ObjectMeta: metav1.ObjectMeta{Name: "cronjob", Namespace: nsName},
+		Spec: batchv1.CronJobSpec{JobTemplate: batchv1.JobTemplateSpec{
+			Spec: batchv1.JobSpec{Template: template}}},
+	}
+
+	tests := []struct {
+		name               string
+		ns                 corev1.Namespace
+		kind               string
+		operation          admissionv1.Operation
+		object             runtime.Object
+		oldObject          runtime.Object
+		strictMode         bool
+		validationErr      error
+		shouldCallValidate bool
+		expectedAllowed    bool
+		expectedMessage    string
+	}{
+		{
+			name:               "deployment with signed image should be allowed",
+			ns:                 enabledNs,
+			kind:               DeploymentType,
+			operation:          admissionv1.Create,
+			object:             deployment,
+			shouldCallValidate: true,
+			expectedAllowed:    true,
+		},
+		{
+			name:               "deployment with unsigned image should be denied",
+			ns:                 enabledNs,
+			kind:               DeploymentType,
+			operation:          admissionv1.Create,
+			object:             deployment,
+			validationErr:      pkg.NewValidationFailedErr(errors.New("validation failed")),
+			shouldCallValidate: true,
+			expectedMessage:    "Deployment pod template images test:test validation failed",
+		},
+		{
+			name:               "cronjob with unsigned image should be denied",
+			ns:                 enabledNs,
+			kind:               CronJobType,
+			operation:          admissionv1.Create,
+			object:             cronJob,
+			validationErr:      pkg.NewValidationFailedErr(errors.New("validation failed")),
+			shouldCallValidate: true,
+			expectedMessage:    "CronJob pod template images test:test validation failed",
+		},
+		{
+			name:               "deployment should be allowed when service is unavailable and strict mode is off",
+			ns:                 enabledNs,
+			kind:               DeploymentType,
+			operation:          admissionv1.Create,
+			object:             deployment,
+			validationErr:      pkg.NewUnknownResultErr(errors.New("notary is not available")),
+			shouldCallValidate: true,
+			expectedAllowed:    true,
+		},
+		{
+			name:               "deployment should be denied when service is unavailable and strict mode is on",
+			ns:                 enabledNs,
+			kind:               DeploymentType,
+			operation:          admissionv1.Create,
+			object:             deployment,
+			strictMode:         StrictModeOn,
+			validationErr:      pkg.NewUnknownResultErr(errors.New("notary is not available")),
+			shouldCallValidate: true,
+			expectedMessage:    "Deployment pod template images test:test validation failed",
+		},
+		{
+			name:               "deployment update with changed images should be validated",
+			ns:                 enabledNs,
+			kind:               DeploymentType,
+			operation:          admissionv1.Update,
+			object:             changedDeployment,
+			oldObject:          deployment,
+			validationErr:      pkg.NewValidationFailedErr(errors.New("validation failed")),
+			shouldCallValidate: true,
+			expectedMessage:    "Deployment pod template images test:changed validation failed",
+		},
+		{
+			name:            "deployment update without changed images should be allowed without validation",
+			ns:              enabledNs,
+			kind:            DeploymentType,
+			operation:       admissionv1.Update,
+			object:          scaledDeployment,
+			oldObject:       deployment,
+			validationErr:   pkg.NewValidationFailedErr(errors.New("validation failed")),
+			expectedAllowed: true,
+		},
+		{
+			name:            "deployment in namespace without validation should be allowed without validation",
+			ns:              disabledNs,
+			kind:            DeploymentType,
+			operation:       admissionv1.Create,
+			object:          deployment,
+			validationErr:   pkg.NewValidationFailedErr(errors.New("validation failed")),
+			expectedAllowed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			mockImageValidator := mocks.ImageValidatorService{}
+			mockImageValidator.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).Return(tt.validationErr)
+			mockPodValidator := validate.NewPodValidator(&mockImageValidator)
+
+			req := newWorkloadRequestFix(t, tt.kind, tt.operation, tt.object, tt.oldObject)
+			client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&tt.ns).Build()
+			webhook := NewWorkloadValidationWebhook(client, client,
+				mockPodValidator, nil, timeout, tt.strictMode, &decoder, logger.Sugar())
+
+			//WHEN
+			res := webhook.Handle(context.TODO(), req)
+
+			//THEN
+			if tt.shouldCallValidate {
+				mockImageValidator.AssertNumberOfCalls(t, "Validate", 1)
+			} else {
+				mockImageValidator.AssertNotCalled(t, "Validate", mock.Anything, mock.Anything, mock.Anything)
+			}
+			require.Equal(t, tt.expectedAllowed, res.Allowed)
+			if tt.expectedMessage != "" {
+				require.Equal(t, tt.expectedMessage, res.Result.Message)
+			}
+		})
+	}
+
+	t.Run("deployment should be denied when service is unavailable and image policy enables strict mode", func(t *testing.T) {
+		//GIVEN
+		mockImageValidator := mocks.ImageValidatorService{}
+		mockImageValidator.Mock.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			Return(pkg.NewUnknownResultErr(errors.New("notary is not available")))
+		resolver := staticPolicyResolver{{
+			Name:       "ImagePolicy/test-namespace/strict",
+			Validator:  validate.NewPodValidator(&mockImageValidator),
+			StrictMode: ptr.To(true),
+		}}
+
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&enabledNs).Build()
+		webhook := NewWorkloadValidationWebhook(client, client,
+			nil, nil, timeout, StrictModeOff, &decoder, logger.Sugar()).WithPolicyResolver(resolver)
+		req := newWorkloadRequestFix(t, DeploymentType, admissionv1.Create, deployment, nil)
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.False(t, res.Allowed)
+		require.Equal(t, "Deployment pod template images test:test validation failed", res.Result.Message)
+	})
+
+	t.Run("unsupported kind should return bad request", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&enabledNs).Build()
+		webhook := NewWorkloadValidationWebhook(client, client,
+			nil, nil, timeout, StrictModeOff, &decoder, logger.Sugar())
+		req := newWorkloadRequestFix(t, PodType, admissionv1.Create, deployment, nil)
+
+		//WHEN
+		res := webhook.Handle(context.TODO(), req)
+
+		//THEN
+		require.False(t, res.Allowed)
+		require.Equal(t, int32(http.StatusBadRequest), res.Result.Code)
+		require.Contains(t, res.Result.Message, "Invalid request kind: Pod")
+	})
+}
+
+func newWorkloadRequestFix(t *testing.T, kind string, operation admissionv1.Operation, object, oldObject runtime.Object) admission.Request {
+	raw, err := json.Marshal(object)
+	require.NoError(t, err)
+
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: kind},
+		Namespace: "test-namespace",
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+	if oldObject != nil {
+		oldRaw, err := json.Marshal(oldObject)
+		require.NoError(t, err)
+		req.OldObject = runtime.RawExtension{Raw: oldRaw}
+	}
+	return req
+}
+
+type staticPolicyResolver []validate.PolicyRule
+
+func (r staticPolicyResolver) Resolve(_ context.Context, _ *corev1.Namespace) ([]validate.PolicyRule, error) {
+	return r, nil
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kyma-project/warden/internal/admission"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	PodType      = "Pod"
+	ImageSource  = "image"
+	decodeBuffer = 4096
+)
+
+// Workload is the pod, or the pod which would be created from the pod template of the manifest object
+type Workload struct {
+	// Source identifies the object in the manifest, e.g. Deployment/app
+	Source string
+	Pod    *corev1.Pod
+}
+
+// ReadManifest returns workloads of all objects with pods in the multi-document YAML or JSON manifest,
+// e.g. rendered by Helm. Objects without pods, e.g. services or config maps, are skipped
+func ReadManifest(r io.Reader) ([]Workload, error) {
+	decoder := yamlutil.NewYAMLOrJSONDecoder(r, decodeBuffer)
+	var workloads []Workload
+	for {
+		obj := map[string]interface{}{}
+		err := decoder.Decode(&obj)
+		if err == io.EOF {
+			return workloads, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "while decoding manifest")
+		}
+		if len(obj) == 0 {
+			// empty document, e.g. a template rendered by Helm without any object
+			continue
+		}
+
+		u := &unstructured.Unstructured{Object: obj}
+		if !u.IsList() {
+			workload, err := workloadFromObject(u)
+			if err != nil {
+				return nil, err
+			}
+			if workload != nil {
+				workloads = append(workloads, *workload)
+			}
+			continue
+		}
+		err = u.EachListItem(func(item runtime.Object) error {
+			workload, err := workloadFromObject(item.(*unstructured.Unstructured))
+			if err != nil {
+				return err
+			}
+			if workload != nil {
+				workloads = append(workloads, *workload)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ImageWorkload returns the workload running given images
+func ImageWorkload(images []string) Workload {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: ImageSource},
+	}
+	for i, image := range images {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{
+			Name:  fmt.Sprintf("container-%d", i),
+			Image: image,
+		})
+	}
+	return Workload{Source: ImageSource, Pod: pod}
+}
+
+// workloadFromObject returns nil when the object doesn't run pods
+func workloadFromObject(u *unstructured.Unstructured) (*Workload, error) {
+	kind := u.GetKind()
+	source := fmt.Sprintf("%s/%s", kind, u.GetName())
+
+	var template *corev1.PodTemplateSpec
+	switch kind {
+	case PodType:
+		pod := &corev1.Pod{}
+		if err := fromUnstructured(u, pod); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		return &Workload{Source: source, Pod: pod}, nil
+	case admission.DeploymentType:
+		obj := &appsv1.Deployment{}
+		if err := fromUnstructured(u, obj); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		template = &obj.Spec.Template
+	case admission.StatefulSetType:
+		obj := &appsv1.StatefulSet{}
+		if err := fromUnstructured(u, obj); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		template = &obj.Spec.Template
+	case admission.DaemonSetType:
+		obj := &appsv1.DaemonSet{}
+		if err := fromUnstructured(u, obj); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		template = &obj.Spec.Template
+	case admission.ReplicaSetType:
+		obj := &appsv1.ReplicaSet{}
+		if err := fromUnstructured(u, obj); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		template = &obj.Spec.Template
+	case admission.JobType:
+		obj := &batchv1.Job{}
+		if err := fromUnstructured(u, obj); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		template = &obj.Spec.Template
+	case admission.CronJobType:
+		obj := &batchv1.CronJob{}
+		if err := fromUnstructured(u, obj); err != nil {
+			return nil, errors.Wrapf(err, "while decoding %s", source)
+		}
+		template = &obj.Spec.JobTemplate.Spec.Template
+	default:
+		return nil, nil
+	}
+
+	objectMeta := template.ObjectMeta.DeepCopy()
+	if objectMeta.Name == "" {
+		objectMeta.Name = u.GetName()
+	}
+	return &Workload{
+		Source: source,
+		Pod: &corev1.Pod{
+			ObjectMeta: *objectMeta,
+			Spec:       *template.Spec.DeepCopy(),
+		},
+	}, nil
+}
+
+func fromUnstructured(u *unstructured.Unstructured, obj interface{}) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), obj)
+}
@@ -0,0 +1,119 @@
+package cli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/cli"
+	"github.com/stretchr/testify/require"
+)
+
+const manifest = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: registry.io/init:1.0
+      containers:
+      - name: app
+        image: registry.io/app:1.0
+---
+# empty document rendered by Helm
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: registry.io/backup:1.0
+---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Pod
+  metadata:
+    name: debug
+  spec:
+    containers:
+    - name: debug
+      image: registry.io/debug:1.0
+`
+
+func TestReadManifest(t *testing.T) {
+	t.Run("read workloads with pods", func(t *testing.T) {
+		//WHEN
+		workloads, err := cli.ReadManifest(strings.NewReader(manifest))
+
+		//THEN
+		require.NoError(t, err)
+		require.Len(t, workloads, 3)
+		require.Equal(t, "Deployment/app", workloads[0].Source)
+		require.Equal(t, "app", workloads[0].Pod.Name)
+		require.Equal(t, "registry.io/init:1.0", workloads[0].Pod.Spec.InitContainers[0].Image)
+		require.Equal(t, "registry.io/app:1.0", workloads[0].Pod.Spec.Containers[0].Image)
+		require.Equal(t, "CronJob/backup", workloads[1].Source)
+		require.Equal(t, "registry.io/backup:1.0", workloads[1].Pod.Spec.Containers[0].Image)
+		require.Equal(t, "Pod/debug", workloads[2].Source)
+		require.Equal(t, "registry.io/debug:1.0", workloads[2].Pod.Spec.Containers[0].Image)
+	})
+	t.Run("read JSON manifest", func(t *testing.T) {
+		//GIVEN
+		jsonManifest := `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "app"},
+			"spec": {"containers": [{"name": "app", "image": "registry.io/app:1.0"}]}}`
+
+		//WHEN
+		workloads, err := cli.ReadManifest(strings.NewReader(jsonManifest))
+
+		//THEN
+		require.NoError(t, err)
+		require.Len(t, workloads, 1)
+		require.Equal(t, "Pod/app", workloads[0].Source)
+	})
+	t.Run("invalid manifest", func(t *testing.T) {
+		//WHEN
+		workloads, err := cli.ReadManifest(strings.NewReader("kind: Pod\nspec: [\n"))
+
+		//THEN
+		require.ErrorContains(t, err, "while decoding manifest")
+		require.Nil(t, workloads)
+	})
+	t.Run("invalid pod template", func(t *testing.T) {
+		//GIVEN
+		invalid := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\nspec:\n  template: []\n"
+
+		//WHEN
+		workloads, err := cli.ReadManifest(strings.NewReader(invalid))
+
+		//THEN
+		require.ErrorContains(t, err, "while decoding Deployment/app")
+		require.Nil(t, workloads)
+	})
+}
+
+func TestImageWorkload(t *testing.T) {
+	//WHEN
+	workload := cli.ImageWorkload([]string{"registry.io/app:1.0", "registry.io/sidecar:1.0"})
+
+	//THEN
+	require.Equal(t, cli.ImageSource, workload.Source)
+	require.Len(t, workload.Pod.Spec.Containers, 2)
+	require.Equal(t, "registry.io/app:1.0", workload.Pod.Spec.Containers[0].Image)
+	require.Equal(t, "registry.io/sidecar:1.0", workload.Pod.Spec.Containers[1].Image)
+}
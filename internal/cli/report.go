@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+)
+
+const (
+	OutputCSV      = "csv"
+	OutputMarkdown = "markdown"
+)
+
+// ScanReport is the result of the cluster scan grouped by namespace, workload owner and image
+type ScanReport struct {
+	Namespaces []NamespaceReport `json:"namespaces"`
+}
+
+type NamespaceReport struct {
+	Name string `json:"name"`
+	// Validation is the value of the namespace validation label
+	Validation string `json:"validation"`
+	// Error describes why the namespace couldn't be scanned, e.g. because of invalid annotations
+	Error     string           `json:"error,omitempty"`
+	Workloads []WorkloadReport `json:"workloads,omitempty"`
+}
+
+type WorkloadReport struct {
+	// Owner is the top-level controller of the pods, e.g. Deployment/app, or the pod itself
+	Owner  string        `json:"owner"`
+	Images []ImageReport `json:"images"`
+}
+
+type ImageReport struct {
+	validate.ImageVerdict `json:",inline"`
+	Pods                  []string `json:"pods"`
+}
+
+// Passed returns true when all namespaces were scanned and all running images are valid
+func (r *ScanReport) Passed() bool {
+	for _, ns := range r.Namespaces {
+		if ns.Error != "" {
+			return false
+		}
+		for _, workload := range ns.Workloads {
+			for _, image := range workload.Images {
+				if image.Status != validate.Valid && image.Status != validate.NoAction {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// Summary returns the number of scanned images by their status
+func (r *ScanReport) Summary() map[validate.ValidationStatus]int {
+	summary := map[validate.ValidationStatus]int{}
+	for _, ns := range r.Namespaces {
+		for _, workload := range ns.Workloads {
+			for _, image := range workload.Images {
+				summary[image.Status]++
+			}
+		}
+	}
+	return summary
+}
+
+// Write writes the report in the given output format
+func (r *ScanReport) Write(w io.Writer, output string) error {
+	switch output {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case OutputCSV:
+		return r.writeCSV(w)
+	case OutputMarkdown:
+		return r.writeMarkdown(w)
+	}
+	return errors.Errorf("unsupported output format: %s, expected one of: %s, %s, %s",
+		output, OutputJSON, OutputCSV, OutputMarkdown)
+}
+
+func (r *ScanReport) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	rows := [][]string{{"namespace", "owner", "image", "status", "reason", "message", "digest", "verifier", "pods"}}
+	for _, ns := range r.Namespaces {
+		if ns.Error != "" {
+			rows = append(rows, []string{ns.Name, "", "", "", "", ns.Error, "", "", ""})
+		}
+		for _, workload := range ns.Workloads {
+			for _, image := range workload.Images {
+				rows = append(rows, []string{ns.Name, workload.Owner, image.Image, string(image.Status), string(image.Reason),
+					image.Message, image.Digest, image.Verifier, strings.Join(image.Pods, " ")})
+			}
+		}
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return errors.Wrap(err, "while writing CSV report")
+	}
+	return nil
+}
+
+func (r *ScanReport) writeMarkdown(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("# Warden Compliance Report\n\n")
+	summary := r.Summary()
+	sb.WriteString("| Status | Images |\n| --- | --- |\n")
+	for _, status := range []validate.ValidationStatus{validate.Valid, validate.Invalid, validate.ServiceUnavailable, validate.NoAction} {
+		fmt.Fprintf(&sb, "| %s | %d |\n", status, summary[status])
+	}
+	for _, ns := range r.Namespaces {
+		fmt.Fprintf(&sb, "\n## %s\n\nValidation: `%s`\n\n", ns.Name, ns.Validation)
+		if ns.Error != "" {
+			fmt.Fprintf(&sb, "Namespace couldn't be scanned: %s\n", markdownCell(ns.Error))
+			continue
+		}
+		if len(ns.Workloads) == 0 {
+			sb.WriteString("No running pods.\n")
+			continue
+		}
+		sb.WriteString("| Owner | Image | Status | Reason | Message | Pods |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, workload := range ns.Workloads {
+			for _, image := range workload.Images {
+				fmt.Fprintf(&sb, "| %s | `%s` | %s | %s | %s | %s |\n", markdownCell(workload.Owner), image.Image,
+					image.Status, image.Reason, markdownCell(image.Message), strings.Join(image.Pods, ", "))
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// markdownCell escapes the text, so it doesn't break the table
+func markdownCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
@@ -0,0 +1,94 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/cli"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/stretchr/testify/require"
+)
+
+var scanReport = &cli.ScanReport{Namespaces: []cli.NamespaceReport{
+	{Name: "broken", Validation: "user", Error: "unsupported verifier: unknown"},
+	{Name: "system", Validation: "system", Workloads: []cli.WorkloadReport{
+		{Owner: "Deployment/app", Images: []cli.ImageReport{{
+			ImageVerdict: validate.ImageVerdict{Image: "registry.io/app:1.0", Status: validate.Valid,
+				Reason: validate.ReasonVerified, Digest: "sha256:aaa", Verifier: "notary"},
+			Pods: []string{"app-a", "app-b"},
+		}}},
+		{Owner: "Pod/debug", Images: []cli.ImageReport{{
+			ImageVerdict: validate.ImageVerdict{Image: "registry.io/unsigned:1.0", Status: validate.Invalid,
+				Reason: validate.ReasonNoTrustData, Message: "no trust data | expired"},
+			Pods: []string{"debug"},
+		}}},
+	}},
+}}
+
+func TestScanReport_Write(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		//GIVEN
+		out := &bytes.Buffer{}
+
+		//WHEN
+		err := scanReport.Write(out, cli.OutputJSON)
+
+		//THEN
+		require.NoError(t, err)
+		var written cli.ScanReport
+		require.NoError(t, json.Unmarshal(out.Bytes(), &written))
+		require.Equal(t, scanReport, &written)
+	})
+	t.Run("csv", func(t *testing.T) {
+		//GIVEN
+		out := &bytes.Buffer{}
+
+		//WHEN
+		err := scanReport.Write(out, cli.OutputCSV)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, "namespace,owner,image,status,reason,message,digest,verifier,pods\n"+
+			"broken,,,,,unsupported verifier: unknown,,,\n"+
+			"system,Deployment/app,registry.io/app:1.0,Valid,Verified,,sha256:aaa,notary,app-a app-b\n"+
+			"system,Pod/debug,registry.io/unsigned:1.0,Invalid,NoTrustData,no trust data | expired,,,debug\n", out.String())
+	})
+	t.Run("markdown", func(t *testing.T) {
+		//GIVEN
+		out := &bytes.Buffer{}
+
+		//WHEN
+		err := scanReport.Write(out, cli.OutputMarkdown)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, "# Warden Compliance Report\n\n"+
+			"| Status | Images |\n| --- | --- |\n"+
+			"| Valid | 1 |\n| Invalid | 1 |\n| ServiceUnavailable | 0 |\n| NoAction | 0 |\n"+
+			"\n## broken\n\nValidation: `user`\n\n"+
+			"Namespace couldn't be scanned: unsupported verifier: unknown\n"+
+			"\n## system\n\nValidation: `system`\n\n"+
+			"| Owner | Image | Status | Reason | Message | Pods |\n| --- | --- | --- | --- | --- | --- |\n"+
+			"| Deployment/app | `registry.io/app:1.0` | Valid | Verified |  | app-a, app-b |\n"+
+			"| Pod/debug | `registry.io/unsigned:1.0` | Invalid | NoTrustData | no trust data \\| expired | debug |\n", out.String())
+	})
+	t.Run("unsupported format", func(t *testing.T) {
+		//WHEN
+		err := scanReport.Write(&bytes.Buffer{}, cli.OutputText)
+
+		//THEN
+		require.ErrorContains(t, err, "unsupported output format: text")
+	})
+}
+
+func TestScanReport_Passed(t *testing.T) {
+	//GIVEN
+	valid := &cli.ScanReport{Namespaces: []cli.NamespaceReport{scanReport.Namespaces[1]}}
+	valid.Namespaces[0].Workloads = valid.Namespaces[0].Workloads[:1]
+
+	//THEN
+	require.True(t, valid.Passed())
+	require.False(t, scanReport.Passed())
+	require.False(t, (&cli.ScanReport{Namespaces: scanReport.Namespaces[:1]}).Passed())
+}
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/policy"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Scanner revalidates images of running pods in all namespaces with enabled validation
+type Scanner struct {
+	client                   k8sclient.Client
+	systemValidator          validate.PodValidator
+	userValidationSvcFactory validate.ValidatorSvcFactory
+	policyResolver           policy.Resolver
+}
+
+func NewScanner(client k8sclient.Client, systemValidator validate.PodValidator, userValidationSvcFactory validate.ValidatorSvcFactory) *Scanner {
+	return &Scanner{
+		client:                   client,
+		systemValidator:          systemValidator,
+		userValidationSvcFactory: userValidationSvcFactory,
+	}
+}
+
+// WithPolicyResolver enables validation with ImagePolicies and ClusterImagePolicies
+func (s *Scanner) WithPolicyResolver(resolver policy.Resolver) *Scanner {
+	s.policyResolver = resolver
+	return s
+}
+
+// scannedImage is the image run by the pod, validated once in the namespace
+type scannedImage struct {
+	// reference is the image pinned to the digest of the running image when it's known
+	reference   string
+	credentials map[string]cliType.AuthConfig
+	pods        map[string]struct{}
+	owner       string
+}
+
+// Scan validates images of all running pods. Images running with the same digest and pulled with the same credentials
+// are validated once in each namespace, the result is reported for all workloads running them
+func (s *Scanner) Scan(ctx context.Context) (*ScanReport, error) {
+	var namespaces corev1.NamespaceList
+	if err := s.client.List(ctx, &namespaces); err != nil {
+		return nil, errors.Wrap(err, "while listing namespaces")
+	}
+	sort.Slice(namespaces.Items, func(i, j int) bool {
+		return namespaces.Items[i].Name < namespaces.Items[j].Name
+	})
+
+	report := &ScanReport{}
+	owners := map[types.NamespacedName]string{}
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if !validate.IsValidationEnabledForNS(ns) {
+			continue
+		}
+		nsReport, err := s.scanNamespace(ctx, ns, owners)
+		if err != nil {
+			return nil, err
+		}
+		report.Namespaces = append(report.Namespaces, nsReport)
+	}
+	return report, nil
+}
+
+func (s *Scanner) scanNamespace(ctx context.Context, ns *corev1.Namespace, owners map[types.NamespacedName]string) (NamespaceReport, error) {
+	nsReport := NamespaceReport{Name: ns.Name, Validation: ns.Labels[pkg.NamespaceValidationLabel]}
+
+	var pods corev1.PodList
+	if err := s.client.List(ctx, &pods, k8sclient.InNamespace(ns.Name)); err != nil {
+		return nsReport, errors.Wrapf(err, "while listing pods in namespace %s", ns.Name)
+	}
+
+	validator, err := policy.NewPodValidator(ctx, s.policyResolver, ns, func() (validate.PodValidator, error) {
+		if validate.IsUserValidationForNS(ns) {
+			return validate.NewUserValidationSvc(ns, s.userValidationSvcFactory)
+		}
+		return s.systemValidator, nil
+	})
+	if err != nil {
+		// invalid namespace configuration is reported, so the rest of the cluster can be still scanned
+		nsReport.Error = err.Error()
+		return nsReport, nil
+	}
+
+	images := map[string]*scannedImage{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		owner, err := s.podOwner(ctx, pod, owners)
+		if err != nil {
+			return nsReport, err
+		}
+		for _, reference := range runningImages(pod) {
+			key := owner + "|" + reference
+			image, ok := images[key]
+			if !ok {
+				credentials, err := helpers.GetRemotePullCredentials(ctx, s.client, pod)
+				if err != nil {
+					return nsReport, errors.Wrapf(err, "while getting pull credentials of pod %s/%s", pod.Namespace, pod.Name)
+				}
+				image = &scannedImage{reference: reference, credentials: credentials, pods: map[string]struct{}{}, owner: owner}
+				images[key] = image
+			}
+			image.pods[pod.Name] = struct{}{}
+		}
+	}
+
+	// verdicts are shared only by images pulled with the same credentials, as the credentials can change the verdict
+	verdicts := map[string]validate.ImageVerdict{}
+	workloads := map[string]*WorkloadReport{}
+	for _, image := range images {
+		verdictKey := image.reference + "|" + validate.PullCredentialsKey(image.credentials)
+		verdict, ok := verdicts[verdictKey]
+		if !ok {
+			verdict, err = validateImage(ctx, validator, ns, image)
+			if err != nil {
+				return nsReport, err
+			}
+			verdicts[verdictKey] = verdict
+		}
+
+		workload, ok := workloads[image.owner]
+		if !ok {
+			workload = &WorkloadReport{Owner: image.owner}
+			workloads[image.owner] = workload
+		}
+		workload.Images = append(workload.Images, ImageReport{ImageVerdict: verdict, Pods: sortedKeys(image.pods)})
+	}
+
+	for _, workload := range workloads {
+		sort.Slice(workload.Images, func(i, j int) bool {
+			return workload.Images[i].Image < workload.Images[j].Image
+		})
+		nsReport.Workloads = append(nsReport.Workloads, *workload)
+	}
+	sort.Slice(nsReport.Workloads, func(i, j int) bool {
+		return nsReport.Workloads[i].Owner < nsReport.Workloads[j].Owner
+	})
+	return nsReport, nil
+}
+
+func validateImage(ctx context.Context, validator validate.PodValidator, ns *corev1.Namespace, image *scannedImage) (validate.ImageVerdict, error) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: ImageSource, Namespace: ns.Name},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: ImageSource, Image: image.reference}},
+		},
+	}
+	result, err := validator.ValidatePod(ctx, pod, ns, image.credentials)
+	if err != nil {
+		return validate.ImageVerdict{}, errors.Wrapf(err, "while validating image %s", image.reference)
+	}
+	if len(result.Images) == 0 {
+		return validate.ImageVerdict{Image: image.reference, Status: result.Status}, nil
+	}
+	return result.Images[0], nil
+}
+
+// podOwner returns the top-level controller of the pod, e.g. the Deployment of its ReplicaSet,
+// or the pod itself when it isn't controlled
+func (s *Scanner) podOwner(ctx context.Context, pod *corev1.Pod, owners map[types.NamespacedName]string) (string, error) {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil {
+		return fmt.Sprintf("Pod/%s", pod.Name), nil
+	}
+	owner := fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+
+	var obj k8sclient.Object
+	switch ref.Kind {
+	case "ReplicaSet":
+		obj = &appsv1.ReplicaSet{}
+	case "Job":
+		obj = &batchv1.Job{}
+	default:
+		return owner, nil
+	}
+
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: owner}
+	if cached, ok := owners[key]; ok {
+		return cached, nil
+	}
+	if err := s.client.Get(ctx, types.NamespacedName{Namespace: pod.Namespace, Name: ref.Name}, obj); k8sclient.IgnoreNotFound(err) != nil {
+		return "", errors.Wrapf(err, "while getting owner of pod %s/%s", pod.Namespace, pod.Name)
+	}
+	if parent := metav1.GetControllerOf(obj); parent != nil {
+		owner = fmt.Sprintf("%s/%s", parent.Kind, parent.Name)
+	}
+	owners[key] = owner
+	return owner, nil
+}
+
+// runningImages returns images of all containers of the pod, pinned to the digests of the images they run
+// when they are known from the container statuses
+func runningImages(pod *corev1.Pod) []string {
+	digests := map[string]string{}
+	var statuses []corev1.ContainerStatus
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	statuses = append(statuses, pod.Status.EphemeralContainerStatuses...)
+	for _, status := range statuses {
+		if _, digest, found := strings.Cut(status.ImageID, "@"); found {
+			digests[status.Name] = digest
+		}
+	}
+
+	images := map[string]struct{}{}
+	addImage := func(name, image string) {
+		if digest, ok := digests[name]; ok && !strings.Contains(image, "@") {
+			image = image + "@" + digest
+		}
+		images[image] = struct{}{}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		addImage(container.Name, container.Image)
+	}
+	for _, container := range pod.Spec.Containers {
+		addImage(container.Name, container.Image)
+	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		addImage(container.Name, container.Image)
+	}
+	return sortedKeys(images)
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
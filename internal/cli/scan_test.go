@@ -0,0 +1,158 @@
+package cli_test
+
+import (
+	"context"
+	"testing"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/kyma-project/warden/internal/cli"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func controllerRef(kind, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &controller}}
+}
+
+func runningPod(namespace, name, image, digest string, owners []metav1.OwnerReference) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, OwnerReferences: owners},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: image}},
+		},
+		Status: corev1.PodStatus{
+			Phase:             corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", Image: image, ImageID: image + "@" + digest}},
+		},
+	}
+}
+
+func podWithImage(image string) interface{} {
+	return mock.MatchedBy(func(pod *corev1.Pod) bool {
+		return len(pod.Spec.Containers) == 1 && pod.Spec.Containers[0].Image == image
+	})
+}
+
+func TestScanner_Scan(t *testing.T) {
+	//GIVEN
+	ctx := helpers.LoggerToContext(context.TODO(), zap.NewNop().Sugar())
+	systemNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "system",
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationSystem}}}
+	brokenNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "broken",
+		Labels:      map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser},
+		Annotations: map[string]string{pkg.NamespaceVerifierAnnotation: "unknown"}}}
+	disabledNs := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "disabled"}}
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "app-123", Namespace: "system",
+		OwnerReferences: controllerRef("Deployment", "app")}}
+	finished := runningPod("system", "finished", "registry.io/job:1.0", "sha256:ccc", nil)
+	finished.Status.Phase = corev1.PodSucceeded
+
+	client := fake.NewClientBuilder().WithObjects(
+		systemNs, brokenNs, disabledNs, replicaSet, finished,
+		runningPod("system", "app-123-a", "registry.io/app:1.0", "sha256:aaa", controllerRef("ReplicaSet", "app-123")),
+		runningPod("system", "app-123-b", "registry.io/app:1.0", "sha256:aaa", controllerRef("ReplicaSet", "app-123")),
+		runningPod("system", "debug", "registry.io/unsigned:1.0", "sha256:bbb", nil),
+		runningPod("broken", "app", "registry.io/app:1.0", "sha256:aaa", nil),
+		runningPod("disabled", "app", "registry.io/unsigned:1.0", "sha256:bbb", nil),
+	).Build()
+
+	validVerdict := validate.ImageVerdict{Image: "registry.io/app:1.0@sha256:aaa", Status: validate.Valid, Reason: validate.ReasonVerified}
+	invalidVerdict := validate.ImageVerdict{Image: "registry.io/unsigned:1.0@sha256:bbb", Status: validate.Invalid, Reason: validate.ReasonNoTrustData}
+	systemValidator := mocks.NewPodValidator(t)
+	// images running with the same digest are validated once
+	systemValidator.On("ValidatePod", mock.Anything, podWithImage(validVerdict.Image), mock.Anything, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Valid, Images: []validate.ImageVerdict{validVerdict}}, nil).Once()
+	systemValidator.On("ValidatePod", mock.Anything, podWithImage(invalidVerdict.Image), mock.Anything, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Invalid, Images: []validate.ImageVerdict{invalidVerdict}}, nil).Once()
+
+	scanner := cli.NewScanner(client, systemValidator, mocks.NewValidatorSvcFactory(t))
+
+	//WHEN
+	report, err := scanner.Scan(ctx)
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, &cli.ScanReport{Namespaces: []cli.NamespaceReport{
+		{
+			Name:       "broken",
+			Validation: pkg.NamespaceValidationUser,
+			Error:      "unsupported verifier: unknown",
+		},
+		{
+			Name:       "system",
+			Validation: pkg.NamespaceValidationSystem,
+			Workloads: []cli.WorkloadReport{
+				{Owner: "Deployment/app", Images: []cli.ImageReport{
+					{ImageVerdict: validVerdict, Pods: []string{"app-123-a", "app-123-b"}},
+				}},
+				{Owner: "Pod/debug", Images: []cli.ImageReport{
+					{ImageVerdict: invalidVerdict, Pods: []string{"debug"}},
+				}},
+			},
+		},
+	}}, report)
+	require.False(t, report.Passed())
+	require.Equal(t, map[validate.ValidationStatus]int{validate.Valid: 1, validate.Invalid: 1}, report.Summary())
+}
+
+func TestScanner_Scan_PullCredentials(t *testing.T) {
+	//GIVEN
+	ctx := helpers.LoggerToContext(context.TODO(), zap.NewNop().Sugar())
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "system",
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationSystem}}}
+	pullSecret := func(name, password string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "system"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte(
+				`{"auths":{"registry.io":{"username":"user","password":"` + password + `"}}}`)},
+		}
+	}
+	podWithSecret := func(name, secret string) *corev1.Pod {
+		pod := runningPod("system", name, "registry.io/app:1.0", "sha256:aaa", nil)
+		pod.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: secret}}
+		return pod
+	}
+	client := fake.NewClientBuilder().WithObjects(ns,
+		pullSecret("valid", "valid"), pullSecret("invalid", "invalid"),
+		podWithSecret("valid-a", "valid"), podWithSecret("valid-b", "valid"), podWithSecret("invalid", "invalid"),
+	).Build()
+
+	image := "registry.io/app:1.0@sha256:aaa"
+	validVerdict := validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified}
+	failedVerdict := validate.ImageVerdict{Image: image, Status: validate.ServiceUnavailable, Reason: validate.ReasonRegistryAuthFailed}
+	withPassword := func(password string) interface{} {
+		return mock.MatchedBy(func(credentials map[string]cliType.AuthConfig) bool {
+			return credentials["registry.io"].Password == password
+		})
+	}
+	systemValidator := mocks.NewPodValidator(t)
+	// the image is validated once for each credentials
+	systemValidator.On("ValidatePod", mock.Anything, podWithImage(image), mock.Anything, withPassword("valid")).
+		Return(validate.ValidationResult{Status: validate.Valid, Images: []validate.ImageVerdict{validVerdict}}, nil).Once()
+	systemValidator.On("ValidatePod", mock.Anything, podWithImage(image), mock.Anything, withPassword("invalid")).
+		Return(validate.ValidationResult{Status: validate.ServiceUnavailable, Images: []validate.ImageVerdict{failedVerdict}}, nil).Once()
+
+	scanner := cli.NewScanner(client, systemValidator, mocks.NewValidatorSvcFactory(t))
+
+	//WHEN
+	report, err := scanner.Scan(ctx)
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, []cli.WorkloadReport{
+		{Owner: "Pod/invalid", Images: []cli.ImageReport{{ImageVerdict: failedVerdict, Pods: []string{"invalid"}}}},
+		{Owner: "Pod/valid-a", Images: []cli.ImageReport{{ImageVerdict: validVerdict, Pods: []string{"valid-a"}}}},
+		{Owner: "Pod/valid-b", Images: []cli.ImageReport{{ImageVerdict: validVerdict, Pods: []string{"valid-b"}}}},
+	}, report.Namespaces[0].Workloads)
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Result is the validation result of the single workload
+type Result struct {
+	Source string                    `json:"source"`
+	Status validate.ValidationStatus `json:"status"`
+	Images []validate.ImageVerdict   `json:"images"`
+	// Admitted is true when the workload would be admitted in the namespace, the strict mode and the enforcement
+	// mode are applied like in the admission
+	Admitted bool `json:"admitted"`
+}
+
+// Verify validates pods of all workloads as they would be validated in the namespace, the system strict mode applies
+// in namespaces with the system validation. Image pull secrets of the pods aren't available,
+// so only public or anonymously readable images can be verified
+func Verify(ctx context.Context, validator validate.PodValidator, ns *corev1.Namespace, systemStrictMode bool, workloads []Workload) ([]Result, error) {
+	auditMode, err := helpers.IsAuditEnforcementMode(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(workloads))
+	for _, workload := range workloads {
+		pod := workload.Pod.DeepCopy()
+		pod.Namespace = ns.Name
+
+		result, err := validator.ValidatePod(ctx, pod, ns, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while validating %s", workload.Source)
+		}
+		strictMode, err := validate.StrictModeForNS(ns, systemStrictMode, result)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, Result{
+			Source:   workload.Source,
+			Status:   result.Status,
+			Images:   result.Images,
+			Admitted: auditMode || !validate.IsRejected(result.Status, strictMode),
+		})
+	}
+	return results, nil
+}
+
+// Passed returns true when none of the workloads would be rejected
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Admitted {
+			return false
+		}
+	}
+	return true
+}
+
+// Print writes verdicts of all images in the given output format
+func Print(w io.Writer, output string, results []Result) error {
+	switch output {
+	case OutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case OutputText:
+		return printText(w, results)
+	}
+	return errors.Errorf("unsupported output format: %s, expected one of: %s, %s", output, OutputText, OutputJSON)
+}
+
+func printText(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tIMAGE\tSTATUS\tREASON\tMESSAGE")
+	for _, result := range results {
+		if len(result.Images) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t%s\t-\t-\n", result.Source, result.Status)
+		}
+		for _, image := range result.Images {
+			message := image.Message
+			if message == "" {
+				message = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", result.Source, image.Image, image.Status, image.Reason, message)
+		}
+	}
+	return tw.Flush()
+}
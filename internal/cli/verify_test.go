@@ -0,0 +1,195 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/cli"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVerify(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-ns"}}
+	workloads := []cli.Workload{
+		cli.ImageWorkload([]string{"registry.io/app:1.0"}),
+		cli.ImageWorkload([]string{"registry.io/unsigned:1.0"}),
+	}
+	validVerdict := validate.ImageVerdict{Image: "registry.io/app:1.0", Status: validate.Valid, Reason: validate.ReasonVerified}
+	invalidVerdict := validate.ImageVerdict{Image: "registry.io/unsigned:1.0", Status: validate.Invalid,
+		Reason: validate.ReasonNoTrustData, Message: "no trust data"}
+
+	t.Run("validate pods in the namespace", func(t *testing.T) {
+		//GIVEN
+		validator := mocks.NewPodValidator(t)
+		validator.On("ValidatePod", mock.Anything, mock.MatchedBy(func(pod *corev1.Pod) bool {
+			return pod.Namespace == ns.Name && pod.Spec.Containers[0].Image == "registry.io/app:1.0"
+		}), ns, mock.Anything).Return(validate.ValidationResult{
+			Status: validate.Valid,
+			Images: []validate.ImageVerdict{validVerdict},
+		}, nil).Once()
+		validator.On("ValidatePod", mock.Anything, mock.MatchedBy(func(pod *corev1.Pod) bool {
+			return pod.Namespace == ns.Name && pod.Spec.Containers[0].Image == "registry.io/unsigned:1.0"
+		}), ns, mock.Anything).Return(validate.ValidationResult{
+			Status: validate.Invalid,
+			Images: []validate.ImageVerdict{invalidVerdict},
+		}, nil).Once()
+
+		//WHEN
+		results, err := cli.Verify(context.TODO(), validator, ns, false, workloads)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, []cli.Result{
+			{Source: cli.ImageSource, Status: validate.Valid, Images: []validate.ImageVerdict{validVerdict}, Admitted: true},
+			{Source: cli.ImageSource, Status: validate.Invalid, Images: []validate.ImageVerdict{invalidVerdict}},
+		}, results)
+		require.False(t, cli.Passed(results))
+		require.True(t, cli.Passed(results[:1]))
+	})
+	t.Run("validation error", func(t *testing.T) {
+		//GIVEN
+		validator := mocks.NewPodValidator(t)
+		validator.On("ValidatePod", mock.Anything, mock.Anything, ns, mock.Anything).
+			Return(validate.ValidationResult{}, errors.New("invalid annotation")).Once()
+
+		//WHEN
+		results, err := cli.Verify(context.TODO(), validator, ns, false, workloads)
+
+		//THEN
+		require.ErrorContains(t, err, "while validating image: invalid annotation")
+		require.Nil(t, results)
+	})
+}
+
+func TestVerify_StrictMode(t *testing.T) {
+	workloads := []cli.Workload{cli.ImageWorkload([]string{"registry.io/app:1.0"})}
+	unavailableResult := validate.ValidationResult{Status: validate.ServiceUnavailable}
+	strict, notStrict := true, false
+
+	tests := []struct {
+		name             string
+		ns               *corev1.Namespace
+		systemStrictMode bool
+		result           validate.ValidationResult
+		wantPassed       bool
+	}{
+		{
+			name:       "unavailable service passes without the system strict mode",
+			ns:         systemNamespace(nil),
+			result:     unavailableResult,
+			wantPassed: true,
+		},
+		{
+			name:             "unavailable service fails in the system strict mode",
+			ns:               systemNamespace(nil),
+			systemStrictMode: true,
+			result:           unavailableResult,
+		},
+		{
+			name:             "user namespace strict mode is used instead of the system one",
+			ns:               userNamespace(map[string]string{pkg.NamespaceStrictModeAnnotation: "false"}),
+			systemStrictMode: true,
+			result:           unavailableResult,
+			wantPassed:       true,
+		},
+		{
+			name:   "user namespace is strict by default",
+			ns:     userNamespace(nil),
+			result: unavailableResult,
+		},
+		{
+			name:             "image policy strict mode overrides the namespace one",
+			ns:               systemNamespace(nil),
+			systemStrictMode: true,
+			result:           validate.ValidationResult{Status: validate.ServiceUnavailable, StrictMode: &notStrict},
+			wantPassed:       true,
+		},
+		{
+			name:   "image policy strict mode rejects unavailable service",
+			ns:     systemNamespace(nil),
+			result: validate.ValidationResult{Status: validate.ServiceUnavailable, StrictMode: &strict},
+		},
+		{
+			name:       "invalid image passes in the audit mode",
+			ns:         systemNamespace(map[string]string{pkg.NamespaceEnforcementModeAnnotation: pkg.EnforcementModeAudit}),
+			result:     validate.ValidationResult{Status: validate.Invalid},
+			wantPassed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			validator := mocks.NewPodValidator(t)
+			validator.On("ValidatePod", mock.Anything, mock.Anything, tt.ns, mock.Anything).Return(tt.result, nil).Once()
+
+			//WHEN
+			results, err := cli.Verify(context.TODO(), validator, tt.ns, tt.systemStrictMode, workloads)
+
+			//THEN
+			require.NoError(t, err)
+			require.Equal(t, tt.wantPassed, cli.Passed(results))
+		})
+	}
+}
+
+func systemNamespace(annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-ns", Annotations: annotations,
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationSystem}}}
+}
+
+func userNamespace(annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "test-ns", Annotations: annotations,
+		Labels: map[string]string{pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser}}}
+}
+
+func TestPrint(t *testing.T) {
+	results := []cli.Result{
+		{Source: "Deployment/app", Status: validate.Invalid, Images: []validate.ImageVerdict{
+			{Image: "registry.io/app:1.0", Status: validate.Valid, Reason: validate.ReasonVerified},
+			{Image: "registry.io/unsigned:1.0", Status: validate.Invalid, Reason: validate.ReasonNoTrustData, Message: "no trust data"},
+		}},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		//GIVEN
+		out := &bytes.Buffer{}
+
+		//WHEN
+		err := cli.Print(out, cli.OutputText, results)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, "SOURCE          IMAGE                     STATUS   REASON       MESSAGE\n"+
+			"Deployment/app  registry.io/app:1.0       Valid    Verified     -\n"+
+			"Deployment/app  registry.io/unsigned:1.0  Invalid  NoTrustData  no trust data\n", out.String())
+	})
+	t.Run("json", func(t *testing.T) {
+		//GIVEN
+		out := &bytes.Buffer{}
+
+		//WHEN
+		err := cli.Print(out, cli.OutputJSON, results)
+
+		//THEN
+		require.NoError(t, err)
+		var printed []cli.Result
+		require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+		require.Equal(t, results, printed)
+	})
+	t.Run("unsupported format", func(t *testing.T) {
+		//WHEN
+		err := cli.Print(&bytes.Buffer{}, "yaml", results)
+
+		//THEN
+		require.ErrorContains(t, err, "unsupported output format: yaml")
+	})
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of environment variables overriding the configuration file,
+// e.g. `WARDEN_NOTARY_URL` overrides `notary.URL` and `WARDEN_ADMISSION_STRICT_MODE` overrides `admission.strictMode`
+const EnvPrefix = "WARDEN"
+
+// overrideFromEnv sets the configuration fields for which the environment variables are set. Values of fields other
+// than strings, e.g. durations or lists, are decoded like the ones in the configuration file
+func overrideFromEnv(config *Config, lookupEnv func(string) (string, bool)) error {
+	return overrideStructFromEnv(reflect.ValueOf(config).Elem(), EnvPrefix, lookupEnv)
+}
+
+func overrideStructFromEnv(value reflect.Value, prefix string, lookupEnv func(string) (string, bool)) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + envName(key)
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := overrideStructFromEnv(value.Field(i), name, lookupEnv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		env, ok := lookupEnv(name)
+		if !ok {
+			continue
+		}
+		if field.Type.Kind() == reflect.String {
+			value.Field(i).SetString(env)
+			continue
+		}
+		if err := yaml.Unmarshal([]byte(env), value.Field(i).Addr().Interface()); err != nil {
+			return errors.Wrapf(err, "while decoding environment variable %s", name)
+		}
+	}
+	return nil
+}
+
+// envName returns the upper snake case of the camel case key, e.g. `POSITIVE_TTL` for `positiveTTL`
+func envName(key string) string {
+	runes := []rune(key)
+	name := strings.Builder{}
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previous := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				name.WriteRune('_')
+			}
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
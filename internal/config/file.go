@@ -1,10 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
@@ -13,6 +17,57 @@ type notary struct {
 	Timeout                         time.Duration `yaml:"timeout"`
 	AllowedRegistries               string        `yaml:"allowedRegistries"`
 	PredefinedUserAllowedRegistries string        `yaml:"predefinedUserAllowedRegistries"`
+	TrustCache                      trustCache    `yaml:"trustCache"`
+}
+
+type trustCache struct {
+	Enabled bool `yaml:"enabled"`
+	MaxSize int  `yaml:"maxSize"`
+	// Dir keeps TUF metadata on disk, it's kept in memory when empty
+	Dir             string        `yaml:"dir"`
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+}
+
+type cosign struct {
+	PublicKeys string        `yaml:"publicKeys"`
+	Timeout    time.Duration `yaml:"timeout"`
+}
+
+type notation struct {
+	Timeout time.Duration `yaml:"timeout"`
+	// TrustPolicy is a notation trust policy document in JSON format
+	TrustPolicy string       `yaml:"trustPolicy"`
+	TrustStores []trustStore `yaml:"trustStores"`
+}
+
+type trustStore struct {
+	Type         string `yaml:"type"`
+	Name         string `yaml:"name"`
+	Certificates string `yaml:"certificates"`
+}
+
+// TrustStoreCertificates returns PEM encoded certificates by trust store reference (<type>:<name>)
+func (n notation) TrustStoreCertificates() map[string]string {
+	stores := map[string]string{}
+	for _, store := range n.TrustStores {
+		stores[fmt.Sprintf("%s:%s", store.Type, store.Name)] = store.Certificates
+	}
+	return stores
+}
+
+type registryMirror struct {
+	Mirror   string `yaml:"mirror"`
+	Upstream string `yaml:"upstream"`
+	// DigestSource is the registry the image digest is fetched from: mirror, upstream or empty for the image registry
+	DigestSource string `yaml:"digestSource"`
+}
+
+type cache struct {
+	Enabled     bool          `yaml:"enabled"`
+	PositiveTTL time.Duration `yaml:"positiveTTL"`
+	NegativeTTL time.Duration `yaml:"negativeTTL"`
+	TagTTL      time.Duration `yaml:"tagTTL"`
+	MaxSize     int           `yaml:"maxSize"`
 }
 
 type admission struct {
@@ -22,6 +77,7 @@ type admission struct {
 	Timeout         time.Duration `yaml:"timeout"`
 	Port            int           `yaml:"port"`
 	StrictMode      bool          `yaml:"strictMode"`
+	DigestPinning   string        `yaml:"digestPinning"`
 }
 
 type operator struct {
@@ -29,13 +85,42 @@ type operator struct {
 	HealthProbeBindAddress    string        `yaml:"healthProbeBindAddress"`
 	LeaderElect               bool          `yaml:"leaderElect"`
 	PodReconcilerRequeueAfter time.Duration `yaml:"podReconcilerRequeueAfter"`
+	Revalidation              revalidation  `yaml:"revalidation"`
+}
+
+type revalidation struct {
+	// Interval of revalidation of successfully validated pods, they aren't revalidated periodically when it's zero
+	Interval time.Duration `yaml:"interval"`
+	// Jitter is the maximum fraction of the interval randomly added to it
+	Jitter float64 `yaml:"jitter"`
+	// RateLimit is the maximum number of revalidations per second, it's unlimited when it's zero
+	RateLimit float64 `yaml:"rateLimit"`
+	// Action is applied to pods which fail the revalidation: none, annotate or evict
+	Action string `yaml:"action"`
+}
+
+type tracing struct {
+	Enabled       bool    `yaml:"enabled"`
+	Endpoint      string  `yaml:"endpoint"`
+	Insecure      bool    `yaml:"insecure"`
+	SamplingRatio float64 `yaml:"samplingRatio"`
 }
 
-type config struct {
-	Notary    notary    `yaml:"notary"`
-	Admission admission `yaml:"admission"`
-	Operator  operator  `yaml:"operator"`
-	Logging   logging   `yaml:"logging"`
+// Config is the configuration of the warden operator and admission
+type Config struct {
+	Verifier string `yaml:"verifier"`
+	// DeniedImages is a comma-separated list of images rejected in all namespaces with enabled validation
+	DeniedImages string `yaml:"deniedImages"`
+	// RegistryMirrors map mirror repositories to the upstream repositories holding the trust data
+	RegistryMirrors []registryMirror `yaml:"registryMirrors"`
+	Notary          notary           `yaml:"notary"`
+	Cosign          cosign           `yaml:"cosign"`
+	Notation        notation         `yaml:"notation"`
+	Cache           cache            `yaml:"cache"`
+	Admission       admission        `yaml:"admission"`
+	Operator        operator         `yaml:"operator"`
+	Logging         logging          `yaml:"logging"`
+	Tracing         tracing          `yaml:"tracing"`
 }
 
 type logging struct {
@@ -43,7 +128,9 @@ type logging struct {
 	Format string `yaml:"format"`
 }
 
-func Load(path string) (*config, error) {
+// Load reads the configuration file, overrides its settings with the WARDEN_* environment variables
+// and validates the result. Unknown fields of the file are rejected, so typos don't leave the defaults silently
+func Load(path string) (*Config, error) {
 	config := defaultConfig()
 
 	sanitizedPath, err := filepath.Abs(path)
@@ -56,16 +143,47 @@ func Load(path string) (*config, error) {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(yamlFile, config)
-	return config, err
+	decoder := yaml.NewDecoder(bytes.NewReader(yamlFile))
+	decoder.KnownFields(true)
+	// empty file has no document to decode
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, errors.Wrapf(err, "while decoding config file %s", sanitizedPath)
+	}
+
+	if err := overrideFromEnv(config, os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
-func defaultConfig() *config {
-	return &config{
+func defaultConfig() *Config {
+	return &Config{
+		Verifier: "notary",
 		Notary: notary{
-			URL:     "https://signing-dev.repositories.cloud.sap",
+			Timeout: time.Second * 30,
+			TrustCache: trustCache{
+				Enabled:         true,
+				MaxSize:         100,
+				RefreshInterval: time.Minute,
+			},
+		},
+		Cosign: cosign{
 			Timeout: time.Second * 30,
 		},
+		Notation: notation{
+			Timeout: time.Second * 30,
+		},
+		Cache: cache{
+			Enabled:     true,
+			PositiveTTL: time.Minute * 5,
+			NegativeTTL: time.Minute,
+			TagTTL:      time.Second * 10,
+			MaxSize:     1000,
+		},
 		Admission: admission{
 			SystemNamespace: "default",
 			ServiceName:     "warden-admission",
@@ -73,16 +191,29 @@ func defaultConfig() *config {
 			Port:            8443,
 			Timeout:         time.Second * 2,
 			StrictMode:      false,
+			DigestPinning:   "disabled",
 		},
 		Operator: operator{
 			MetricsBindAddress:        ":8080",
 			HealthProbeBindAddress:    ":8081",
 			LeaderElect:               false,
 			PodReconcilerRequeueAfter: time.Minute * 60,
+			Revalidation: revalidation{
+				Interval:  0,
+				Jitter:    0.1,
+				RateLimit: 10,
+				Action:    "none",
+			},
 		},
 		Logging: logging{
 			Level:  "info",
 			Format: "text",
 		},
+		Tracing: tracing{
+			Enabled:       false,
+			Endpoint:      "localhost:4318",
+			Insecure:      false,
+			SamplingRatio: 1,
+		},
 	}
 }
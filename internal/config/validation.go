@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/logger"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+)
+
+// Validate returns error describing all invalid settings of the configuration
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	switch c.Verifier {
+	case pkg.VerifierNotary, pkg.VerifierCosign, pkg.VerifierNotation:
+	default:
+		problems = append(problems, fmt.Sprintf("verifier: unsupported verifier %q", c.Verifier))
+	}
+	check(validateImageRules("deniedImages", validate.ValidateDeniedImages(validate.ParseDeniedImages(c.DeniedImages))))
+	check(validateImageRules("registryMirrors", validate.ValidateRegistryMirrors(c.Mirrors())))
+
+	if c.Notary.URL == "" && c.Verifier == pkg.VerifierNotary {
+		problems = append(problems, "notary.URL: URL is required when the notary verifier is used")
+	} else if c.Notary.URL != "" {
+		check(validateURL("notary.URL", c.Notary.URL))
+	}
+	check(validatePositive("notary.timeout", c.Notary.Timeout))
+	check(validateImageRules("notary.allowedRegistries",
+		validate.ValidateAllowedRegistries(validate.ParseAllowedRegistries(c.Notary.AllowedRegistries))))
+	check(validateImageRules("notary.predefinedUserAllowedRegistries",
+		validate.ValidateAllowedRegistries(validate.ParseAllowedRegistries(c.Notary.PredefinedUserAllowedRegistries))))
+	if c.Notary.TrustCache.Enabled {
+		check(validatePositive("notary.trustCache.maxSize", c.Notary.TrustCache.MaxSize))
+	} else {
+		check(validateNotNegative("notary.trustCache.maxSize", c.Notary.TrustCache.MaxSize))
+	}
+	check(validateNotNegative("notary.trustCache.refreshInterval", c.Notary.TrustCache.RefreshInterval))
+
+	check(validatePositive("cosign.timeout", c.Cosign.Timeout))
+	check(validatePositive("notation.timeout", c.Notation.Timeout))
+
+	check(validateNotNegative("cache.positiveTTL", c.Cache.PositiveTTL))
+	check(validateNotNegative("cache.negativeTTL", c.Cache.NegativeTTL))
+	check(validateNotNegative("cache.tagTTL", c.Cache.TagTTL))
+	check(validateNotNegative("cache.maxSize", c.Cache.MaxSize))
+
+	check(validatePositive("admission.timeout", c.Admission.Timeout))
+	if c.Admission.Port <= 0 || c.Admission.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("admission.port: invalid port %d", c.Admission.Port))
+	}
+	if _, err := helpers.ParseDigestPinningMode(c.Admission.DigestPinning); err != nil {
+		problems = append(problems, "admission.digestPinning: "+err.Error())
+	}
+
+	check(validateNotNegative("operator.podReconcilerRequeueAfter", c.Operator.PodReconcilerRequeueAfter))
+	check(validateNotNegative("operator.revalidation.interval", c.Operator.Revalidation.Interval))
+	if c.Operator.Revalidation.Jitter < 0 || c.Operator.Revalidation.Jitter > 1 {
+		problems = append(problems, fmt.Sprintf("operator.revalidation.jitter: jitter %v is not between 0 and 1", c.Operator.Revalidation.Jitter))
+	}
+	if c.Operator.Revalidation.RateLimit < 0 {
+		problems = append(problems, fmt.Sprintf("operator.revalidation.rateLimit: must not be negative, got %v", c.Operator.Revalidation.RateLimit))
+	}
+	switch c.Operator.Revalidation.Action {
+	case pkg.RevalidationActionNone, pkg.RevalidationActionAnnotate, pkg.RevalidationActionEvict:
+	default:
+		problems = append(problems, fmt.Sprintf("operator.revalidation.action: unsupported action %q", c.Operator.Revalidation.Action))
+	}
+
+	if _, err := logger.MapLevel(c.Logging.Level); err != nil {
+		problems = append(problems, "logging.level: "+err.Error())
+	}
+	if _, err := logger.MapFormat(c.Logging.Format); err != nil {
+		problems = append(problems, "logging.format: "+err.Error())
+	}
+
+	if c.Tracing.Enabled && c.Tracing.Endpoint == "" {
+		problems = append(problems, "tracing.endpoint: endpoint is required when tracing is enabled")
+	}
+	if c.Tracing.SamplingRatio < 0 || c.Tracing.SamplingRatio > 1 {
+		problems = append(problems, fmt.Sprintf("tracing.samplingRatio: ratio %v is not between 0 and 1", c.Tracing.SamplingRatio))
+	}
+
+	if len(problems) != 0 {
+		return errors.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Mirrors returns the registry mirrors in the form used by the validators
+func (c *Config) Mirrors() validate.RegistryMirrors {
+	mirrors := validate.RegistryMirrors{}
+	for _, mirror := range c.RegistryMirrors {
+		mirrors = append(mirrors, validate.RegistryMirror{
+			Mirror:       mirror.Mirror,
+			Upstream:     mirror.Upstream,
+			DigestSource: mirror.DigestSource,
+		})
+	}
+	return mirrors
+}
+
+func validateImageRules(key string, err error) error {
+	if err != nil {
+		return errors.Wrap(err, key)
+	}
+	return nil
+}
+
+func validateURL(key, value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return errors.Wrap(err, key)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf("%s: %q is not a http(s) URL", key, value)
+	}
+	return nil
+}
+
+func validatePositive[T int | time.Duration](key string, value T) error {
+	if value <= 0 {
+		return errors.Errorf("%s: must be positive, got %v", key, value)
+	}
+	return nil
+}
+
+func validateNotNegative[T int | time.Duration](key string, value T) error {
+	if value < 0 {
+		return errors.Errorf("%s: must not be negative, got %v", key, value)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/pkg/errors"
+)
+
+// NewValidators creates the system validator and the factory of user validators from the configuration,
+// the notary repository pool and the verification cache are shared by validators of all configurations
+func (c *Config) NewValidators(notaryRepoPool *validate.NotaryRepoPool, verificationCache *validate.VerificationCache) (validate.PodValidator, validate.ValidatorSvcFactory, error) {
+	// the configuration is validated when it's loaded
+	predefinedUserAllowedRegistries := validate.ParseAllowedRegistries(c.Notary.PredefinedUserAllowedRegistries)
+	deniedImages := validate.ParseDeniedImages(c.DeniedImages)
+	registryMirrors := c.Mirrors()
+
+	var notationConfig *validate.NotationConfig
+	if c.Notation.TrustPolicy != "" {
+		var err error
+		notationConfig, err = validate.NewNotationConfig(c.Notation.TrustPolicy,
+			c.Notation.TrustStoreCertificates(), c.Notation.Timeout)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "while loading notation trust policy")
+		}
+	}
+
+	systemValidator, err := validate.NewSystemValidationSvc(validate.SystemValidationConfig{
+		Verifier:          c.Verifier,
+		NotaryURL:         c.Notary.URL,
+		NotaryTimeout:     c.Notary.Timeout,
+		AllowedRegistries: c.Notary.AllowedRegistries,
+		CosignPublicKeys:  c.Cosign.PublicKeys,
+		CosignTimeout:     c.Cosign.Timeout,
+		NotationTimeout:   c.Notation.Timeout,
+	}, validate.NewValidatorSvcFactoryWithConfig(validate.ValidatorSvcFactoryConfig{
+		NotationConfig:  notationConfig,
+		Cache:           verificationCache,
+		DeniedImages:    deniedImages,
+		RegistryMirrors: registryMirrors,
+		NotaryRepoPool:  notaryRepoPool,
+	}))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "while creating system validator")
+	}
+
+	userValidationSvcFactory := validate.NewValidatorSvcFactoryWithConfig(validate.ValidatorSvcFactoryConfig{
+		PredefinedAllowedRegistries: predefinedUserAllowedRegistries,
+		NotationConfig:              notationConfig,
+		Cache:                       verificationCache,
+		DeniedImages:                deniedImages,
+		RegistryMirrors:             registryMirrors,
+		NotaryRepoPool:              notaryRepoPool,
+	})
+	return systemValidator, userValidationSvcFactory, nil
+}
@@ -1,14 +1,25 @@
 package config
 
 import (
+	"os"
+	"path"
+	"reflect"
+
 	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
-	"os"
-	"path"
 )
 
-func Watch(filePath string, log *zap.SugaredLogger) error {
+// exit is replaced in tests
+var exit = os.Exit
+
+// ReloadFunc applies the changed configuration to the running components,
+// returned error means the configuration is invalid and the previous one is kept
+type ReloadFunc func(cfg *Config) error
+
+// Watch reloads the configuration when the file changes. Invalid configuration is rejected and the current one is kept.
+// The process is restarted when settings applied only on start, e.g. ports or the certificate secret, are changed.
+func Watch(filePath string, current *Config, reload ReloadFunc, log *zap.SugaredLogger) error {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return errors.Wrap(err, "while creating file watcher")
@@ -23,11 +34,10 @@ func Watch(filePath string, log *zap.SugaredLogger) error {
 			select {
 			case event := <-watcher.Events:
 				log.Debugf("event name: %s, op: %s", event.Name, event.Op)
-				log.Info("Config changed, restarting")
-				os.Exit(0)
+				current = reloadConfig(filePath, current, reload, log)
 			case watchErr := <-watcher.Errors:
 				log.Error(watchErr.Error())
-				os.Exit(1)
+				exit(1)
 			}
 		}
 	}()
@@ -40,3 +50,83 @@ func Watch(filePath string, log *zap.SugaredLogger) error {
 	}
 	return nil
 }
+
+// reloadConfig returns configuration used after the change of the file
+func reloadConfig(filePath string, current *Config, reload ReloadFunc, log *zap.SugaredLogger) *Config {
+	changed, err := Load(filePath)
+	if err != nil {
+		log.Errorf("invalid config, keeping the current one: %s", err.Error())
+		return current
+	}
+	if reflect.DeepEqual(changed, current) {
+		return current
+	}
+	if !reflect.DeepEqual(changed.restartSettings(), current.restartSettings()) {
+		log.Info("Config changed, restarting")
+		exit(0)
+		return current
+	}
+	if err := reload(changed); err != nil {
+		log.Errorf("invalid config, keeping the current one: %s", err.Error())
+		return current
+	}
+	log.Info("config reloaded")
+	return changed
+}
+
+// restartSettings returns settings which are applied only when the process starts
+func (c *Config) restartSettings() any {
+	return struct {
+		SystemNamespace        string
+		ServiceName            string
+		SecretName             string
+		Port                   int
+		MetricsBindAddress     string
+		HealthProbeBindAddress string
+		LeaderElect            bool
+		LogFormat              string
+		Tracing                tracing
+		Cache                  cache
+		TrustCache             trustCache
+	}{
+		SystemNamespace:        c.Admission.SystemNamespace,
+		ServiceName:            c.Admission.ServiceName,
+		SecretName:             c.Admission.SecretName,
+		Port:                   c.Admission.Port,
+		MetricsBindAddress:     c.Operator.MetricsBindAddress,
+		HealthProbeBindAddress: c.Operator.HealthProbeBindAddress,
+		LeaderElect:            c.Operator.LeaderElect,
+		LogFormat:              c.Logging.Format,
+		Tracing:                c.Tracing,
+		Cache:                  c.Cache,
+		TrustCache:             c.Notary.TrustCache,
+	}
+}
+
+// TrustChanged returns true when the settings deciding whether images are trusted differ from the previous configuration,
+// so images of running pods have to be revalidated
+func (c *Config) TrustChanged(previous *Config) bool {
+	return !reflect.DeepEqual(c.trustSettings(), previous.trustSettings())
+}
+
+func (c *Config) trustSettings() any {
+	return struct {
+		Verifier                        string
+		DeniedImages                    string
+		RegistryMirrors                 []registryMirror
+		NotaryURL                       string
+		AllowedRegistries               string
+		PredefinedUserAllowedRegistries string
+		CosignPublicKeys                string
+		Notation                        notation
+	}{
+		Verifier:                        c.Verifier,
+		DeniedImages:                    c.DeniedImages,
+		RegistryMirrors:                 c.RegistryMirrors,
+		NotaryURL:                       c.Notary.URL,
+		AllowedRegistries:               c.Notary.AllowedRegistries,
+		PredefinedUserAllowedRegistries: c.Notary.PredefinedUserAllowedRegistries,
+		CosignPublicKeys:                c.Cosign.PublicKeys,
+		Notation:                        c.Notation,
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const watchedConfig = `
+notary:
+  URL: "https://notary.io"
+admission:
+  port: 8443
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func TestReloadConfig(t *testing.T) {
+	log := zap.NewNop().Sugar()
+
+	tests := []struct {
+		name            string
+		content         string
+		reloadErr       error
+		expectedURL     string
+		expectedReload  bool
+		expectedRestart bool
+	}{
+		{
+			name:           "reload changed config",
+			content:        "notary:\n  URL: \"https://other-notary.io\"\nadmission:\n  port: 8443\n",
+			expectedURL:    "https://other-notary.io",
+			expectedReload: true,
+		},
+		{
+			name:        "skip unchanged config",
+			content:     watchedConfig,
+			expectedURL: "https://notary.io",
+		},
+		{
+			name:        "keep current config when file is invalid",
+			content:     "notary: [",
+			expectedURL: "https://notary.io",
+		},
+		{
+			name:           "keep current config when it's rejected",
+			content:        "notary:\n  URL: \"https://other-notary.io\"\nadmission:\n  port: 8443\n",
+			reloadErr:      errors.New("invalid verifier"),
+			expectedURL:    "https://notary.io",
+			expectedReload: true,
+		},
+		{
+			name:            "restart when port is changed",
+			content:         "notary:\n  URL: \"https://notary.io\"\nadmission:\n  port: 9443\n",
+			expectedURL:     "https://notary.io",
+			expectedRestart: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			writeConfig(t, path, watchedConfig)
+			current, err := Load(path)
+			require.NoError(t, err)
+			writeConfig(t, path, tt.content)
+
+			restarted := false
+			exit = func(int) { restarted = true }
+			defer func() { exit = os.Exit }()
+			reloaded := false
+			reload := func(cfg *Config) error {
+				reloaded = true
+				return tt.reloadErr
+			}
+
+			//WHEN
+			cfg := reloadConfig(path, current, reload, log)
+
+			//THEN
+			require.Equal(t, tt.expectedURL, cfg.Notary.URL)
+			require.Equal(t, tt.expectedReload, reloaded)
+			require.Equal(t, tt.expectedRestart, restarted)
+		})
+	}
+}
+
+func TestWatch(t *testing.T) {
+	//GIVEN
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, watchedConfig)
+	current, err := Load(path)
+	require.NoError(t, err)
+
+	reloaded := make(chan *Config, 10)
+	err = Watch(path, current, func(cfg *Config) error {
+		reloaded <- cfg
+		return nil
+	}, zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	//WHEN
+	// the file is replaced like the one mounted from the ConfigMap, so it's never read partially written
+	tmpPath := filepath.Join(filepath.Dir(path), "config.yaml.tmp")
+	writeConfig(t, tmpPath, "notary:\n  URL: \"https://other-notary.io\"\nadmission:\n  port: 8443\n")
+	require.NoError(t, os.Rename(tmpPath, path))
+
+	//THEN
+	select {
+	case cfg := <-reloaded:
+		require.Equal(t, "https://other-notary.io", cfg.Notary.URL)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+}
+
+func TestConfig_TrustChanged(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(cfg *Config)
+		expected bool
+	}{
+		{
+			name:     "unchanged config",
+			change:   func(cfg *Config) {},
+			expected: false,
+		},
+		{
+			name:     "changed log level",
+			change:   func(cfg *Config) { cfg.Logging.Level = "debug" },
+			expected: false,
+		},
+		{
+			name:     "changed notary URL",
+			change:   func(cfg *Config) { cfg.Notary.URL = "https://other-notary.io" },
+			expected: true,
+		},
+		{
+			name:     "changed denied images",
+			change:   func(cfg *Config) { cfg.DeniedImages = "registry.io/revoked" },
+			expected: true,
+		},
+		{
+			name:     "changed cosign public keys",
+			change:   func(cfg *Config) { cfg.Cosign.PublicKeys = "key" },
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			previous := defaultConfig()
+			changed := defaultConfig()
+			tt.change(changed)
+
+			//WHEN
+			trustChanged := changed.TrustChanged(previous)
+
+			//THEN
+			require.Equal(t, tt.expected, trustChanged)
+		})
+	}
+}
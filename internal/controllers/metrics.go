@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	requeueReasonValidationPending = "validation_pending"
+	requeueReasonLabelingFailed    = "labeling_failed"
+	requeueReasonError             = "error"
+	requeueReasonRevalidation      = "revalidation"
+	requeueReasonRateLimited       = "rate_limited"
+)
+
+var (
+	podReconcilerRequeues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "warden_pod_reconciler_requeues_total",
+			Help: "Number of pod reconciliations which were requeued, partitioned by reason (validation_pending, labeling_failed, error, revalidation, rate_limited).",
+		},
+		[]string{"reason"},
+	)
+	podValidationLost = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "warden_pod_validation_lost_total",
+			Help: "Number of pods which failed the revalidation after they were validated successfully.",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(podReconcilerRequeues, podValidationLost)
+}
@@ -12,6 +12,7 @@ import (
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,6 +22,8 @@ type Reconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    *zap.SugaredLogger
+	// Recorder reports relabeled pods on the namespace, events are not reported when it's nil
+	Recorder record.EventRecorder
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -36,6 +39,7 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 //+kubebuilder:rbac:groups="",resources=pods,verbs=list;update
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -83,6 +87,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	logger.Debugf("%d/%d pod[s] labeled", labelCount, len(pods.Items))
+	r.recordLabelingEvent(&instance, labelCount, len(pods.Items))
 
 	result := ctrl.Result{
 		Requeue: len(pods.Items) != labelCount,
@@ -92,3 +97,17 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	return result, nil
 }
+
+// recordLabelingEvent reports on the namespace how many pods are going to be validated
+func (r *Reconciler) recordLabelingEvent(ns *corev1.Namespace, labelCount, podCount int) {
+	if r.Recorder == nil || podCount == 0 {
+		return
+	}
+	if labelCount != podCount {
+		r.Recorder.Eventf(ns, corev1.EventTypeWarning, warden.EventReasonPodsLabelingFailed,
+			"%d/%d pods couldn't be labeled for validation", podCount-labelCount, podCount)
+		return
+	}
+	r.Recorder.Eventf(ns, corev1.EventTypeNormal, warden.EventReasonPodsValidationScheduled,
+		"%d pods were labeled for validation", podCount)
+}
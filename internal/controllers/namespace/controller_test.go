@@ -13,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -152,3 +153,48 @@ func Test_NamespaceReconcile(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func Test_recordLabelingEvent(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: validatableNs}}
+
+	tests := []struct {
+		name           string
+		labelCount     int
+		podCount       int
+		expectedEvents []string
+	}{
+		{
+			name:           "all pods labeled",
+			labelCount:     3,
+			podCount:       3,
+			expectedEvents: []string{"Normal PodsValidationScheduled 3 pods were labeled for validation"},
+		},
+		{
+			name:           "some pods not labeled",
+			labelCount:     1,
+			podCount:       3,
+			expectedEvents: []string{"Warning PodsLabelingFailed 2/3 pods couldn't be labeled for validation"},
+		},
+		{
+			name: "namespace without pods",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			recorder := record.NewFakeRecorder(1)
+			r := &Reconciler{Recorder: recorder}
+
+			//WHEN
+			r.recordLabelingEvent(ns, tt.labelCount, tt.podCount)
+
+			//THEN
+			close(recorder.Events)
+			var events []string
+			for event := range recorder.Events {
+				events = append(events, event)
+			}
+			require.Equal(t, tt.expectedEvents, events)
+		})
+	}
+}
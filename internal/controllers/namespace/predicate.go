@@ -80,6 +80,9 @@ func userValidationAnnotationsUpdated(oldAnnotations, newAnnotations, newLabels
 		warden.NamespaceAllowedRegistriesAnnotation,
 		warden.NamespaceNotaryTimeoutAnnotation,
 		warden.NamespaceStrictModeAnnotation,
+		warden.NamespaceVerifierAnnotation,
+		warden.NamespaceCosignPublicKeysAnnotation,
+		warden.NamespaceDeniedImagesAnnotation,
 	} {
 		oldValue := oldAnnotations[key]
 		newValue := newAnnotations[key]
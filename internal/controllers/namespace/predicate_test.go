@@ -112,6 +112,17 @@ func Test_nsUpdated(t *testing.T) {
 					Annotations: map[string]string{warden.NamespaceStrictModeAnnotation: "false"}}}},
 			want: true,
 		},
+		{
+			name: "ns updated - changed user validation annotations (verifier) value for user validation",
+			event: event.UpdateEvent{
+				ObjectOld: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{warden.NamespaceValidationLabel: warden.NamespaceValidationUser},
+					Annotations: map[string]string{warden.NamespaceVerifierAnnotation: warden.VerifierNotary}}},
+				ObjectNew: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{warden.NamespaceValidationLabel: warden.NamespaceValidationUser},
+					Annotations: map[string]string{warden.NamespaceVerifierAnnotation: warden.VerifierCosign}}}},
+			want: true,
+		},
 		{
 			name: "ns updated - added user validation annotations (notary url) value for user validation",
 			event: event.UpdateEvent{
@@ -18,25 +18,56 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
+	"math"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kyma-project/warden/api/v1alpha1"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/internal/policy"
 
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 type PodReconcilerConfig struct {
 	RequeueAfter time.Duration
+	// RevalidationInterval is the period of revalidation of successfully validated pods,
+	// they aren't revalidated periodically when it's zero
+	RevalidationInterval time.Duration
+	// RevalidationJitter is the maximum fraction of the interval randomly added to it,
+	// so pods validated at once aren't revalidated at once
+	RevalidationJitter float64
+	// RevalidationRateLimit is the maximum number of revalidations of successfully validated pods per second,
+	// they aren't limited when it's zero
+	RevalidationRateLimit float64
+	// RevalidationAction is applied to pods which fail the revalidation after they were validated successfully
+	RevalidationAction string
 }
 
 // PodReconciler reconciles a Pod object
@@ -47,7 +78,14 @@ type PodReconciler struct {
 	systemValidator          validate.PodValidator
 	userValidationSvcFactory validate.ValidatorSvcFactory
 	baseLogger               *zap.SugaredLogger
-	PodReconcilerConfig
+	policyResolver           policy.Resolver
+	recorder                 record.EventRecorder
+	configMu                 sync.RWMutex
+	config                   PodReconcilerConfig
+	revalidationLimiter      *rate.Limiter
+	revalidationSlotsMu      sync.Mutex
+	revalidationSlots        map[types.NamespacedName]time.Time
+	revalidations            chan event.GenericEvent
 }
 
 func NewPodReconciler(client client.Client, reader client.Reader, scheme *runtime.Scheme,
@@ -60,15 +98,53 @@ func NewPodReconciler(client client.Client, reader client.Reader, scheme *runtim
 		systemValidator:          validator,
 		userValidationSvcFactory: userValidationSvcFactory,
 		baseLogger:               logger,
-		PodReconcilerConfig:      reconcileCfg,
+		config:                   reconcileCfg,
+		revalidationLimiter:      rate.NewLimiter(revalidationLimit(reconcileCfg.RevalidationRateLimit)),
+		revalidationSlots:        map[types.NamespacedName]time.Time{},
+		revalidations:            make(chan event.GenericEvent),
 	}
 }
 
+// Reload replaces the configuration used by the next reconciliations
+func (r *PodReconciler) Reload(cfg PodReconcilerConfig) {
+	r.configMu.Lock()
+	defer r.configMu.Unlock()
+	r.config = cfg
+	limit, burst := revalidationLimit(cfg.RevalidationRateLimit)
+	r.revalidationLimiter.SetLimit(limit)
+	r.revalidationLimiter.SetBurst(burst)
+}
+
+// revalidationLimit returns the limit and the burst of revalidations, they aren't limited when the rate limit is zero
+func revalidationLimit(rateLimit float64) (rate.Limit, int) {
+	if rateLimit <= 0 {
+		return rate.Inf, 1
+	}
+	return rate.Limit(rateLimit), int(math.Max(1, rateLimit))
+}
+
+func (r *PodReconciler) reconcilerConfig() PodReconcilerConfig {
+	r.configMu.RLock()
+	defer r.configMu.RUnlock()
+	return r.config
+}
+
+// WithPolicyResolver enables validation with ImagePolicies and ClusterImagePolicies
+func (r *PodReconciler) WithPolicyResolver(resolver policy.Resolver) *PodReconciler {
+	r.policyResolver = resolver
+	return r
+}
+
+// WithEventRecorder enables events reporting validation results on pods
+func (r *PodReconciler) WithEventRecorder(recorder record.EventRecorder) *PodReconciler {
+	r.recorder = recorder
+	return r
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Pod{}).
-		WithEventFilter(predicate.Funcs{
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&corev1.Pod{}, builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(e event.CreateEvent) bool {
 				return r.isValidationEnabledForNS(e.Object.GetNamespace())
 			},
@@ -98,88 +174,318 @@ func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			GenericFunc: func(genericEvent event.GenericEvent) bool {
 				return false
 			},
-		}).
-		Complete(r)
+		})).
+		WatchesRawSource(source.Channel(r.revalidations, &handler.EnqueueRequestForObject{}))
+	if r.policyResolver != nil {
+		// pods are revalidated when the image policies applied to them change
+		b = b.
+			Watches(&v1alpha1.ImagePolicy{}, handler.EnqueueRequestsFromMapFunc(r.policyPods),
+				builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+			Watches(&v1alpha1.ClusterImagePolicy{}, handler.EnqueueRequestsFromMapFunc(r.policyPods),
+				builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+	return b.Complete(r)
+}
+
+// RevalidateAll schedules validation of all pods in namespaces with enabled validation, e.g. when the trust configuration
+// is changed. Revalidations of successfully validated pods are rate limited
+func (r *PodReconciler) RevalidateAll(ctx context.Context) error {
+	requests, err := r.podRequests(ctx, "")
+	if err != nil {
+		return errors.Wrap(err, "while listing pods to revalidate")
+	}
+	for _, req := range requests {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace, Name: req.Name}}
+		select {
+		case r.revalidations <- event.GenericEvent{Object: pod}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// policyPods returns pods affected by the image policy, cluster image policies affect pods in all namespaces
+func (r *PodReconciler) policyPods(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests, err := r.podRequests(ctx, obj.GetNamespace())
+	if err != nil {
+		r.baseLogger.With("policy", client.ObjectKeyFromObject(obj)).Errorf("while listing pods to revalidate: %s", err.Error())
+		return nil
+	}
+	return requests
+}
+
+// podRequests returns requests of all pods in the namespace with enabled validation,
+// or in all such namespaces when the namespace is empty
+func (r *PodReconciler) podRequests(ctx context.Context, namespace string) ([]reconcile.Request, error) {
+	var namespaces []corev1.Namespace
+	if namespace == "" {
+		var nsList corev1.NamespaceList
+		if err := r.client.List(ctx, &nsList); err != nil {
+			return nil, err
+		}
+		namespaces = nsList.Items
+	} else {
+		var ns corev1.Namespace
+		if err := r.client.Get(ctx, client.ObjectKey{Name: namespace}, &ns); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		namespaces = []corev1.Namespace{ns}
+	}
+
+	var requests []reconcile.Request
+	for i := range namespaces {
+		if !validate.IsValidationEnabledForNS(&namespaces[i]) {
+			continue
+		}
+		var pods corev1.PodList
+		if err := r.client.List(ctx, &pods, client.InNamespace(namespaces[i].Name)); err != nil {
+			return nil, err
+		}
+		for j := range pods.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&pods.Items[j])})
+		}
+	}
+	return requests, nil
 }
 
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
 //+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reqUUID := uuid.New().String()
 	logger := r.baseLogger.With("req", req).With("req-id", reqUUID)
+	ctx, span := tracing.StartSpan(ctx, "reconcile pod", attribute.String("pod", req.String()))
+	defer span.End()
 	ctxLogger := helpers.LoggerToContext(ctx, logger)
 	logger.Debugf("reconciliation started")
 
 	var pod corev1.Pod
 	if err := r.client.Get(ctxLogger, req.NamespacedName, &pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.forgetRevalidation(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	result, err := r.checkPod(ctxLogger, &pod)
+	cfg := r.reconcilerConfig()
+	wasValid := pod.Labels[pkg.PodValidationLabel] == pkg.ValidationStatusSuccess
+	if wasValid && imagesChangedSinceValidation(&pod) {
+		// changed images are validated right away, the rate limit applies only to revalidations
+		r.forgetRevalidation(req.NamespacedName)
+	} else if wasValid {
+		// revalidations are spread, so trust changes don't trigger validation of all pods at once
+		if delay := r.revalidationDelay(req.NamespacedName); delay > 0 {
+			podReconcilerRequeues.WithLabelValues(requeueReasonRateLimited).Inc()
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+	}
+
+	validationResult, err := r.checkPod(ctxLogger, &pod)
 	if err != nil {
+		podReconcilerRequeues.WithLabelValues(requeueReasonError).Inc()
 		return ctrl.Result{}, err
 	}
+	result := validationResult.Status
+	// events are recorded only for changed results, so revalidations don't repeat them
+	if validationChanged(&pod, validationResult) {
+		r.recordValidationEvent(&pod, validationResult)
+	}
 
-	shouldRetry := ctrl.Result{RequeueAfter: r.RequeueAfter}
+	shouldRetry := ctrl.Result{RequeueAfter: cfg.RequeueAfter}
+	requeueReason := requeueReasonValidationPending
 	switch result {
 	case validate.Valid:
 		logger.Info("pod validated successfully")
 		shouldRetry = ctrl.Result{}
+		if cfg.RevalidationInterval > 0 {
+			shouldRetry.RequeueAfter = wait.Jitter(cfg.RevalidationInterval, cfg.RevalidationJitter)
+			requeueReason = requeueReasonRevalidation
+		}
 	case validate.Invalid:
 		logger.Info("pod validation failed")
 		shouldRetry = ctrl.Result{}
 	}
-	if err := r.labelPod(ctx, pod, result); err != nil {
+
+	validationLostAt := ""
+	if wasValid && result == validate.Invalid {
+		logger.Info("pod lost its valid status")
+		podValidationLost.Inc()
+		r.recordValidationLostEvent(&pod, validationResult)
+		if cfg.RevalidationAction == pkg.RevalidationActionAnnotate || cfg.RevalidationAction == pkg.RevalidationActionEvict {
+			validationLostAt = time.Now().UTC().Format(time.RFC3339)
+		}
+	}
+	if err := r.labelPod(ctx, pod, validationResult, validationLostAt); err != nil {
 		logger.Info("pod labeling failed ", "err", err.Error())
 		shouldRetry.Requeue = true
+		podReconcilerRequeues.WithLabelValues(requeueReasonLabelingFailed).Inc()
+		return shouldRetry, nil
+	}
+
+	// pods which couldn't be evicted keep the annotation, so their eviction is retried
+	lost := validationLostAt != "" || pod.Annotations[pkg.PodValidationLostAnnotation] != ""
+	if result == validate.Invalid && lost && cfg.RevalidationAction == pkg.RevalidationActionEvict {
+		if err := r.evictPod(ctx, &pod); err != nil {
+			logger.Info("pod eviction failed ", "err", err.Error())
+			r.recordEvictionFailedEvent(&pod, err)
+			shouldRetry.RequeueAfter = cfg.RequeueAfter
+			requeueReason = requeueReasonError
+		} else {
+			logger.Info("pod evicted")
+		}
+	}
+	if shouldRetry.RequeueAfter != 0 {
+		podReconcilerRequeues.WithLabelValues(requeueReason).Inc()
 	}
 	return shouldRetry, nil
 }
 
-func (r *PodReconciler) checkPod(ctx context.Context, pod *corev1.Pod) (validate.ValidationStatus, error) {
+// revalidationDelay returns how long the revalidation of the pod has to be postponed by the rate limit.
+// The time is reserved in the limiter once and kept in revalidationSlots, so the postponed pod is revalidated
+// when it's requeued instead of being postponed again
+func (r *PodReconciler) revalidationDelay(key types.NamespacedName) time.Duration {
+	r.revalidationSlotsMu.Lock()
+	defer r.revalidationSlotsMu.Unlock()
+	if slot, ok := r.revalidationSlots[key]; ok {
+		delay := time.Until(slot)
+		if delay <= 0 {
+			delete(r.revalidationSlots, key)
+		}
+		return delay
+	}
+	delay := r.revalidationLimiter.Reserve().Delay()
+	if delay > 0 {
+		r.revalidationSlots[key] = time.Now().Add(delay)
+	}
+	return delay
+}
+
+func (r *PodReconciler) forgetRevalidation(key types.NamespacedName) {
+	r.revalidationSlotsMu.Lock()
+	defer r.revalidationSlotsMu.Unlock()
+	delete(r.revalidationSlots, key)
+}
+
+func (r *PodReconciler) checkPod(ctx context.Context, pod *corev1.Pod) (validate.ValidationResult, error) {
 	var ns corev1.Namespace
 	if err := r.client.Get(ctx, client.ObjectKey{Name: pod.Namespace}, &ns); err != nil {
-		return validate.NoAction, err
+		return validate.ValidationResult{Status: validate.NoAction}, err
 	}
 
-	validator := r.systemValidator
-	if validate.IsUserValidationForNS(&ns) {
-		var err error
-		validator, err = validate.NewUserValidationSvc(&ns, r.userValidationSvcFactory)
-		if err != nil {
-			return validate.NoAction, err
+	validator, err := policy.NewPodValidator(ctx, r.policyResolver, &ns, func() (validate.PodValidator, error) {
+		if validate.IsUserValidationForNS(&ns) {
+			return validate.NewUserValidationSvc(&ns, r.userValidationSvcFactory)
 		}
+		return r.systemValidator, nil
+	})
+	if err != nil {
+		return validate.ValidationResult{Status: validate.NoAction}, err
 	}
 
 	imagePullCredentials, err := helpers.GetRemotePullCredentials(ctx, r.client, pod)
 	if err != nil {
-		return validate.NoAction, err
+		return validate.ValidationResult{Status: validate.NoAction}, err
 	}
 
 	result, err := validator.ValidatePod(ctx, pod, &ns, imagePullCredentials)
 	if err != nil {
-		return validate.NoAction, err
+		return validate.ValidationResult{Status: validate.NoAction}, err
+	}
+
+	return result, nil
+}
+
+// validationChanged returns true when the validation label or the validation report of the pod differs from the result
+func validationChanged(pod *corev1.Pod, result validate.ValidationResult) bool {
+	if pod.Labels[pkg.PodValidationLabel] != labelForValidationResult(result.Status) {
+		return true
+	}
+	report, err := result.Report()
+	return err != nil || !validate.SameReports(pod.Annotations[pkg.PodValidationReportAnnotation], report)
+}
+
+// recordValidationEvent reports the validation result on the pod, so it's visible in `kubectl describe pod`
+func (r *PodReconciler) recordValidationEvent(pod *corev1.Pod, result validate.ValidationResult) {
+	if r.recorder == nil {
+		return
+	}
+	images := strings.Join(result.InvalidImages, ", ")
+	switch result.Status {
+	case validate.Valid:
+		r.recorder.Event(pod, corev1.EventTypeNormal, pkg.EventReasonValidationSucceeded, "Pod images were verified successfully")
+	case validate.Invalid:
+		r.recorder.Eventf(pod, corev1.EventTypeWarning, pkg.EventReasonImageSignatureInvalid, "Pod images %s failed verification", images)
+	case validate.ServiceUnavailable:
+		r.recorder.Eventf(pod, corev1.EventTypeWarning, pkg.EventReasonNotaryUnavailable,
+			"Verification of pod images %s is pending, because the verification service is unavailable", images)
+	}
+}
+
+// recordValidationLostEvent reports that the pod failed the revalidation after it was validated successfully
+func (r *PodReconciler) recordValidationLostEvent(pod *corev1.Pod, result validate.ValidationResult) {
+	if r.recorder == nil {
+		return
+	}
+	r.recorder.Eventf(pod, corev1.EventTypeWarning, pkg.EventReasonValidationLost,
+		"Pod images %s failed revalidation after they were verified successfully", strings.Join(result.InvalidImages, ", "))
+}
+
+func (r *PodReconciler) recordEvictionFailedEvent(pod *corev1.Pod, err error) {
+	if r.recorder == nil {
+		return
 	}
+	r.recorder.Eventf(pod, corev1.EventTypeWarning, pkg.EventReasonPodEvictionFailed,
+		"Pod which failed revalidation couldn't be evicted: %s", err.Error())
+}
 
-	return result.Status, nil
+// evictPod evicts the pod respecting its disruption budget, so it's recreated and validated by the admission
+func (r *PodReconciler) evictPod(ctx context.Context, pod *corev1.Pod) error {
+	eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name}}
+	return client.IgnoreNotFound(r.client.SubResource("eviction").Create(ctx, pod, eviction))
 }
 
-func (r *PodReconciler) labelPod(ctx context.Context, pod corev1.Pod, result validate.ValidationStatus) error {
+// labelPod sets the validation label and persists the validation report in the pod annotation,
+// validationLostAt is set when the pod has to be annotated, because it lost its valid status
+func (r *PodReconciler) labelPod(ctx context.Context, pod corev1.Pod, result validate.ValidationResult, validationLostAt string) error {
 
-	resultLabel := labelForValidationResult(result)
+	resultLabel := labelForValidationResult(result.Status)
 	if resultLabel == "" {
 		return nil
 	}
-	if pod.Labels[pkg.PodValidationLabel] != resultLabel {
+	report, err := result.Report()
+	if err != nil {
+		return err
+	}
+	lostAnnotation := pod.Annotations[pkg.PodValidationLostAnnotation]
+	if validationLostAt != "" {
+		lostAnnotation = validationLostAt
+	} else if result.Status == validate.Valid {
+		lostAnnotation = ""
+	}
+	// the report isn't updated when only its messages change, so the patch doesn't trigger the next reconciliation
+	if pod.Labels[pkg.PodValidationLabel] != resultLabel || !validate.SameReports(pod.Annotations[pkg.PodValidationReportAnnotation], report) ||
+		pod.Annotations[pkg.PodValidationLostAnnotation] != lostAnnotation {
 		out := pod.DeepCopy()
 		if out.ObjectMeta.Labels == nil {
 			out.ObjectMeta.Labels = map[string]string{}
 		}
 		out.Labels[pkg.PodValidationLabel] = resultLabel
+		if out.ObjectMeta.Annotations == nil {
+			out.ObjectMeta.Annotations = map[string]string{}
+		}
+		out.Annotations[pkg.PodValidationReportAnnotation] = report
+		if lostAnnotation == "" {
+			delete(out.Annotations, pkg.PodValidationLostAnnotation)
+		} else {
+			out.Annotations[pkg.PodValidationLostAnnotation] = lostAnnotation
+		}
 		if err := r.client.Patch(ctx, out, client.MergeFrom(&pod)); client.IgnoreNotFound(err) != nil {
 			return err
 		}
@@ -203,6 +509,30 @@ func areImagesChanged(oldPod *corev1.Pod, newPod *corev1.Pod) bool {
 	return false
 }
 
+// imagesChangedSinceValidation returns true when the pod has images which aren't in its validation report,
+// e.g. an ephemeral container was added. Pods without the report were validated only by the admission,
+// so their images didn't change since the validation
+func imagesChangedSinceValidation(pod *corev1.Pod) bool {
+	encoded, ok := pod.Annotations[pkg.PodValidationReportAnnotation]
+	if !ok {
+		return false
+	}
+	var report validate.ValidationReport
+	if err := json.Unmarshal([]byte(encoded), &report); err != nil {
+		return true
+	}
+	validated := map[string]struct{}{}
+	for _, verdict := range report.Images {
+		validated[verdict.Image] = struct{}{}
+	}
+	for _, image := range getPodImages(pod) {
+		if _, ok := validated[image]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
 func getPodImages(pod *corev1.Pod) []string {
 	var result []string
 	for _, container := range pod.Spec.InitContainers {
@@ -211,6 +541,9 @@ func getPodImages(pod *corev1.Pod) []string {
 	for _, container := range pod.Spec.Containers {
 		result = append(result, container.Image)
 	}
+	for _, container := range pod.Spec.EphemeralContainers {
+		result = append(result, container.Image)
+	}
 	return result
 }
 
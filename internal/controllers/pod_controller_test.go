@@ -14,12 +14,15 @@ import (
 	"github.com/kyma-project/warden/internal/validate/mocks"
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -333,10 +336,374 @@ func TestReconcile_K8sOperationFails(t *testing.T) {
 
 }
 
+func TestReconcile_RecordsEvents(t *testing.T) {
+	imageValidator := mocks.NewImageValidatorService(t)
+	imageValidator.On("Validate", mock.Anything, validImage, mock.Anything).Return(nil).Maybe()
+	imageValidator.On("Validate", mock.Anything, invalidImage, mock.Anything).
+		Return(pkg.NewValidationFailedErr(errors.New("invalid"))).Maybe()
+	imageValidator.On("Validate", mock.Anything, unavailableImage, mock.Anything).
+		Return(pkg.NewUnknownResultErr(errors.New("notary is not available"))).Maybe()
+	podValidator := validate.NewPodValidator(imageValidator)
+
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "warden-enabled",
+		Labels: map[string]string{
+			pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled,
+		}},
+	}
+
+	tests := []struct {
+		name             string
+		image            string
+		expectedEvent    string
+		expectedRequeues float64
+	}{
+		{
+			name:          "valid image",
+			image:         validImage,
+			expectedEvent: "Normal ValidationSucceeded Pod images were verified successfully",
+		},
+		{
+			name:          "invalid image",
+			image:         invalidImage,
+			expectedEvent: "Warning ImageSignatureInvalid Pod images invalid failed verification",
+		},
+		{
+			name:             "unavailable notary",
+			image:            unavailableImage,
+			expectedEvent:    "Warning NotaryUnavailable Verification of pod images unavailable is pending, because the verification service is unavailable",
+			expectedRequeues: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "pod"},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: tt.image, Name: "container"}}}}
+			k8sClient := fake.NewClientBuilder().WithObjects(&ns, &pod).Build()
+			recorder := record.NewFakeRecorder(1)
+			reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+				PodReconcilerConfig{RequeueAfter: time.Minute}, test_helpers.NewTestZapLogger(t).Sugar()).
+				WithEventRecorder(recorder)
+			requeuesBefore := testutil.ToFloat64(podReconcilerRequeues.WithLabelValues(requeueReasonValidationPending))
+
+			//WHEN
+			_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: ns.Name, Name: pod.Name}})
+
+			//THEN
+			require.NoError(t, err)
+			require.Len(t, recorder.Events, 1)
+			require.Equal(t, tt.expectedEvent, <-recorder.Events)
+			require.Equal(t, requeuesBefore+tt.expectedRequeues,
+				testutil.ToFloat64(podReconcilerRequeues.WithLabelValues(requeueReasonValidationPending)))
+		})
+	}
+}
+
+func TestReconcile_RecordsEventsOnlyOnChange(t *testing.T) {
+	//GIVEN
+	imageValidator := mocks.NewImageValidatorService(t)
+	imageValidator.On("Validate", mock.Anything, invalidImage, mock.Anything).
+		Return(pkg.NewValidationFailedErr(errors.New("invalid"))).Twice()
+	podValidator := validate.NewPodValidator(imageValidator)
+
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "warden-enabled",
+		Labels: map[string]string{
+			pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled,
+		}},
+	}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "pod"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: invalidImage, Name: "container"}}}}
+	k8sClient := fake.NewClientBuilder().WithObjects(&ns, &pod).Build()
+	recorder := record.NewFakeRecorder(2)
+	reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+		PodReconcilerConfig{RequeueAfter: time.Minute}, test_helpers.NewTestZapLogger(t).Sugar()).
+		WithEventRecorder(recorder)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ns.Name, Name: pod.Name}}
+
+	//WHEN
+	_, err := reconciler.Reconcile(context.TODO(), req)
+	require.NoError(t, err)
+	_, err = reconciler.Reconcile(context.TODO(), req)
+
+	//THEN
+	require.NoError(t, err)
+	require.Len(t, recorder.Events, 1)
+	require.Equal(t, "Warning ImageSignatureInvalid Pod images invalid failed verification", <-recorder.Events)
+}
+
+func TestReconcile_PersistsValidationReport(t *testing.T) {
+	//GIVEN
+	imageValidator := mocks.NewImageValidatorService(t)
+	imageValidator.On("Validate", mock.Anything, validImage, mock.Anything).Return(nil)
+	imageValidator.On("Validate", mock.Anything, invalidImage, mock.Anything).
+		Return(pkg.NewValidationFailedErr(errors.New("invalid")))
+	podValidator := validate.NewPodValidator(imageValidator)
+
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "warden-enabled",
+		Labels: map[string]string{
+			pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled,
+		}},
+	}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "pod"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Image: validImage, Name: "valid"},
+			{Image: invalidImage, Name: "invalid"},
+		}}}
+	k8sClient := fake.NewClientBuilder().WithObjects(&ns, &pod).Build()
+	reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+		PodReconcilerConfig{RequeueAfter: time.Minute}, test_helpers.NewTestZapLogger(t).Sugar())
+
+	//WHEN
+	_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: ns.Name, Name: pod.Name}})
+
+	//THEN
+	require.NoError(t, err)
+	var labeledPod corev1.Pod
+	require.NoError(t, k8sClient.Get(context.TODO(), ctrlclient.ObjectKeyFromObject(&pod), &labeledPod))
+	require.Equal(t, pkg.ValidationStatusFailed, labeledPod.Labels[pkg.PodValidationLabel])
+	require.JSONEq(t, `{"status":"Invalid","images":[
+		{"image":"invalid","status":"Invalid","reason":"ValidationFailed","message":"notary validation error: invalid"},
+		{"image":"valid","status":"Valid","reason":"Verified"}]}`,
+		labeledPod.Annotations[pkg.PodValidationReportAnnotation])
+}
+
+func TestReconcile_KeepsReportWithChangedMessages(t *testing.T) {
+	//GIVEN
+	imageValidator := mocks.NewImageValidatorService(t)
+	imageValidator.On("Validate", mock.Anything, invalidImage, mock.Anything).
+		Return(pkg.NewValidationFailedErr(errors.New("request 2 failed")))
+	podValidator := validate.NewPodValidator(imageValidator)
+
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "warden-enabled",
+		Labels: map[string]string{
+			pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled,
+		}},
+	}
+	report := `{"status":"Invalid","images":[
+		{"image":"invalid","status":"Invalid","reason":"ValidationFailed","message":"notary validation error: request 1 failed"}]}`
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "pod",
+		Labels:      map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusFailed},
+		Annotations: map[string]string{pkg.PodValidationReportAnnotation: report}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: invalidImage, Name: "container"}}}}
+	k8sClient := fake.NewClientBuilder().WithObjects(&ns, &pod).Build()
+	var before corev1.Pod
+	require.NoError(t, k8sClient.Get(context.TODO(), ctrlclient.ObjectKeyFromObject(&pod), &before))
+	reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+		PodReconcilerConfig{RequeueAfter: time.Minute}, test_helpers.NewTestZapLogger(t).Sugar())
+
+	//WHEN
+	_, err := reconciler.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: ns.Name, Name: pod.Name}})
+
+	//THEN
+	require.NoError(t, err)
+	var after corev1.Pod
+	require.NoError(t, k8sClient.Get(context.TODO(), ctrlclient.ObjectKeyFromObject(&pod), &after))
+	require.Equal(t, before.ResourceVersion, after.ResourceVersion)
+	require.Equal(t, report, after.Annotations[pkg.PodValidationReportAnnotation])
+}
+
+func TestReconcile_Revalidation(t *testing.T) {
+	imageValidator := mocks.NewImageValidatorService(t)
+	imageValidator.On("Validate", mock.Anything, validImage, mock.Anything).Return(nil).Maybe()
+	imageValidator.On("Validate", mock.Anything, invalidImage, mock.Anything).
+		Return(pkg.NewValidationFailedErr(errors.New("invalid"))).Maybe()
+	podValidator := validate.NewPodValidator(imageValidator)
+
+	ns := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "warden-enabled",
+		Labels: map[string]string{
+			pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled,
+		}},
+	}
+	validatedPod := func(image string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: ns.Name, Name: "pod",
+			Labels: map[string]string{pkg.PodValidationLabel: pkg.ValidationStatusSuccess}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: image, Name: "container"}}}}
+	}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: ns.Name, Name: "pod"}}
+
+	t.Run("valid pod is revalidated after the jittered interval", func(t *testing.T) {
+		//GIVEN
+		k8sClient := fake.NewClientBuilder().WithObjects(&ns, validatedPod(validImage)).Build()
+		reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+			PodReconcilerConfig{RequeueAfter: time.Minute, RevalidationInterval: time.Hour, RevalidationJitter: 0.5},
+			test_helpers.NewTestZapLogger(t).Sugar())
+		requeuesBefore := testutil.ToFloat64(podReconcilerRequeues.WithLabelValues(requeueReasonRevalidation))
+
+		//WHEN
+		res, err := reconciler.Reconcile(context.TODO(), req)
+
+		//THEN
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, res.RequeueAfter, time.Hour)
+		require.LessOrEqual(t, res.RequeueAfter, 90*time.Minute)
+		require.Equal(t, requeuesBefore+1, testutil.ToFloat64(podReconcilerRequeues.WithLabelValues(requeueReasonRevalidation)))
+	})
+	t.Run("revalidation is postponed by the rate limit", func(t *testing.T) {
+		//GIVEN
+		k8sClient := fake.NewClientBuilder().WithObjects(&ns, validatedPod(validImage)).Build()
+		podValidator := mocks.NewPodValidator(t)
+		podValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validResult(validImage), nil).Once()
+		reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+			PodReconcilerConfig{RequeueAfter: time.Minute, RevalidationRateLimit: 0.001},
+			test_helpers.NewTestZapLogger(t).Sugar())
+		_, err := reconciler.Reconcile(context.TODO(), req)
+		require.NoError(t, err)
+
+		//WHEN
+		res, err := reconciler.Reconcile(context.TODO(), req)
+		requeuedRes, requeuedErr := reconciler.Reconcile(context.TODO(), req)
+
+		//THEN
+		require.NoError(t, err)
+		require.Greater(t, res.RequeueAfter, 900*time.Second)
+		require.LessOrEqual(t, res.RequeueAfter, 1000*time.Second)
+		// the pod keeps its reserved time, so it isn't postponed again
+		require.NoError(t, requeuedErr)
+		require.Greater(t, requeuedRes.RequeueAfter, time.Duration(0))
+		require.LessOrEqual(t, requeuedRes.RequeueAfter, res.RequeueAfter)
+	})
+	t.Run("changed images are validated regardless of the rate limit", func(t *testing.T) {
+		//GIVEN
+		k8sClient := fake.NewClientBuilder().WithObjects(&ns, validatedPod(validImage)).Build()
+		podValidator := mocks.NewPodValidator(t)
+		podValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validResult(validImage), nil).Once()
+		podValidator.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(validResult(validImage, "ephemeral:1.0"), nil).Once()
+		reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+			PodReconcilerConfig{RequeueAfter: time.Minute, RevalidationRateLimit: 0.001},
+			test_helpers.NewTestZapLogger(t).Sugar())
+		_, err := reconciler.Reconcile(context.TODO(), req)
+		require.NoError(t, err)
+		var pod corev1.Pod
+		require.NoError(t, k8sClient.Get(context.TODO(), req.NamespacedName, &pod))
+		pod.Spec.EphemeralContainers = []corev1.EphemeralContainer{{
+			EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debug", Image: "ephemeral:1.0"}}}
+		require.NoError(t, k8sClient.Update(context.TODO(), &pod))
+
+		//WHEN
+		res, err := reconciler.Reconcile(context.TODO(), req)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, reconcile.Result{}, res)
+		podValidator.AssertNumberOfCalls(t, "ValidatePod", 2)
+	})
+
+	tests := []struct {
+		name               string
+		action             string
+		expectedAnnotation bool
+		expectedEvicted    bool
+	}{
+		{
+			name:   "pod which lost its valid status is labeled",
+			action: pkg.RevalidationActionNone,
+		},
+		{
+			name:               "pod which lost its valid status is annotated",
+			action:             pkg.RevalidationActionAnnotate,
+			expectedAnnotation: true,
+		},
+		{
+			name:            "pod which lost its valid status is evicted",
+			action:          pkg.RevalidationActionEvict,
+			expectedEvicted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			pod := validatedPod(invalidImage)
+			k8sClient := fake.NewClientBuilder().WithObjects(&ns, pod).Build()
+			recorder := record.NewFakeRecorder(2)
+			reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, podValidator, nil,
+				PodReconcilerConfig{RequeueAfter: time.Minute, RevalidationAction: tt.action},
+				test_helpers.NewTestZapLogger(t).Sugar()).
+				WithEventRecorder(recorder)
+			lostBefore := testutil.ToFloat64(podValidationLost)
+
+			//WHEN
+			res, err := reconciler.Reconcile(context.TODO(), req)
+
+			//THEN
+			require.NoError(t, err)
+			require.Equal(t, reconcile.Result{}, res)
+			require.Equal(t, lostBefore+1, testutil.ToFloat64(podValidationLost))
+			require.Len(t, recorder.Events, 2)
+			require.Equal(t, "Warning ImageSignatureInvalid Pod images invalid failed verification", <-recorder.Events)
+			require.Equal(t, "Warning ValidationLost Pod images invalid failed revalidation after they were verified successfully", <-recorder.Events)
+
+			var revalidatedPod corev1.Pod
+			err = k8sClient.Get(context.TODO(), ctrlclient.ObjectKeyFromObject(pod), &revalidatedPod)
+			if tt.expectedEvicted {
+				require.True(t, apierrors.IsNotFound(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, pkg.ValidationStatusFailed, revalidatedPod.Labels[pkg.PodValidationLabel])
+			_, annotated := revalidatedPod.Annotations[pkg.PodValidationLostAnnotation]
+			require.Equal(t, tt.expectedAnnotation, annotated)
+		})
+	}
+}
+
+func validResult(images ...string) validate.ValidationResult {
+	result := validate.ValidationResult{Status: validate.Valid}
+	for _, image := range images {
+		result.Images = append(result.Images, validate.ImageVerdict{Image: image, Status: validate.Valid, Reason: validate.ReasonVerified})
+	}
+	return result
+}
+
+func TestPodReconciler_RevalidateAll(t *testing.T) {
+	//GIVEN
+	enabledNs := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: "warden-enabled",
+		Labels: map[string]string{
+			pkg.NamespaceValidationLabel: pkg.NamespaceValidationEnabled,
+		}},
+	}
+	disabledNs := corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "warden-disabled"}}
+	k8sClient := fake.NewClientBuilder().WithObjects(&enabledNs, &disabledNs,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: enabledNs.Name, Name: "pod-a"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: enabledNs.Name, Name: "pod-b"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: disabledNs.Name, Name: "pod"}},
+	).Build()
+	reconciler := NewPodReconciler(k8sClient, k8sClient, scheme.Scheme, mocks.NewPodValidator(t), nil,
+		PodReconcilerConfig{RequeueAfter: time.Minute}, test_helpers.NewTestZapLogger(t).Sugar())
+
+	revalidated := make(chan []string)
+	go func() {
+		var pods []string
+		for i := 0; i < 2; i++ {
+			pods = append(pods, ctrlclient.ObjectKeyFromObject((<-reconciler.revalidations).Object).String())
+		}
+		revalidated <- pods
+	}()
+
+	//WHEN
+	err := reconciler.RevalidateAll(context.TODO())
+
+	//THEN
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"warden-enabled/pod-a", "warden-enabled/pod-b"}, <-revalidated)
+}
+
 func Test_areImagesChanged(t *testing.T) {
 	type podImages struct {
-		Containers     []corev1.Container
-		InitContainers []corev1.Container
+		Containers          []corev1.Container
+		InitContainers      []corev1.Container
+		EphemeralContainers []corev1.EphemeralContainer
 	}
 	type args struct {
 		oldPod podImages
@@ -432,12 +799,33 @@ func Test_areImagesChanged(t *testing.T) {
 			},
 			want: true,
 		},
+		{
+			name: "added ephemeral container",
+			args: args{
+				oldPod: podImages{
+					Containers: []corev1.Container{
+						{Image: "image-golf", Name: "container-golf"},
+					},
+				},
+				newPod: podImages{
+					Containers: []corev1.Container{
+						{Image: "image-golf", Name: "container-golf"},
+					},
+					EphemeralContainers: []corev1.EphemeralContainer{
+						{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Image: "image-hotel", Name: "debugger-hotel"}},
+					},
+				},
+			},
+			want: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			//GIVEN
 			oldPod := fixPod(tt.args.oldPod.InitContainers, tt.args.oldPod.Containers)
+			oldPod.Spec.EphemeralContainers = tt.args.oldPod.EphemeralContainers
 			newPod := fixPod(tt.args.newPod.InitContainers, tt.args.newPod.Containers)
+			newPod.Spec.EphemeralContainers = tt.args.newPod.EphemeralContainers
 			//WHEN
 			got := areImagesChanged(oldPod, newPod)
 
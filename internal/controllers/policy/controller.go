@@ -0,0 +1,103 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/policy"
+	"github.com/kyma-project/warden/internal/validate"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ImagePolicyReconciler reports whether the trust material of the ImagePolicy can be loaded
+type ImagePolicyReconciler struct {
+	client.Client
+	ValidatorSvcFactory validate.ValidatorSvcFactory
+	Log                 *zap.SugaredLogger
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ImagePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ImagePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=imagepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=imagepolicies/status,verbs=get;update
+
+func (r *ImagePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.With("req", req).With("req-id", uuid.New().String())
+	logger.Debug("reconciliation started")
+
+	var instance v1alpha1.ImagePolicy
+	if err := r.Get(ctx, req.NamespacedName, &instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !updateStatus(&instance.Status, instance.Generation, instance.Spec, r.ValidatorSvcFactory, logger) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, client.IgnoreNotFound(r.Status().Update(ctx, &instance))
+}
+
+// ClusterImagePolicyReconciler reports whether the trust material of the ClusterImagePolicy can be loaded
+type ClusterImagePolicyReconciler struct {
+	client.Client
+	ValidatorSvcFactory validate.ValidatorSvcFactory
+	Log                 *zap.SugaredLogger
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterImagePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ClusterImagePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
+
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=clusterimagepolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=warden.kyma-project.io,resources=clusterimagepolicies/status,verbs=get;update
+
+func (r *ClusterImagePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Log.With("req", req).With("req-id", uuid.New().String())
+	logger.Debug("reconciliation started")
+
+	var instance v1alpha1.ClusterImagePolicy
+	if err := r.Get(ctx, req.NamespacedName, &instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !updateStatus(&instance.Status, instance.Generation, instance.Spec.ImagePolicySpec, r.ValidatorSvcFactory, logger) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, client.IgnoreNotFound(r.Status().Update(ctx, &instance))
+}
+
+// updateStatus sets the TrustMaterialLoaded condition and returns true when the status was changed
+func updateStatus(status *v1alpha1.ImagePolicyStatus, generation int64, spec v1alpha1.ImagePolicySpec,
+	factory validate.ValidatorSvcFactory, logger *zap.SugaredLogger) bool {
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionTrustMaterialLoaded,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             v1alpha1.ReasonTrustMaterialLoaded,
+		Message:            "trust material of the policy was loaded",
+	}
+	if _, err := policy.NewValidator(spec, factory); err != nil {
+		logger.Infow("trust material of the policy can't be loaded", "err", err.Error())
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.ReasonTrustMaterialLoadFailed
+		condition.Message = err.Error()
+	}
+
+	changed := status.ObservedGeneration != generation
+	status.ObservedGeneration = generation
+	return meta.SetStatusCondition(&status.Conditions, condition) || changed
+}
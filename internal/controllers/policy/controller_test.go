@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/test_helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestImagePolicyReconciler_Reconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	logger := test_helpers.NewTestZapLogger(t).Sugar()
+
+	tests := []struct {
+		name           string
+		spec           v1alpha1.ImagePolicySpec
+		expectedStatus metav1.ConditionStatus
+		expectedReason string
+	}{
+		{
+			name: "policy with loadable trust material",
+			spec: v1alpha1.ImagePolicySpec{
+				Verifier: pkg.VerifierNotary,
+				Notary:   &v1alpha1.NotaryVerification{URL: "https://notary.local"},
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1alpha1.ReasonTrustMaterialLoaded,
+		},
+		{
+			name:           "policy without trust material",
+			spec:           v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierNotary},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.ReasonTrustMaterialLoadFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			policy := &v1alpha1.ImagePolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "policy", Namespace: "default", Generation: 2},
+				Spec:       tt.spec,
+			}
+			client := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(policy).WithStatusSubresource(policy).Build()
+			factory := mocks.NewValidatorSvcFactory(t)
+			factory.On("NewValidatorSvc", "https://notary.local", "", 30*time.Second).
+				Return(validate.NewPodValidator(nil)).Maybe()
+			r := &ImagePolicyReconciler{Client: client, ValidatorSvcFactory: factory, Log: logger}
+
+			//WHEN
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "policy", Namespace: "default"}})
+
+			//THEN
+			require.NoError(t, err)
+			var updated v1alpha1.ImagePolicy
+			require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "policy", Namespace: "default"}, &updated))
+			require.Equal(t, int64(2), updated.Status.ObservedGeneration)
+			condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.ConditionTrustMaterialLoaded)
+			require.NotNil(t, condition)
+			require.Equal(t, tt.expectedStatus, condition.Status)
+			require.Equal(t, tt.expectedReason, condition.Reason)
+		})
+	}
+}
+
+func TestClusterImagePolicyReconciler_Reconcile(t *testing.T) {
+	//GIVEN
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	policy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy", Generation: 1},
+		Spec: v1alpha1.ClusterImagePolicySpec{ImagePolicySpec: v1alpha1.ImagePolicySpec{
+			Verifier: pkg.VerifierCosign,
+			Cosign:   &v1alpha1.CosignVerification{PublicKeys: "invalid"},
+		}},
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(policy).WithStatusSubresource(policy).Build()
+	r := &ClusterImagePolicyReconciler{
+		Client:              client,
+		ValidatorSvcFactory: validate.NewValidatorSvcFactory(),
+		Log:                 test_helpers.NewTestZapLogger(t).Sugar(),
+	}
+
+	//WHEN
+	_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "policy"}})
+
+	//THEN
+	require.NoError(t, err)
+	var updated v1alpha1.ClusterImagePolicy
+	require.NoError(t, client.Get(context.TODO(), types.NamespacedName{Name: "policy"}, &updated))
+	condition := meta.FindStatusCondition(updated.Status.Conditions, v1alpha1.ConditionTrustMaterialLoaded)
+	require.NotNil(t, condition)
+	require.Equal(t, metav1.ConditionFalse, condition.Status)
+	require.Contains(t, condition.Message, "while parsing cosign public keys")
+}
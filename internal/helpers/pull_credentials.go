@@ -13,38 +13,125 @@ import (
 	k8sclient "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	defaultServiceAccountName = "default"
+	dockerHubRegistry         = "index.docker.io"
+)
+
+// dockerHubAliases are hosts of the Docker Hub used in the docker config files
+var dockerHubAliases = map[string]struct{}{
+	"docker.io":            {},
+	"index.docker.io":      {},
+	"registry-1.docker.io": {},
+}
+
+// GetRemotePullCredentials returns credentials from the image pull secrets of the pod and of its service account,
+// like the kubelet uses them. The credentials are keyed by the registry host with optional repository path,
+// e.g. `registry.io` or `registry.io/team`, when the same key is defined more than once the first one wins.
+// Missing and invalid secrets are skipped, as the kubelet does
 func GetRemotePullCredentials(ctx context.Context, reader k8sclient.Reader, pod *corev1.Pod) (map[string]cliType.AuthConfig, error) {
+	secretNames, err := imagePullSecretNames(ctx, reader, pod)
+	if err != nil {
+		return nil, err
+	}
+
 	remoteSecrets := make(map[string]cliType.AuthConfig)
-	for _, imagePullSecret := range pod.Spec.ImagePullSecrets {
+	for _, secretName := range secretNames {
 		secret := &corev1.Secret{}
-		var dockerConfig []byte
-		if err := reader.Get(ctx, k8sclient.ObjectKey{Namespace: pod.Namespace, Name: imagePullSecret.Name}, secret); err != nil {
+		if err := reader.Get(ctx, k8sclient.ObjectKey{Namespace: pod.Namespace, Name: secretName}, secret); err != nil {
 			if k8sclient.IgnoreNotFound(err) != nil {
-				return nil, errors.Wrap(err, fmt.Sprintf("can't get %s/%s", pod.Namespace, imagePullSecret.Name)) //"failed to get secret")
+				return nil, errors.Wrap(err, fmt.Sprintf("can't get %s/%s", pod.Namespace, secretName))
 			}
-			return remoteSecrets, nil
-		}
-		if dc, ok := secret.Data[".dockerconfigjson"]; ok {
-			dockerConfig = dc
-		} else if dc, ok := secret.Data["config.json"]; ok {
-			dockerConfig = dc
-		} else {
-			return nil, errors.New("no dockerconfigjson or config.json found in secret")
+			LoggerFromCtx(ctx).Debugw("image pull secret not found", "secret", secretName)
+			continue
 		}
 
-		var config k8sconfig.ConfigFile
-		if err := json.Unmarshal(dockerConfig, &config); err != nil {
-			return nil, errors.Wrap(err, "failed to unmarshal dockerconfigjson")
+		authConfigs, err := secretAuthConfigs(secret)
+		if err != nil {
+			// the pod images can be public or covered by other secrets, so the invalid secret doesn't fail the validation
+			LoggerFromCtx(ctx).Warnw("invalid image pull secret skipped", "secret", secretName, "error", err.Error())
+			continue
 		}
-		for authRepo, auth := range config.AuthConfigs {
-			// remove any protocol from authRepo string, and trailing slash
-			authRepoFragments := strings.Split(authRepo, "://")
-			repoURL := authRepoFragments[len(authRepoFragments)-1]
-			repoURL = strings.TrimRight(repoURL, "/")
-
-			// technically, you could get a slice of authConfigs for each repoURL
-			remoteSecrets[repoURL] = auth
+		for authRepo, auth := range authConfigs {
+			key := normalizeAuthKey(authRepo)
+			if _, ok := remoteSecrets[key]; !ok {
+				remoteSecrets[key] = auth
+			}
 		}
 	}
 	return remoteSecrets, nil
 }
+
+// imagePullSecretNames returns names of the pod image pull secrets followed by the service account ones
+func imagePullSecretNames(ctx context.Context, reader k8sclient.Reader, pod *corev1.Pod) ([]string, error) {
+	var names []string
+	seen := map[string]struct{}{}
+	add := func(refs []corev1.LocalObjectReference) {
+		for _, ref := range refs {
+			if _, ok := seen[ref.Name]; ok || ref.Name == "" {
+				continue
+			}
+			seen[ref.Name] = struct{}{}
+			names = append(names, ref.Name)
+		}
+	}
+	add(pod.Spec.ImagePullSecrets)
+
+	serviceAccountName := pod.Spec.ServiceAccountName
+	if serviceAccountName == "" {
+		serviceAccountName = defaultServiceAccountName
+	}
+	serviceAccount := &corev1.ServiceAccount{}
+	if err := reader.Get(ctx, k8sclient.ObjectKey{Namespace: pod.Namespace, Name: serviceAccountName}, serviceAccount); err != nil {
+		if k8sclient.IgnoreNotFound(err) != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("can't get service account %s/%s", pod.Namespace, serviceAccountName))
+		}
+		return names, nil
+	}
+	add(serviceAccount.ImagePullSecrets)
+	return names, nil
+}
+
+// secretAuthConfigs returns auth configs from the docker config json, or from the legacy docker config
+func secretAuthConfigs(secret *corev1.Secret) (map[string]cliType.AuthConfig, error) {
+	if dc, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+		return parseDockerConfigJSON(dc)
+	}
+	if dc, ok := secret.Data["config.json"]; ok {
+		return parseDockerConfigJSON(dc)
+	}
+	if dc, ok := secret.Data[corev1.DockerConfigKey]; ok {
+		authConfigs := map[string]cliType.AuthConfig{}
+		if err := json.Unmarshal(dc, &authConfigs); err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal dockercfg")
+		}
+		return authConfigs, nil
+	}
+	return nil, errors.New("no dockerconfigjson, config.json or dockercfg found in secret")
+}
+
+func parseDockerConfigJSON(dockerConfig []byte) (map[string]cliType.AuthConfig, error) {
+	var config k8sconfig.ConfigFile
+	if err := json.Unmarshal(dockerConfig, &config); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal dockerconfigjson")
+	}
+	return config.AuthConfigs, nil
+}
+
+// normalizeAuthKey removes the protocol and the trailing slash from the key,
+// and replaces the Docker Hub aliases with the registry host used in image references
+func normalizeAuthKey(authRepo string) string {
+	authRepoFragments := strings.Split(authRepo, "://")
+	key := authRepoFragments[len(authRepoFragments)-1]
+	key = strings.TrimRight(key, "/")
+
+	host, path, _ := strings.Cut(key, "/")
+	if _, ok := dockerHubAliases[strings.ToLower(host)]; !ok {
+		return key
+	}
+	// the Docker Hub API version is a part of the legacy keys, e.g. `https://index.docker.io/v1/`
+	if path == "v1" || path == "v2" || path == "" {
+		return dockerHubRegistry
+	}
+	return dockerHubRegistry + "/" + path
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -44,7 +45,7 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "incorrect secret",
+			name: "incorrect secret is skipped",
 			secrets: []*corev1.Secret{
 				{
 					ObjectMeta: metav1.ObjectMeta{
@@ -69,10 +70,10 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 				},
 			},
 			want:    map[string]cliType.AuthConfig{},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
-			name: "malformed secret",
+			name: "malformed secret is skipped",
 			secrets: []*corev1.Secret{
 				{
 					ObjectMeta: metav1.ObjectMeta{
@@ -97,7 +98,7 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 				},
 			},
 			want:    map[string]cliType.AuthConfig{},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
 			name: "secret with .dockerconfigjson",
@@ -183,3 +184,86 @@ func Test_GetRemotePullCredentials(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetRemotePullCredentials_ServiceAccountAndLegacySecrets(t *testing.T) {
+	//GIVEN
+	ctx := context.Background()
+	dockerConfigJSON := func(auths string) map[string][]byte {
+		return map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths": {` + auths + `}}`)}
+	}
+	client := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod-secret", Namespace: "default"},
+			Data: dockerConfigJSON(`"https://registry.io/team/": {"username": "team", "password": "team"},
+				"registry.io": {"username": "pod", "password": "pod"}`),
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sa-secret", Namespace: "default"},
+			Data: dockerConfigJSON(`"registry.io": {"username": "sa", "password": "sa"},
+				"https://index.docker.io/v1/": {"username": "hub", "password": "hub"},
+				"docker.io/user": {"username": "user", "password": "user"}`),
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy-secret", Namespace: "default"},
+			Data: map[string][]byte{
+				corev1.DockerConfigKey: []byte(`{"legacy.io": {"auth": "bGVnYWN5OmxlZ2FjeQ==", "email": "legacy@legacy.io"}}`),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "malformed-secret", Namespace: "default"},
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("malformed")},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "pod-secret"}, {Name: "malformed-secret"},
+				{Name: "sa-secret"}, {Name: "legacy-secret"}},
+		},
+	).Build()
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: "app",
+			ImagePullSecrets:   []corev1.LocalObjectReference{{Name: "missing-secret"}, {Name: "pod-secret"}},
+		},
+	}
+
+	//WHEN
+	got, err := GetRemotePullCredentials(ctx, client, pod)
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, map[string]cliType.AuthConfig{
+		"registry.io/team":     {Username: "team", Password: "team"},
+		"registry.io":          {Username: "pod", Password: "pod"},
+		"index.docker.io":      {Username: "hub", Password: "hub"},
+		"index.docker.io/user": {Username: "user", Password: "user"},
+		"legacy.io":            {Auth: "bGVnYWN5OmxlZ2FjeQ==", Email: "legacy@legacy.io"},
+	}, got)
+}
+
+func Test_GetRemotePullCredentials_DefaultServiceAccount(t *testing.T) {
+	//GIVEN
+	ctx := context.Background()
+	client := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "sa-secret", Namespace: "default"},
+			Data: map[string][]byte{
+				corev1.DockerConfigJsonKey: []byte(`{"auths": {"registry.io": {"username": "sa", "password": "sa"}}}`),
+			},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "sa-secret"}},
+		},
+	).Build()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default"}}
+
+	//WHEN
+	got, err := GetRemotePullCredentials(ctx, client, pod)
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, map[string]cliType.AuthConfig{
+		"registry.io": {Username: "sa", Password: "sa"},
+	}, got)
+}
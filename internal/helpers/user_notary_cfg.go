@@ -55,3 +55,107 @@ func GetUserValidationStrictMode(ns *corev1.Namespace) (bool, error) {
 	}
 	return strictMode, nil
 }
+
+// IsAuditEnforcementMode returns true when pods in the namespace should be admitted even if their validation fails
+func IsAuditEnforcementMode(ns *corev1.Namespace) (bool, error) {
+	mode, ok := ns.GetAnnotations()[pkg.NamespaceEnforcementModeAnnotation]
+	if !ok || mode == "" {
+		return false, nil
+	}
+	switch mode {
+	case pkg.EnforcementModeAudit:
+		return true, nil
+	case pkg.EnforcementModeEnforce:
+		return false, nil
+	}
+	return false, errors.Errorf("unsupported %s annotation value: %s", pkg.NamespaceEnforcementModeAnnotation, mode)
+}
+
+// ParseDigestPinningMode returns the digest pinning mode, the empty value means the pinning is disabled
+func ParseDigestPinningMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return pkg.DigestPinningDisabled, nil
+	case pkg.DigestPinningDisabled, pkg.DigestPinningDigest, pkg.DigestPinningTagDigest:
+		return mode, nil
+	}
+	return "", errors.Errorf("unsupported digest pinning mode: %s", mode)
+}
+
+// GetDigestPinningMode returns the digest pinning mode of the namespace, or the default mode when it's not set
+func GetDigestPinningMode(ns *corev1.Namespace, defaultMode string) (string, error) {
+	mode, ok := ns.GetAnnotations()[pkg.NamespaceDigestPinningAnnotation]
+	if !ok || mode == "" {
+		return ParseDigestPinningMode(defaultMode)
+	}
+	parsed, err := ParseDigestPinningMode(mode)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid %s annotation value", pkg.NamespaceDigestPinningAnnotation)
+	}
+	return parsed, nil
+}
+
+type UserValidationCosignConfig struct {
+	PublicKeys        string
+	AllowedRegistries string
+	Timeout           time.Duration
+}
+
+type UserValidationNotationConfig struct {
+	AllowedRegistries string
+	Timeout           time.Duration
+}
+
+func GetUserValidationVerifier(ns *corev1.Namespace) (string, error) {
+	verifier, ok := ns.GetAnnotations()[pkg.NamespaceVerifierAnnotation]
+	if !ok || verifier == "" {
+		return pkg.VerifierNotary, nil
+	}
+	if verifier != pkg.VerifierNotary && verifier != pkg.VerifierCosign && verifier != pkg.VerifierNotation {
+		return "", errors.Errorf("unsupported verifier: %s", verifier)
+	}
+	return verifier, nil
+}
+
+func GetUserValidationCosignConfig(ns *corev1.Namespace) (UserValidationCosignConfig, error) {
+	publicKeys, ok := ns.GetAnnotations()[pkg.NamespaceCosignPublicKeysAnnotation]
+	if !ok {
+		return UserValidationCosignConfig{}, errors.New("cosign public keys are not set")
+	}
+	allowedRegistries, timeout, err := getUserAllowedRegistriesAndTimeout(ns)
+	if err != nil {
+		return UserValidationCosignConfig{}, err
+	}
+	return UserValidationCosignConfig{
+		PublicKeys:        publicKeys,
+		AllowedRegistries: allowedRegistries,
+		Timeout:           timeout,
+	}, nil
+}
+
+func GetUserValidationNotationConfig(ns *corev1.Namespace) (UserValidationNotationConfig, error) {
+	allowedRegistries, timeout, err := getUserAllowedRegistriesAndTimeout(ns)
+	if err != nil {
+		return UserValidationNotationConfig{}, err
+	}
+	return UserValidationNotationConfig{
+		AllowedRegistries: allowedRegistries,
+		Timeout:           timeout,
+	}, nil
+}
+
+func getUserAllowedRegistriesAndTimeout(ns *corev1.Namespace) (string, time.Duration, error) {
+	userAllowedRegistries, okAllowedRegistries := ns.GetAnnotations()[pkg.NamespaceAllowedRegistriesAnnotation]
+	if !okAllowedRegistries {
+		userAllowedRegistries = DefaultUserAllowedRegistries
+	}
+	timeoutString, okTimeoutString := ns.GetAnnotations()[pkg.NamespaceNotaryTimeoutAnnotation]
+	if !okTimeoutString {
+		timeoutString = DefaultUserNotaryTimeoutString
+	}
+	timeout, errTimeoutParse := time.ParseDuration(timeoutString)
+	if errTimeoutParse != nil {
+		return "", 0, errTimeoutParse
+	}
+	return userAllowedRegistries, timeout, nil
+}
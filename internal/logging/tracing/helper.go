@@ -1,12 +1,23 @@
 package tracing
 
-import "context"
+import (
+	"context"
 
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GetMetadata returns ids of the trace and the span from the context,
+// ids of the recorded span take precedence over the ids copied from the B3 headers
 func GetMetadata(ctx context.Context) map[string]string {
 	m := map[string]string{
 		TRACE_KEY: UNKNOWN_VALUE,
 		SPAN_KEY:  UNKNOWN_VALUE,
 	}
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() && spanCtx.IsSampled() {
+		m[TRACE_KEY] = spanCtx.TraceID().String()
+		m[SPAN_KEY] = spanCtx.SpanID().String()
+		return m
+	}
 	if val, ok := ctx.Value(TRACE_KEY).(string); ok {
 		m[TRACE_KEY] = val
 	}
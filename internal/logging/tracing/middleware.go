@@ -4,6 +4,10 @@ import (
 	"context"
 	"net/http"
 	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -24,10 +28,16 @@ func NewTracingMiddleware(handler func(w http.ResponseWriter, r *http.Request))
 	}
 }
 
+// ServeHTTP handles the request in the span continuing the trace propagated in the W3C or B3 headers
 func (m *tracingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	newCtx := addHeaderToCtx(r.Context(), r.Header, TRACE_HEADER_KEY, TRACE_KEY)
 	newCtx = addHeaderToCtx(newCtx, r.Header, SPAN_HEADER_KEY, SPAN_KEY)
 
+	newCtx = otel.GetTextMapPropagator().Extract(newCtx, propagation.HeaderCarrier(r.Header))
+	newCtx, span := otel.Tracer(tracerName).Start(newCtx, "admission "+r.URL.Path,
+		trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
 	m.handler(w, r.WithContext(newCtx))
 }
 
@@ -34,7 +34,7 @@ func TestMiddleware(t *testing.T) {
 		assert.Equal(t, "mytrace", ctx.Value(tracing.TRACE_KEY))
 	})
 
-	t.Run("wihtout trace and span should not change the context", func(t *testing.T) {
+	t.Run("wihtout trace and span should not put traceid and spanid to context", func(t *testing.T) {
 		//GIVEN
 		var enhancedRequest *http.Request
 		middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
@@ -50,6 +50,8 @@ func TestMiddleware(t *testing.T) {
 
 		//THEN
 		ctx := enhancedRequest.Context()
-		assert.Equal(t, ctx, r.Context())
+		assert.Nil(t, ctx.Value(tracing.TRACE_KEY))
+		assert.Nil(t, ctx.Value(tracing.SPAN_KEY))
+		assert.Equal(t, tracing.UNKNOWN_VALUE, tracing.GetMetadata(ctx)[tracing.TRACE_KEY])
 	})
 }
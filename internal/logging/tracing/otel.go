@@ -0,0 +1,91 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/kyma-project/warden"
+
+type Config struct {
+	ServiceName string
+	// Endpoint is the host and port of the OTLP HTTP collector
+	Endpoint      string
+	Insecure      bool
+	SamplingRatio float64
+}
+
+// Setup configures the global tracer provider exporting spans to the OTLP collector,
+// and the global propagator. The returned function flushes and stops the exporter
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating OTLP trace exporter")
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating trace resource")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SamplingRatio))),
+	)
+	SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// SetTracerProvider sets the global tracer provider and the propagator of W3C trace context, baggage and B3 headers
+func SetTracerProvider(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(NewPropagator())
+}
+
+// NewPropagator returns propagator extracting W3C trace context, baggage and B3 headers,
+// B3 headers are injected in the multiple headers encoding
+func NewPropagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)),
+	)
+}
+
+// StartSpan starts span using the global tracer provider
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartDetachedSpan starts the root span linked to the span of the context, it's used for work shared by multiple
+// requests, which isn't a part of the trace of the request that started it
+func StartDetachedSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithNewRoot(),
+		trace.WithLinks(trace.LinkFromContext(ctx)), trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the error in the span, if there is any, and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
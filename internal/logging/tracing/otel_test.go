@@ -0,0 +1,110 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func setupInMemoryTracing(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracing.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		tracing.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+	return exporter
+}
+
+func TestMiddleware_Spans(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{
+			name:    "W3C trace context",
+			headers: map[string]string{"traceparent": "00-" + testTraceID + "-" + testSpanID + "-01"},
+		},
+		{
+			name: "B3 headers",
+			headers: map[string]string{
+				tracing.TRACE_HEADER_KEY: testTraceID,
+				tracing.SPAN_HEADER_KEY:  testSpanID,
+				"X-B3-Sampled":           "1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			exporter := setupInMemoryTracing(t)
+			var metadata map[string]string
+			middleware := tracing.NewTracingMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				metadata = tracing.GetMetadata(r.Context())
+			})
+			r := httptest.NewRequest(http.MethodPost, "/defaulting", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+
+			//WHEN
+			middleware.ServeHTTP(httptest.NewRecorder(), r)
+
+			//THEN
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			require.Equal(t, "admission /defaulting", spans[0].Name)
+			require.Equal(t, trace.SpanKindServer, spans[0].SpanKind)
+			require.Equal(t, testTraceID, spans[0].SpanContext.TraceID().String())
+			require.Equal(t, testSpanID, spans[0].Parent.SpanID().String())
+			require.Equal(t, testTraceID, metadata[tracing.TRACE_KEY])
+			require.Equal(t, spans[0].SpanContext.SpanID().String(), metadata[tracing.SPAN_KEY])
+		})
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	t.Run("span with error", func(t *testing.T) {
+		//GIVEN
+		exporter := setupInMemoryTracing(t)
+		_, span := tracing.StartSpan(context.TODO(), "failing")
+
+		//WHEN
+		tracing.EndSpan(span, errors.New("upstream failed"))
+
+		//THEN
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, codes.Error, spans[0].Status.Code)
+		require.Equal(t, "upstream failed", spans[0].Status.Description)
+		require.Len(t, spans[0].Events, 1)
+	})
+
+	t.Run("span without error", func(t *testing.T) {
+		//GIVEN
+		exporter := setupInMemoryTracing(t)
+		_, span := tracing.StartSpan(context.TODO(), "succeeding")
+
+		//WHEN
+		tracing.EndSpan(span, nil)
+
+		//THEN
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 1)
+		require.Equal(t, codes.Unset, spans[0].Status.Code)
+	})
+}
@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"context"
+	"sort"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Resolver returns rules of image policies applied to the namespace
+type Resolver interface {
+	Resolve(ctx context.Context, ns *corev1.Namespace) ([]validate.PolicyRule, error)
+}
+
+var _ Resolver = &resolver{}
+
+type resolver struct {
+	reader  client.Reader
+	factory validate.ValidatorSvcFactory
+}
+
+// NewResolver creates resolver reading policies with given reader, it should be backed by the informer cache
+func NewResolver(reader client.Reader, factory validate.ValidatorSvcFactory) Resolver {
+	return &resolver{
+		reader:  reader,
+		factory: factory,
+	}
+}
+
+// Resolve returns rules of ClusterImagePolicies selecting the namespace followed by rules of ImagePolicies from the namespace,
+// policies of the same kind are ordered by name. Images matching a cluster policy are never validated with a namespace policy,
+// so users allowed to create ImagePolicies can't weaken them. ImagePolicies are ignored in namespaces with the system validation
+func (r *resolver) Resolve(ctx context.Context, ns *corev1.Namespace) ([]validate.PolicyRule, error) {
+	logger := helpers.LoggerFromCtx(ctx)
+
+	var clusterImagePolicies v1alpha1.ClusterImagePolicyList
+	if err := r.reader.List(ctx, &clusterImagePolicies); err != nil {
+		return nil, errors.Wrap(err, "while listing cluster image policies")
+	}
+	sort.Slice(clusterImagePolicies.Items, func(i, j int) bool {
+		return clusterImagePolicies.Items[i].Name < clusterImagePolicies.Items[j].Name
+	})
+
+	var rules []validate.PolicyRule
+	for _, p := range clusterImagePolicies.Items {
+		selected, err := selectsNamespace(p.Spec.NamespaceSelector, ns)
+		if err != nil {
+			logger.Infow("cluster image policy namespace selector is invalid", "policy", p.Name, "err", err.Error())
+			continue
+		}
+		if selected {
+			rules = append(rules, r.newRule(ctx, "ClusterImagePolicy/"+p.Name, p.Spec.ImagePolicySpec))
+		}
+	}
+
+	if !validate.IsUserValidationForNS(ns) {
+		return rules, nil
+	}
+	var imagePolicies v1alpha1.ImagePolicyList
+	if err := r.reader.List(ctx, &imagePolicies, client.InNamespace(ns.Name)); err != nil {
+		return nil, errors.Wrap(err, "while listing image policies")
+	}
+	sort.Slice(imagePolicies.Items, func(i, j int) bool {
+		return imagePolicies.Items[i].Name < imagePolicies.Items[j].Name
+	})
+	for _, p := range imagePolicies.Items {
+		rules = append(rules, r.newRule(ctx, "ImagePolicy/"+p.Namespace+"/"+p.Name, p.Spec))
+	}
+	return rules, nil
+}
+
+func (r *resolver) newRule(ctx context.Context, name string, spec v1alpha1.ImagePolicySpec) validate.PolicyRule {
+	validator, err := NewValidator(spec, r.factory)
+	if err != nil {
+		// images matching the broken policy are not validated with the other policy,
+		// they are reported as unavailable, so the strict mode decides if they are admitted
+		helpers.LoggerFromCtx(ctx).Infow("image policy trust material can't be loaded", "policy", name, "err", err.Error())
+		validator = validate.NewPodValidator(&unavailableValidator{err: errors.Wrapf(err, "image policy %s", name)})
+	}
+	return validate.PolicyRule{
+		Name:       name,
+		Images:     spec.Images,
+		Validator:  validator,
+		StrictMode: spec.StrictMode,
+	}
+}
+
+func selectsNamespace(selector *metav1.LabelSelector, ns *corev1.Namespace) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(ns.Labels)), nil
+}
+
+// NewPodValidator returns validator using image policies for matching images and the fallback for the rest of them,
+// images denied by the namespace annotation are rejected before they are validated
+func NewPodValidator(ctx context.Context, resolver Resolver, ns *corev1.Namespace, fallback func() (validate.PodValidator, error)) (validate.PodValidator, error) {
+	deniedImages := validate.ParseDeniedImages(ns.GetAnnotations()[pkg.NamespaceDeniedImagesAnnotation])
+	if err := validate.ValidateDeniedImages(deniedImages); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s annotation value", pkg.NamespaceDeniedImagesAnnotation)
+	}
+	validator, err := newPodValidator(ctx, resolver, ns, fallback)
+	if err != nil {
+		return nil, err
+	}
+	return validate.NewDenyingPodValidator(validator, deniedImages), nil
+}
+
+func newPodValidator(ctx context.Context, resolver Resolver, ns *corev1.Namespace, fallback func() (validate.PodValidator, error)) (validate.PodValidator, error) {
+	if resolver == nil {
+		return fallback()
+	}
+	rules, err := resolver.Resolve(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return fallback()
+	}
+	return validate.NewPolicyPodValidator(rules, fallback), nil
+}
+
+type unavailableValidator struct {
+	err error
+}
+
+func (v *unavailableValidator) Validate(_ context.Context, _ string, _ map[string]cliType.AuthConfig) error {
+	return pkg.NewUnknownResultErr(v.err)
+}
@@ -0,0 +1,248 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestResolver_Resolve(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"env": "prod",
+		pkg.NamespaceValidationLabel: pkg.NamespaceValidationUser}}}
+	notaryValidator := validate.NewPodValidator(nil)
+
+	imagePolicy := &v1alpha1.ImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "b-policy", Namespace: "default"},
+		Spec: v1alpha1.ImagePolicySpec{
+			Images:     []string{"ghcr.io/**"},
+			Verifier:   pkg.VerifierNotary,
+			Notary:     &v1alpha1.NotaryVerification{URL: "https://notary.local"},
+			Timeout:    &metav1.Duration{Duration: time.Second},
+			StrictMode: ptr.To(true),
+		},
+	}
+	otherNsPolicy := &v1alpha1.ImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-policy", Namespace: "other"},
+		Spec: v1alpha1.ImagePolicySpec{
+			Verifier: pkg.VerifierNotary,
+			Notary:   &v1alpha1.NotaryVerification{URL: "https://notary.local"},
+		},
+	}
+	selectedClusterPolicy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-cluster-policy"},
+		Spec: v1alpha1.ClusterImagePolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "prod"}},
+			ImagePolicySpec: v1alpha1.ImagePolicySpec{
+				Verifier: pkg.VerifierNotary,
+				Notary:   &v1alpha1.NotaryVerification{URL: "https://notary.local"},
+			},
+		},
+	}
+	notSelectedClusterPolicy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "b-cluster-policy"},
+		Spec: v1alpha1.ClusterImagePolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}},
+			ImagePolicySpec: v1alpha1.ImagePolicySpec{
+				Verifier: pkg.VerifierNotary,
+				Notary:   &v1alpha1.NotaryVerification{URL: "https://notary.local"},
+			},
+		},
+	}
+	brokenClusterPolicy := &v1alpha1.ClusterImagePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "c-cluster-policy"},
+		Spec: v1alpha1.ClusterImagePolicySpec{
+			ImagePolicySpec: v1alpha1.ImagePolicySpec{
+				Verifier: pkg.VerifierCosign,
+			},
+		},
+	}
+
+	t.Run("selected cluster policies are followed by namespace policies", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).
+			WithObjects(imagePolicy, otherNsPolicy, selectedClusterPolicy, notSelectedClusterPolicy, brokenClusterPolicy).Build()
+		factory := mocks.NewValidatorSvcFactory(t)
+		factory.On("NewValidatorSvc", "https://notary.local", "", time.Second).Return(notaryValidator).Once()
+		factory.On("NewValidatorSvc", "https://notary.local", "", DefaultTimeout).Return(notaryValidator).Once()
+
+		//WHEN
+		rules, err := NewResolver(client, factory).Resolve(context.TODO(), ns)
+
+		//THEN
+		require.NoError(t, err)
+		require.Len(t, rules, 3)
+		require.Equal(t, "ClusterImagePolicy/a-cluster-policy", rules[0].Name)
+		require.Equal(t, "ClusterImagePolicy/c-cluster-policy", rules[1].Name)
+		require.Equal(t, "ImagePolicy/default/b-policy", rules[2].Name)
+		require.Equal(t, []string{"ghcr.io/**"}, rules[2].Images)
+		require.Equal(t, ptr.To(true), rules[2].StrictMode)
+	})
+
+	t.Run("namespace policies are ignored in namespaces with the system validation", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(imagePolicy, selectedClusterPolicy).Build()
+		factory := mocks.NewValidatorSvcFactory(t)
+		factory.On("NewValidatorSvc", "https://notary.local", "", DefaultTimeout).Return(notaryValidator).Once()
+		systemNs := ns.DeepCopy()
+		systemNs.Labels[pkg.NamespaceValidationLabel] = pkg.NamespaceValidationSystem
+
+		//WHEN
+		rules, err := NewResolver(client, factory).Resolve(context.TODO(), systemNs)
+
+		//THEN
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		require.Equal(t, "ClusterImagePolicy/a-cluster-policy", rules[0].Name)
+	})
+
+	t.Run("images matching broken policy are reported as unavailable", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(brokenClusterPolicy).Build()
+		factory := mocks.NewValidatorSvcFactory(t)
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "ghcr.io/app:v1"}}},
+		}
+
+		//WHEN
+		validator, err := NewPodValidator(context.TODO(), NewResolver(client, factory), ns, func() (validate.PodValidator, error) {
+			return notaryValidator, nil
+		})
+		require.NoError(t, err)
+		result, err := validator.ValidatePod(context.TODO(), pod, ns, nil)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.ServiceUnavailable, result.Status)
+		require.Equal(t, []string{"ghcr.io/app:v1"}, result.InvalidImages)
+	})
+
+	t.Run("fallback is used without policies", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).Build()
+		factory := mocks.NewValidatorSvcFactory(t)
+
+		//WHEN
+		validator, err := NewPodValidator(context.TODO(), NewResolver(client, factory), ns, func() (validate.PodValidator, error) {
+			return notaryValidator, nil
+		})
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, notaryValidator, validator)
+	})
+
+	t.Run("images denied by namespace annotation are rejected before validation", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).WithObjects(imagePolicy).Build()
+		factory := mocks.NewValidatorSvcFactory(t)
+		factory.On("NewValidatorSvc", "https://notary.local", "", time.Second).Return(notaryValidator).Once()
+		deniedNs := ns.DeepCopy()
+		deniedNs.Annotations = map[string]string{pkg.NamespaceDeniedImagesAnnotation: "ghcr.io/evil, registry.io/evil"}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "policy", Image: "ghcr.io/evil/app:v1"},
+				{Name: "fallback", Image: "registry.io/evil/app:v1"},
+			}},
+		}
+
+		//WHEN
+		validator, err := NewPodValidator(context.TODO(), NewResolver(client, factory), deniedNs, func() (validate.PodValidator, error) {
+			return mocks.NewPodValidator(t), nil
+		})
+		require.NoError(t, err)
+		result, err := validator.ValidatePod(context.TODO(), pod, deniedNs, nil)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Invalid, result.Status)
+		require.Equal(t, []string{"ghcr.io/evil/app:v1", "registry.io/evil/app:v1"}, result.InvalidImages)
+	})
+
+	t.Run("invalid denied images annotation", func(t *testing.T) {
+		//GIVEN
+		client := fake.NewClientBuilder().WithScheme(scheme).Build()
+		deniedNs := ns.DeepCopy()
+		deniedNs.Annotations = map[string]string{pkg.NamespaceDeniedImagesAnnotation: "regex:ghcr.io/(evil"}
+
+		//WHEN
+		_, err := NewPodValidator(context.TODO(), NewResolver(client, mocks.NewValidatorSvcFactory(t)), deniedNs, func() (validate.PodValidator, error) {
+			return notaryValidator, nil
+		})
+
+		//THEN
+		require.ErrorContains(t, err, "invalid namespaces.warden.kyma-project.io/denied-images annotation value")
+	})
+}
+
+func TestNewValidator(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1alpha1.ImagePolicySpec
+		wantErr string
+	}{
+		{
+			name:    "notary without url",
+			spec:    v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierNotary},
+			wantErr: "notary url is not configured",
+		},
+		{
+			name:    "notary with invalid url",
+			spec:    v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierNotary, Notary: &v1alpha1.NotaryVerification{URL: "notary"}},
+			wantErr: "while parsing notary url",
+		},
+		{
+			name:    "cosign without keys",
+			spec:    v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierCosign, Cosign: &v1alpha1.CosignVerification{}},
+			wantErr: "cosign public keys are not configured",
+		},
+		{
+			name: "notation with invalid trust policy",
+			spec: v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierNotation,
+				Notation: &v1alpha1.NotationVerification{TrustPolicy: "{"}},
+			wantErr: "while loading notation trust policy",
+		},
+		{
+			name: "invalid allowed registry expression",
+			spec: v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierNotary, AllowedRegistries: []string{"regex:registry.io/(team"},
+				Notary: &v1alpha1.NotaryVerification{URL: "https://notary.io"}},
+			wantErr: "invalid allowed registry regex:registry.io/(team",
+		},
+		{
+			name: "invalid denied image expression",
+			spec: v1alpha1.ImagePolicySpec{Verifier: pkg.VerifierNotary, DeniedImages: []string{"regex:registry.io/(team"},
+				Notary: &v1alpha1.NotaryVerification{URL: "https://notary.io"}},
+			wantErr: "invalid denied image regex:registry.io/(team",
+		},
+		{
+			name:    "unsupported verifier",
+			spec:    v1alpha1.ImagePolicySpec{Verifier: "sigstore"},
+			wantErr: "unsupported verifier: sigstore",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			_, err := NewValidator(tt.spec, mocks.NewValidatorSvcFactory(t))
+
+			//THEN
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kyma-project/warden/api/v1alpha1"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout is used when the policy doesn't define the timeout
+const DefaultTimeout = 30 * time.Second
+
+// NewValidator creates validator configured by the image policy,
+// the error means the trust material of the policy can't be loaded
+func NewValidator(spec v1alpha1.ImagePolicySpec, factory validate.ValidatorSvcFactory) (validate.PodValidator, error) {
+	if err := validate.ValidateDeniedImages(spec.DeniedImages); err != nil {
+		return nil, err
+	}
+	validator, err := newVerifierValidator(spec, factory)
+	if err != nil {
+		return nil, err
+	}
+	return validate.NewDenyingPodValidator(validator, spec.DeniedImages), nil
+}
+
+func newVerifierValidator(spec v1alpha1.ImagePolicySpec, factory validate.ValidatorSvcFactory) (validate.PodValidator, error) {
+	timeout := DefaultTimeout
+	if spec.Timeout != nil {
+		timeout = spec.Timeout.Duration
+	}
+	if err := validate.ValidateAllowedRegistries(spec.AllowedRegistries); err != nil {
+		return nil, err
+	}
+	allowedRegistries := strings.Join(spec.AllowedRegistries, ",")
+
+	switch spec.Verifier {
+	case pkg.VerifierNotary:
+		if spec.Notary == nil || spec.Notary.URL == "" {
+			return nil, errors.New("notary url is not configured")
+		}
+		if _, err := url.ParseRequestURI(spec.Notary.URL); err != nil {
+			return nil, errors.Wrap(err, "while parsing notary url")
+		}
+		return factory.NewValidatorSvc(spec.Notary.URL, allowedRegistries, timeout), nil
+	case pkg.VerifierCosign:
+		if spec.Cosign == nil || spec.Cosign.PublicKeys == "" {
+			return nil, errors.New("cosign public keys are not configured")
+		}
+		return factory.NewCosignValidatorSvc(spec.Cosign.PublicKeys, allowedRegistries, timeout)
+	case pkg.VerifierNotation:
+		if spec.Notation == nil || spec.Notation.TrustPolicy == "" {
+			return factory.NewNotationValidatorSvc(allowedRegistries, timeout)
+		}
+		trustStores := map[string]string{}
+		for _, store := range spec.Notation.TrustStores {
+			trustStores[fmt.Sprintf("%s:%s", store.Type, store.Name)] = store.Certificates
+		}
+		notationConfig, err := validate.NewNotationConfig(spec.Notation.TrustPolicy, trustStores, timeout)
+		if err != nil {
+			return nil, errors.Wrap(err, "while loading notation trust policy")
+		}
+		return factory.NewNotationValidatorSvcWithConfig(notationConfig, allowedRegistries, timeout)
+	}
+	return nil, errors.Errorf("unsupported verifier: %s", spec.Verifier)
+}
@@ -0,0 +1,290 @@
+package validate
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
+	"github.com/kyma-project/warden/pkg"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	cacheResultHit  = "hit"
+	cacheResultMiss = "miss"
+)
+
+type CacheConfig struct {
+	// PositiveTTL defines how long successful verification results are kept
+	PositiveTTL time.Duration
+	// NegativeTTL defines how long failed verification results are kept
+	NegativeTTL time.Duration
+	// TagTTL defines how long digests resolved for image tags are kept, so the image registry isn't asked
+	// for the digest on every validation. The tag moved within the TTL is validated with its previous digest
+	TagTTL time.Duration
+	// MaxSize is the maximum number of kept results and digests, the least recently used ones are evicted first
+	MaxSize int
+}
+
+// VerificationCache keeps image verification results keyed by image digest and trust configuration.
+// It's safe for concurrent use and is meant to be shared by all validators created by one factory.
+type VerificationCache struct {
+	config  CacheConfig
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type cacheEntry struct {
+	key       string
+	err       error
+	warnings  []string
+	details   imageDetailsValues
+	expiresAt time.Time
+}
+
+func NewVerificationCache(cfg CacheConfig) *VerificationCache {
+	return &VerificationCache{
+		config:  cfg,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// Get returns whether the verification result is cached and the result itself,
+// nil error means the image was verified successfully
+func (c *VerificationCache) Get(key string) (bool, error) {
+	entry, found := c.get(key)
+	if !found {
+		return false, nil
+	}
+	return true, entry.err
+}
+
+func (c *VerificationCache) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.remove(element)
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(element)
+	return *entry, true
+}
+
+// Set stores verification result with warnings reported during the verification,
+// only successful and definitely failed verifications are cached
+func (c *VerificationCache) Set(key string, err error, warnings ...string) {
+	c.set(key, err, imageDetailsValues{}, warnings...)
+}
+
+func (c *VerificationCache) set(key string, err error, details imageDetailsValues, warnings ...string) {
+	ttl := c.config.PositiveTTL
+	if err != nil {
+		// invalid or rejected credentials can be fixed anytime, so they don't fail the validation for the whole TTL
+		if pkg.ErrorCode(err) != pkg.ValidationError || ReasonFromError(err) == ReasonRegistryAuthFailed {
+			return
+		}
+		ttl = c.config.NegativeTTL
+	}
+	c.store(&cacheEntry{key: key, err: err, warnings: warnings, details: details}, ttl)
+}
+
+// getDigest returns the digest resolved for the image tag
+func (c *VerificationCache) getDigest(key string) (v1.Hash, bool) {
+	entry, found := c.get(key)
+	if !found {
+		return v1.Hash{}, false
+	}
+	digest, err := v1.NewHash(entry.details.digest)
+	return digest, err == nil
+}
+
+// setDigest stores the digest resolved for the image tag for the TagTTL
+func (c *VerificationCache) setDigest(key string, digest v1.Hash) {
+	c.store(&cacheEntry{key: key, details: imageDetailsValues{digest: digest.String()}}, c.config.TagTTL)
+}
+
+func (c *VerificationCache) store(entry *cacheEntry, ttl time.Duration) {
+	if ttl <= 0 || c.config.MaxSize <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := entry.key
+	entry.expiresAt = c.now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.lru.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.config.MaxSize {
+		c.remove(c.lru.Back())
+	}
+	verificationCacheEntries.Set(float64(c.lru.Len()))
+}
+
+// Len returns number of kept results, including expired ones which were not evicted yet
+func (c *VerificationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *VerificationCache) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	verificationCacheEntries.Set(float64(c.lru.Len()))
+}
+
+type cachedImageValidator struct {
+	validator         ImageValidatorService
+	cache             *VerificationCache
+	trustConfigKey    string
+	allowedRegistries []string
+}
+
+// NewCachedImageValidator returns validator which keeps results of the given validator in the cache.
+// The trustConfigKey has to identify the trust configuration (verifier, notary URL, keys, etc.) used by the validator
+func NewCachedImageValidator(validator ImageValidatorService, cache *VerificationCache, trustConfigKey string, allowedRegistries []string) ImageValidatorService {
+	return &cachedImageValidator{
+		validator:         validator,
+		cache:             cache,
+		trustConfigKey:    trustConfigKey,
+		allowedRegistries: allowedRegistries,
+	}
+}
+
+func (s *cachedImageValidator) Validate(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) error {
+	// allowed images are not verified so there is nothing to cache
+	if isImageAllowed(image, s.allowedRegistries) {
+		return s.validator.Validate(ctx, image, imagePullCredentials)
+	}
+
+	ref, err := name.ParseReference(image, name.StrictValidation)
+	if err != nil {
+		return s.validator.Validate(ctx, image, imagePullCredentials)
+	}
+
+	digest, err := s.resolveDigest(ctx, ref, imagePullCredentials)
+	if err != nil {
+		// let the validator report the problem with the registry
+		return s.validator.Validate(ctx, image, imagePullCredentials)
+	}
+
+	logger := helpers.LoggerFromCtx(ctx).With("image", image, "digest", digest.String())
+	key := s.cacheKey(image, ref, digest)
+	if entry, found := s.cache.get(key); found {
+		verificationCacheRequests.WithLabelValues(cacheResultHit).Inc()
+		logger.Info("image verification result taken from cache")
+		for _, warning := range entry.warnings {
+			AddWarning(ctx, warning)
+		}
+		reportImageDetails(ctx, entry.details)
+		return entry.err
+	}
+	verificationCacheRequests.WithLabelValues(cacheResultMiss).Inc()
+	logger.Debug("image verification result not found in cache")
+
+	// warnings and image details are cached with the result, so they are reported on every cache hit
+	validatorCtx := contextWithImageDetails(ContextWithWarnings(ctx))
+	err = s.validator.Validate(validatorCtx, image, imagePullCredentials)
+	warnings := WarningsFromContext(validatorCtx)
+	for _, warning := range warnings {
+		AddWarning(ctx, warning)
+	}
+	details := imageDetailsFromContext(validatorCtx)
+	if details.digest == "" {
+		details.digest = digest.String()
+	}
+	reportImageDetails(ctx, details)
+	s.cache.set(key, err, details, warnings...)
+	return err
+}
+
+// resolveDigest returns the digest of the image, digests of tags are kept in the cache for the TagTTL
+// and concurrent lookups of the same tag are collapsed into one registry request
+func (s *cachedImageValidator) resolveDigest(ctx context.Context, ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) (v1.Hash, error) {
+	if digest, ok := ref.(name.Digest); ok {
+		return v1.NewHash(digest.DigestStr())
+	}
+
+	// credentials are part of the key, so the digest is never reused by a caller which couldn't resolve it itself
+	credentials, _ := lookupCredentials(ref.Context(), imagePullCredentials)
+	key := strings.Join([]string{"tag", ref.Name(), credentialsKey(credentials)}, "|")
+	if digest, found := s.cache.getDigest(key); found {
+		return digest, nil
+	}
+	closeLog := helpers.LogStartTime(ctx, "request to image registry (shared digest lookup)")
+	defer closeLog()
+	digest, err := doShared(ctx, &registryLookups, key, 0, func(ctx context.Context) (v1.Hash, error) {
+		return loggedResolveImageDigest(ctx, ref, imagePullCredentials)
+	})
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	s.cache.setDigest(key, digest)
+	return digest, nil
+}
+
+// cacheKey contains the whole reference because notary trust data is stored per tag
+func (s *cachedImageValidator) cacheKey(image string, ref name.Reference, digest v1.Hash) string {
+	refName := ref.Name()
+	if tag, ok := pinnedTag(image, ref); ok {
+		refName = tag.Name()
+	}
+	return strings.Join([]string{s.trustConfigKey, refName, digest.String()}, "|")
+}
+
+// TrustConfigKey returns short, stable identifier of the given trust configuration values
+func TrustConfigKey(values ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+func loggedResolveImageDigest(ctx context.Context, ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) (v1.Hash, error) {
+	if digest, ok := ref.(name.Digest); ok {
+		return v1.NewHash(digest.DigestStr())
+	}
+
+	const message = "request to image registry (resolve digest)"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+	defer observeDuration(registryRequestDuration, registryOperationHeadDescriptor)()
+	_, span := tracing.StartSpan(ctx, "registry remote.Head", attribute.String("image", ref.Name()))
+	digest, err := resolveImageDigest(ref, imagePullCredentials, remote.WithContext(ctx))
+	tracing.EndSpan(span, err)
+	return digest, err
+}
+
+func resolveImageDigest(ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig, remoteOptions ...remote.Option) (v1.Hash, error) {
+	descriptor, _, err := withRegistryAuth(ref, imagePullCredentials, remoteOptions, "head image descriptor",
+		func(options ...remote.Option) (*v1.Descriptor, error) {
+			return remote.Head(ref, options...)
+		})
+	if err != nil {
+		return v1.Hash{}, err
+	}
+	return descriptor.Digest, nil
+}
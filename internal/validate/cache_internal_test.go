@@ -0,0 +1,50 @@
+package validate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_validatorSvcFactory_cacheKey(t *testing.T) {
+	cache := NewVerificationCache(CacheConfig{})
+	mirrors := RegistryMirrors{{Mirror: "mirror.io", Upstream: "docker.io", DigestSource: DigestSourceMirror}}
+	trustConfigKey := func(factory ValidatorSvcFactory, allowedRegistries string) string {
+		validator := factory.NewValidatorSvc("https://notary.io", allowedRegistries, 0).(*denyingPodValidator)
+		return validator.validator.(*podValidator).Validator.(*cachedImageValidator).trustConfigKey
+	}
+
+	//GIVEN
+	factory := NewValidatorSvcFactoryWithConfig(ValidatorSvcFactoryConfig{Cache: cache, DeniedImages: []string{"evil.io"}})
+	mirroredFactory := NewValidatorSvcFactoryWithConfig(ValidatorSvcFactoryConfig{Cache: cache, DeniedImages: []string{"evil.io"},
+		RegistryMirrors: mirrors})
+
+	//WHEN
+	key := trustConfigKey(factory, "registry.io")
+
+	//THEN
+	require.Equal(t, key, trustConfigKey(factory, "registry.io"))
+	require.NotEqual(t, key, trustConfigKey(factory, "other.io"))
+	require.NotEqual(t, key, trustConfigKey(mirroredFactory, "registry.io"))
+}
+
+func TestVerificationCache_credentialsFailures(t *testing.T) {
+	//GIVEN
+	cache := NewVerificationCache(CacheConfig{PositiveTTL: time.Minute, NegativeTTL: time.Minute, MaxSize: 10})
+
+	//WHEN
+	cache.Set("invalid-credentials", withReason(ReasonRegistryAuthFailed,
+		pkg.NewValidationFailedErr(errors.New("invalid image pull secret"))))
+	cache.Set("invalid-signature", withReason(ReasonSignatureInvalid,
+		pkg.NewValidationFailedErr(errors.New("invalid signature"))))
+
+	//THEN
+	found, _ := cache.Get("invalid-credentials")
+	require.False(t, found)
+	found, err := cache.Get("invalid-signature")
+	require.True(t, found)
+	require.ErrorContains(t, err, "invalid signature")
+}
@@ -0,0 +1,240 @@
+package validate_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_CachedImageValidator(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+
+	image := pushRandomImage(t, registryHost, "cached:v1")
+	taggedImage := registryHost + "/cached:v1"
+
+	cacheConfig := validate.CacheConfig{
+		PositiveTTL: time.Minute,
+		NegativeTTL: time.Minute,
+		MaxSize:     10,
+	}
+
+	t.Run("valid result is taken from cache", func(t *testing.T) {
+		//GIVEN
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, taggedImage, mock.Anything).Return(nil).Once()
+		s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(cacheConfig), "trust", nil)
+
+		//WHEN
+		errFirst := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+		errSecond := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+
+		//THEN
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+	})
+
+	t.Run("validation error is taken from cache", func(t *testing.T) {
+		//GIVEN
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, image.String(), mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("unexpected image hash value"))).Once()
+		s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(cacheConfig), "trust", nil)
+
+		//WHEN
+		errFirst := s.Validate(context.TODO(), image.String(), emptyAuthData)
+		errSecond := s.Validate(context.TODO(), image.String(), emptyAuthData)
+
+		//THEN
+		require.ErrorContains(t, errFirst, "unexpected image hash value")
+		require.ErrorContains(t, errSecond, "unexpected image hash value")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(errSecond))
+	})
+
+	t.Run("unknown result is not cached", func(t *testing.T) {
+		//GIVEN
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, taggedImage, mock.Anything).
+			Return(pkg.NewUnknownResultErr(errors.New("notary is not available"))).Twice()
+		s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(cacheConfig), "trust", nil)
+
+		//WHEN
+		errFirst := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+		errSecond := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+
+		//THEN
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(errFirst))
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(errSecond))
+	})
+
+	t.Run("results are separated by trust configuration", func(t *testing.T) {
+		//GIVEN
+		cache := validate.NewVerificationCache(cacheConfig)
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, taggedImage, mock.Anything).Return(nil).Twice()
+		s := validate.NewCachedImageValidator(validatorMock, cache, validate.TrustConfigKey("notary", "url1"), nil)
+		otherSvc := validate.NewCachedImageValidator(validatorMock, cache, validate.TrustConfigKey("notary", "url2"), nil)
+
+		//WHEN
+		errFirst := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+		errSecond := otherSvc.Validate(context.TODO(), taggedImage, emptyAuthData)
+
+		//THEN
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+		require.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("expired result is validated again", func(t *testing.T) {
+		//GIVEN
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, taggedImage, mock.Anything).Return(nil).Twice()
+		s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(validate.CacheConfig{
+			PositiveTTL: time.Millisecond * 10,
+			MaxSize:     10,
+		}), "trust", nil)
+
+		//WHEN
+		errFirst := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+		time.Sleep(time.Millisecond * 20)
+		errSecond := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+
+		//THEN
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+	})
+
+	t.Run("digest of the tag is resolved again only after the tag TTL", func(t *testing.T) {
+		for _, tt := range []struct {
+			name        string
+			repoTag     string
+			tagTTL      time.Duration
+			validations int
+		}{
+			{name: "digest is kept", repoTag: "kept:v1", tagTTL: time.Minute, validations: 1},
+			{name: "digest is not kept", repoTag: "not-kept:v1", validations: 2},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				//GIVEN
+				retaggedImage := registryHost + "/" + tt.repoTag
+				pushRandomImage(t, registryHost, tt.repoTag)
+				validatorMock := mocks.NewImageValidatorService(t)
+				validatorMock.On("Validate", mock.Anything, retaggedImage, mock.Anything).Return(nil).Times(tt.validations)
+				s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(validate.CacheConfig{
+					PositiveTTL: time.Minute,
+					TagTTL:      tt.tagTTL,
+					MaxSize:     10,
+				}), "trust", nil)
+
+				//WHEN
+				errFirst := s.Validate(context.TODO(), retaggedImage, emptyAuthData)
+				pushRandomImage(t, registryHost, tt.repoTag)
+				errSecond := s.Validate(context.TODO(), retaggedImage, emptyAuthData)
+
+				//THEN
+				require.NoError(t, errFirst)
+				require.NoError(t, errSecond)
+			})
+		}
+	})
+
+	t.Run("warnings are reported with cached result", func(t *testing.T) {
+		//GIVEN
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, taggedImage, mock.Anything).
+			Run(func(args mock.Arguments) {
+				validate.AddWarning(args.Get(0).(context.Context), "deprecated: manifest hash was used for verification")
+			}).Return(nil).Once()
+		s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(cacheConfig), "trust", nil)
+		firstCtx := validate.ContextWithWarnings(context.TODO())
+		secondCtx := validate.ContextWithWarnings(context.TODO())
+
+		//WHEN
+		errFirst := s.Validate(firstCtx, taggedImage, emptyAuthData)
+		errSecond := s.Validate(secondCtx, taggedImage, emptyAuthData)
+
+		//THEN
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+		require.Equal(t, []string{"deprecated: manifest hash was used for verification"}, validate.WarningsFromContext(firstCtx))
+		require.Equal(t, []string{"deprecated: manifest hash was used for verification"}, validate.WarningsFromContext(secondCtx))
+	})
+
+	t.Run("allowed image is not cached", func(t *testing.T) {
+		//GIVEN
+		cache := validate.NewVerificationCache(cacheConfig)
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, taggedImage, mock.Anything).Return(nil).Twice()
+		s := validate.NewCachedImageValidator(validatorMock, cache, "trust", []string{registryHost})
+
+		//WHEN
+		errFirst := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+		errSecond := s.Validate(context.TODO(), taggedImage, emptyAuthData)
+
+		//THEN
+		require.NoError(t, errFirst)
+		require.NoError(t, errSecond)
+		require.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("not resolvable image is validated without cache", func(t *testing.T) {
+		//GIVEN
+		notExistingImage := registryHost + "/not-existing:v1"
+		validatorMock := mocks.NewImageValidatorService(t)
+		validatorMock.On("Validate", mock.Anything, notExistingImage, mock.Anything).
+			Return(pkg.NewUnknownResultErr(errors.New("get image descriptor anonymously"))).Once()
+		s := validate.NewCachedImageValidator(validatorMock, validate.NewVerificationCache(cacheConfig), "trust", nil)
+
+		//WHEN
+		err := s.Validate(context.TODO(), notExistingImage, emptyAuthData)
+
+		//THEN
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+	})
+}
+
+func Test_VerificationCache(t *testing.T) {
+	t.Run("least recently used result is evicted", func(t *testing.T) {
+		//GIVEN
+		cache := validate.NewVerificationCache(validate.CacheConfig{PositiveTTL: time.Minute, MaxSize: 2})
+		cache.Set("first", nil)
+		cache.Set("second", nil)
+		found, _ := cache.Get("first")
+		require.True(t, found)
+
+		//WHEN
+		cache.Set("third", nil)
+
+		//THEN
+		require.Equal(t, 2, cache.Len())
+		found, _ = cache.Get("second")
+		require.False(t, found)
+		found, _ = cache.Get("first")
+		require.True(t, found)
+		found, _ = cache.Get("third")
+		require.True(t, found)
+	})
+
+	t.Run("negative results are not cached without TTL", func(t *testing.T) {
+		//GIVEN
+		cache := validate.NewVerificationCache(validate.CacheConfig{PositiveTTL: time.Minute, MaxSize: 2})
+
+		//WHEN
+		cache.Set("invalid", pkg.NewValidationFailedErr(errors.New("invalid")))
+
+		//THEN
+		found, _ := cache.Get("invalid")
+		require.False(t, found)
+	})
+}
@@ -0,0 +1,234 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+)
+
+const (
+	cosignSignatureTagSuffix  = "sig"
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignSimpleSigningType   = "cosign container image signature"
+
+	// maxSignatureLayerSize limits the size of signature layers downloaded from registries
+	maxSignatureLayerSize = 4 << 20
+)
+
+type CosignConfig struct {
+	PublicKeys []crypto.PublicKey
+	Timeout    time.Duration
+}
+
+type cosignService struct {
+	CosignConfig
+	AllowedRegistries []string
+}
+
+// simpleSigningPayload is the payload signed by cosign, see:
+// https://github.com/containers/image/blob/main/docs/containers-signature.5.md#json-data-format
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+func NewCosignValidator(cc *CosignConfig, allowedRegistries []string) ImageValidatorService {
+	return &cosignService{
+		CosignConfig:      *cc,
+		AllowedRegistries: allowedRegistries,
+	}
+}
+
+// ParseCosignPublicKeys parses all PEM encoded public keys from the given string
+func ParseCosignPublicKeys(keys string) ([]crypto.PublicKey, error) {
+	var publicKeys []crypto.PublicKey
+	rest := []byte(keys)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, errors.Errorf("unexpected PEM block type: %s", block.Type)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "while parsing public key")
+		}
+		publicKeys = append(publicKeys, key)
+	}
+	if len(publicKeys) == 0 {
+		return nil, errors.New("no public keys found")
+	}
+	return publicKeys, nil
+}
+
+func (s *cosignService) Validate(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) error {
+	logger := helpers.LoggerFromCtx(ctx).With("image", image)
+	ctx = helpers.LoggerToContext(ctx, logger)
+
+	reportImageDetails(ctx, imageDetailsValues{verifier: pkg.VerifierCosign})
+	if allowed := isImageAllowed(image, s.AllowedRegistries); allowed {
+		logger.Info("image validation skipped, because it's allowed")
+		reportImageDetails(ctx, imageDetailsValues{reason: ReasonAllowed})
+		return nil
+	}
+
+	// strict validation requires image name to contain domain and a tag, and/or sha256
+	ref, err := name.ParseReference(image, name.StrictValidation)
+	if err != nil {
+		return withReason(ReasonInvalidReference, pkg.NewValidationFailedErr(errors.Wrap(err, "image name could not be parsed")))
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	const message = "request to image registry (cosign)"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+
+	descriptor, remoteOptions, err := getImageDescriptor(ctx, ref, imagePullCredentials, remote.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	reportImageDetails(ctx, imageDetailsValues{digest: descriptor.Digest.String()})
+
+	return s.verifySignatures(ref, descriptor.Digest, remoteOptions...)
+}
+
+func (s *cosignService) verifySignatures(ref name.Reference, digest v1.Hash, remoteOptions ...remote.Option) error {
+	sigTag := ref.Context().Tag(fmt.Sprintf("%s-%s.%s", digest.Algorithm, digest.Hex, cosignSignatureTagSuffix))
+	sigImage, err := remote.Image(sigTag, remoteOptions...)
+	if err != nil {
+		if isNotFoundErr(err) {
+			return withReason(ReasonSignatureNotFound, pkg.NewValidationFailedErr(errors.Errorf("no cosign signatures found for %s", digest)))
+		}
+		return newRegistryErr(err, "get cosign signatures")
+	}
+
+	manifest, err := sigImage.Manifest()
+	if err != nil {
+		return pkg.NewUnknownResultErr(errors.Wrap(err, "cosign signatures manifest"))
+	}
+
+	for _, layer := range manifest.Layers {
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		payload, err := getLayerPayload(sigImage, layer)
+		if err != nil {
+			return err
+		}
+		if s.verifySignature(payload, signature, digest) == nil {
+			return nil
+		}
+	}
+
+	return withReason(ReasonSignatureInvalid, pkg.NewValidationFailedErr(errors.Errorf("no valid cosign signature found for %s", digest)))
+}
+
+func getLayerPayload(img v1.Image, descriptor v1.Descriptor) ([]byte, error) {
+	if descriptor.Size > maxSignatureLayerSize {
+		return nil, withReason(ReasonSignatureInvalid, pkg.NewValidationFailedErr(
+			errors.Errorf("signature layer %s exceeds the maximum size of %d bytes", descriptor.Digest, maxSignatureLayerSize)))
+	}
+	layer, err := img.LayerByDigest(descriptor.Digest)
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get signature layer"))
+	}
+	reader, err := layer.Compressed()
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "read signature layer"))
+	}
+	defer reader.Close()
+	// the descriptor size is not trusted, the registry may serve more data than announced
+	payload, err := io.ReadAll(io.LimitReader(reader, maxSignatureLayerSize+1))
+	if err != nil {
+		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "read signature layer"))
+	}
+	if len(payload) > maxSignatureLayerSize {
+		return nil, withReason(ReasonSignatureInvalid, pkg.NewValidationFailedErr(
+			errors.Errorf("signature layer %s exceeds the maximum size of %d bytes", descriptor.Digest, maxSignatureLayerSize)))
+	}
+	return payload, nil
+}
+
+func (s *cosignService) verifySignature(payload []byte, signature string, digest v1.Hash) error {
+	rawSignature, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "cannot decode base64 encoded signature")
+	}
+
+	verified := false
+	for _, key := range s.PublicKeys {
+		if verifyWithKey(key, payload, rawSignature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return errors.New("signature does not match any public key")
+	}
+
+	var p simpleSigningPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return errors.Wrap(err, "cannot unmarshal signature payload")
+	}
+	if p.Critical.Type != cosignSimpleSigningType {
+		return errors.Errorf("unexpected signature type: %s", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != digest.String() {
+		return errors.New("unexpected image hash value")
+	}
+	return nil
+}
+
+func verifyWithKey(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	}
+	return false
+}
+
+func isNotFoundErr(err error) bool {
+	var transportErr *transport.Error
+	if errors.As(err, &transportErr) {
+		return transportErr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package validate_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+const cosignSimpleSigningMediaType types.MediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+func Test_CosignValidate(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+
+	signingKey := newCosignKey(t)
+	otherKey := newCosignKey(t)
+
+	signedImage := pushRandomImage(t, registryHost, "signed:v1")
+	signImage(t, signedImage, signingKey, "")
+
+	unsignedImage := pushRandomImage(t, registryHost, "unsigned:v1")
+
+	otherKeyImage := pushRandomImage(t, registryHost, "other-key:v1")
+	signImage(t, otherKeyImage, otherKey, "")
+
+	differentDigestImage := pushRandomImage(t, registryHost, "different-digest:v1")
+	signImage(t, differentDigestImage, signingKey, "sha256:0000000000000000000000000000000000000000000000000000000000000000")
+
+	oversizedSignatureImage := pushRandomImage(t, registryHost, "oversized-signature:v1")
+	pushSignature(t, oversizedSignatureImage, make([]byte, 4<<20+1), "")
+
+	cfg := validate.CosignConfig{
+		PublicKeys: []crypto.PublicKey{&signingKey.PublicKey},
+		Timeout:    time.Second * 5,
+	}
+
+	t.Run("signed image should pass", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), signedImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("image signed with one of keys should pass", func(t *testing.T) {
+		multiKeyCfg := validate.CosignConfig{
+			PublicKeys: []crypto.PublicKey{&otherKey.PublicKey, &signingKey.PublicKey},
+		}
+		s := validate.NewCosignValidator(&multiKeyCfg, nil)
+
+		err := s.Validate(context.TODO(), signedImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("unsigned image should return validation error", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), unsignedImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "no cosign signatures found")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("image signed with unknown key should return validation error", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), otherKeyImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "no valid cosign signature found")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("signature for different digest should return validation error", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), differentDigestImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "no valid cosign signature found")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("oversized signature layer should return validation error", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), oversizedSignatureImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "exceeds the maximum size")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("not existing image should return unknown result", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), fmt.Sprintf("%s/not-existing:v1", registryHost), emptyAuthData)
+
+		require.ErrorContains(t, err, "get image descriptor anonymously")
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+	})
+
+	t.Run("invalid image name should return validation error", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, nil)
+
+		err := s.Validate(context.TODO(), "makapaka", emptyAuthData)
+
+		require.ErrorContains(t, err, "image name could not be parsed")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("unsigned image from allowed registry should pass", func(t *testing.T) {
+		s := validate.NewCosignValidator(&cfg, []string{registryHost})
+
+		err := s.Validate(context.TODO(), unsignedImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+}
+
+func Test_ParseCosignPublicKeys(t *testing.T) {
+	key := newCosignKey(t)
+	keyPEM := encodePublicKey(t, &key.PublicKey)
+
+	t.Run("parse multiple keys", func(t *testing.T) {
+		keys, err := validate.ParseCosignPublicKeys(keyPEM + "\n" + keyPEM)
+
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+	})
+
+	t.Run("no keys", func(t *testing.T) {
+		keys, err := validate.ParseCosignPublicKeys("")
+
+		require.ErrorContains(t, err, "no public keys found")
+		require.Nil(t, keys)
+	})
+
+	t.Run("unexpected block type", func(t *testing.T) {
+		keys, err := validate.ParseCosignPublicKeys(strings.ReplaceAll(keyPEM, "PUBLIC KEY", "PRIVATE KEY"))
+
+		require.ErrorContains(t, err, "unexpected PEM block type")
+		require.Nil(t, keys)
+	})
+}
+
+func newCosignKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func encodePublicKey(t *testing.T, key crypto.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func pushRandomImage(t *testing.T, registryHost, repoTag string) name.Digest {
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(fmt.Sprintf("%s/%s", registryHost, repoTag))
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	return ref.Context().Digest(digest.String())
+}
+
+// signImage pushes cosign-compatible signature of the image, signed digest can be overridden
+func signImage(t *testing.T, image name.Digest, key *ecdsa.PrivateKey, signedDigest string) {
+	if signedDigest == "" {
+		signedDigest = image.DigestStr()
+	}
+	payload := fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`,
+		image.Context().Name(), signedDigest)
+	hash := sha256.Sum256([]byte(payload))
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	pushSignature(t, image, []byte(payload), base64.StdEncoding.EncodeToString(signature))
+}
+
+func pushSignature(t *testing.T, image name.Digest, payload []byte, signature string) {
+	sigImage, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(payload, cosignSimpleSigningMediaType),
+		Annotations: map[string]string{
+			"dev.cosignproject.cosign/signature": signature,
+		},
+	})
+	require.NoError(t, err)
+
+	digest, err := v1.NewHash(image.DigestStr())
+	require.NoError(t, err)
+	sigTag := image.Context().Tag(fmt.Sprintf("%s-%s.sig", digest.Algorithm, digest.Hex))
+	require.NoError(t, remote.Write(sigTag, sigImage))
+}
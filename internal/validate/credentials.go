@@ -0,0 +1,132 @@
+package validate
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+var _ authn.Keychain = &podKeychain{}
+
+// podKeychain resolves the image pull credentials of the pod
+type podKeychain struct {
+	credentials map[string]cliType.AuthConfig
+}
+
+// NewKeychain returns keychain resolving the image pull credentials like the kubelet does: the credentials with
+// the most specific key matching the repository are used, e.g. `registry.io/team` before `registry.io`, and
+// the key host can contain wildcards, e.g. `*.registry.io`. Repositories without matching credentials use
+// the docker config and the credential helpers of the Warden process, or the anonymous access
+func NewKeychain(imagePullCredentials map[string]cliType.AuthConfig) authn.Keychain {
+	return authn.NewMultiKeychain(&podKeychain{credentials: imagePullCredentials}, authn.DefaultKeychain)
+}
+
+func (k *podKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	credentials, ok := lookupCredentials(target, k.credentials)
+	if !ok {
+		return authn.Anonymous, nil
+	}
+	authenticator, err := parseCredentials(credentials)
+	if err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+// lookupCredentials returns credentials with the most specific key matching the repository,
+// the target is matched by the registry only when it isn't the repository
+func lookupCredentials(target authn.Resource, imagePullCredentials map[string]cliType.AuthConfig) (cliType.AuthConfig, bool) {
+	repository := ""
+	if repo, ok := target.(name.Repository); ok {
+		repository = repo.RepositoryStr()
+	}
+
+	keys := make([]string, 0, len(imagePullCredentials))
+	for key := range imagePullCredentials {
+		keys = append(keys, key)
+	}
+	// longer keys are more specific, the order of the keys of the same length is stable
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	for _, key := range keys {
+		if credentialsKeyMatches(key, target.RegistryStr(), repository) {
+			return imagePullCredentials[key], true
+		}
+	}
+	return cliType.AuthConfig{}, false
+}
+
+func credentialsKeyMatches(key, registry, repository string) bool {
+	keyHost, keyPath, _ := strings.Cut(key, "/")
+	if !hostMatches(strings.ToLower(keyHost), strings.ToLower(registry)) {
+		return false
+	}
+	return keyPath == "" || repository == keyPath || strings.HasPrefix(repository, keyPath+"/")
+}
+
+// hostMatches matches the host against the pattern, each domain label of the pattern can be the glob,
+// the ports have to be the same
+func hostMatches(pattern, host string) bool {
+	if pattern == host {
+		return true
+	}
+	patternName, patternPort, _ := strings.Cut(pattern, ":")
+	hostName, hostPort, _ := strings.Cut(host, ":")
+	if patternPort != hostPort {
+		return false
+	}
+	patternLabels := strings.Split(patternName, ".")
+	hostLabels := strings.Split(hostName, ".")
+	if len(patternLabels) != len(hostLabels) {
+		return false
+	}
+	for i := range patternLabels {
+		if matched, err := path.Match(patternLabels[i], hostLabels[i]); err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// withRegistryAuth calls the fetch with the credentials resolved for the image repository, or anonymously when
+// there are none. The anonymous access is tried also when the credentials are rejected or can't be parsed,
+// so public images are verified even with broken image pull secrets. The remote options used by
+// the successful fetch are returned, they have to be used for further requests to the same repository
+func withRegistryAuth[T any](ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig, remoteOptions []remote.Option, message string, fetch func(...remote.Option) (T, error)) (T, []remote.Option, error) {
+	var empty T
+	authenticator, credentialsErr := NewKeychain(imagePullCredentials).Resolve(ref.Context())
+	var rejectedErr error
+	if credentialsErr == nil && authenticator != authn.Anonymous {
+		authOptions := append(slices.Clip(remoteOptions), remote.WithAuth(authenticator))
+		result, err := fetch(authOptions...)
+		if err == nil {
+			return result, authOptions, nil
+		}
+		if !isRegistryAuthErr(err) {
+			return empty, nil, newRegistryErr(err, message)
+		}
+		rejectedErr = err
+	}
+
+	result, err := fetch(remoteOptions...)
+	if err == nil {
+		return result, remoteOptions, nil
+	}
+	if credentialsErr != nil {
+		return empty, nil, withReason(ReasonRegistryAuthFailed, credentialsErr)
+	}
+	if rejectedErr != nil {
+		return empty, nil, newRegistryErr(rejectedErr, message)
+	}
+	return empty, nil, newRegistryErr(err, message+" anonymously")
+}
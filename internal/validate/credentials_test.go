@@ -0,0 +1,142 @@
+package validate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_lookupCredentials(t *testing.T) {
+	credentials := map[string]cliType.AuthConfig{
+		"registry.io":            {Username: "registry"},
+		"registry.io/team":       {Username: "team"},
+		"registry.io/team/app":   {Username: "app"},
+		"*.cloud.io":             {Username: "cloud"},
+		"localhost:5000":         {Username: "local"},
+		"index.docker.io/user":   {Username: "hub-user"},
+		"index.docker.io":        {Username: "hub"},
+		"other.io/team/app/long": {Username: "other"},
+	}
+	tests := []struct {
+		name     string
+		image    string
+		want     string
+		wantNone bool
+	}{
+		{name: "registry", image: "registry.io/other/app:1.0", want: "registry"},
+		{name: "repository path", image: "registry.io/team/sidecar:1.0", want: "team"},
+		{name: "most specific path", image: "registry.io/team/app:1.0", want: "app"},
+		{name: "path on segment boundary", image: "registry.io/team-evil/app:1.0", want: "registry"},
+		{name: "wildcard host", image: "eu.cloud.io/app:1.0", want: "cloud"},
+		{name: "wildcard matches single label", image: "a.eu.cloud.io/app:1.0", wantNone: true},
+		{name: "registry with port", image: "localhost:5000/app:1.0", want: "local"},
+		{name: "registry with different port", image: "localhost:5001/app:1.0", wantNone: true},
+		{name: "docker hub user", image: "user/app:1.0", want: "hub-user"},
+		{name: "docker hub official image", image: "nginx:1.25", want: "hub"},
+		{name: "longer path doesn't match", image: "other.io/team/app:1.0", wantNone: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+
+			got, found := lookupCredentials(ref.Context(), credentials)
+
+			require.Equal(t, !tt.wantNone, found)
+			require.Equal(t, tt.want, got.Username)
+		})
+	}
+}
+
+func Test_getImageDescriptor_WithCredentials(t *testing.T) {
+	//GIVEN
+	registryHandler := registry.New()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// images in the private path require credentials of the team
+		if strings.Contains(r.URL.Path, "/private/") {
+			if user, password, ok := r.BasicAuth(); !ok || user != "team" || password != "secret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		registryHandler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+	for _, repo := range []string{"private/app:v1", "public/app:v1"} {
+		img, err := random.Image(256, 1)
+		require.NoError(t, err)
+		ref, err := name.ParseReference(registryHost + "/" + repo)
+		require.NoError(t, err)
+		require.NoError(t, remote.Write(ref, img, remote.WithAuth(&authn.Basic{Username: "team", Password: "secret"})))
+	}
+
+	tests := []struct {
+		name           string
+		image          string
+		credentials    map[string]cliType.AuthConfig
+		expectedReason Reason
+	}{
+		{
+			name:  "path specific credentials",
+			image: registryHost + "/private/app:v1",
+			credentials: map[string]cliType.AuthConfig{
+				registryHost:              {Username: "other", Password: "other"},
+				registryHost + "/private": {Username: "team", Password: "secret"},
+			},
+		},
+		{
+			name:           "without credentials",
+			image:          registryHost + "/private/app:v1",
+			expectedReason: ReasonRegistryAuthFailed,
+		},
+		{
+			name:  "rejected credentials",
+			image: registryHost + "/private/app:v1",
+			credentials: map[string]cliType.AuthConfig{
+				registryHost: {Username: "other", Password: "other"},
+			},
+			expectedReason: ReasonRegistryAuthFailed,
+		},
+		{
+			name:  "public image with rejected credentials",
+			image: registryHost + "/public/app:v1",
+			credentials: map[string]cliType.AuthConfig{
+				registryHost + "/public": {Auth: "bm90LWJhc2ljLWF1dGg="},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+
+			//WHEN
+			descriptor, _, err := getImageDescriptor(context.TODO(), ref, tt.credentials)
+
+			//THEN
+			if tt.expectedReason != "" {
+				require.Error(t, err)
+				require.Equal(t, tt.expectedReason, ReasonFromError(err))
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, descriptor)
+
+			digest, err := resolveImageDigest(ref, tt.credentials)
+			require.NoError(t, err)
+			require.Equal(t, descriptor.Digest, digest)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// deniedDigestRule matches rules denying the image digest, optionally only in the given repository
+var deniedDigestRule = regexp.MustCompile(`^(?:(.+)@)?(sha256:[a-f0-9]{64})$`)
+
+// ParseDeniedImages parses the comma-separated list of denied images rules
+func ParseDeniedImages(images string) []string {
+	return ParseAllowedRegistries(images)
+}
+
+// ValidateDeniedImages returns error when any of the denied images rules can't be matched
+func ValidateDeniedImages(deniedImages []string) error {
+	for _, rule := range deniedImages {
+		if err := validateImageRule(rule); err != nil {
+			return errors.Wrapf(err, "invalid denied image %s", rule)
+		}
+	}
+	return nil
+}
+
+var _ PodValidator = &denyingPodValidator{}
+
+type denyingPodValidator struct {
+	validator PodValidator
+	// patternRules deny images by name, digestRules deny images by digest, optionally only in the repository
+	patternRules []string
+	digestRules  [][]string
+	workers      int
+}
+
+// NewDenyingPodValidator creates validator which rejects images matching the denied images rules before
+// they are verified, the rest of the images is validated with the given validator.
+// Rules have the format of allowed registries (see MatchAllowedRegistry), or they deny the digest
+// in the `sha256:<hex>` or `<repository>@sha256:<hex>` format. The digest of the image is resolved
+// in the registry when it's not pinned in the image name, images whose digest can't be resolved
+// are reported as ServiceUnavailable, so they aren't admitted in the strict mode
+func NewDenyingPodValidator(validator PodValidator, deniedImages []string) PodValidator {
+	if len(deniedImages) == 0 {
+		return validator
+	}
+	v := &denyingPodValidator{
+		validator: validator,
+		workers:   DefaultImageValidationWorkers,
+	}
+	for _, rule := range deniedImages {
+		if match := deniedDigestRule.FindStringSubmatch(rule); match != nil {
+			v.digestRules = append(v.digestRules, match)
+			continue
+		}
+		v.patternRules = append(v.patternRules, rule)
+	}
+	return v
+}
+
+// denyCheck is the result of the denied images check of a single image
+type denyCheck struct {
+	rule   string
+	digest string
+	err    error
+}
+
+func (v *denyingPodValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error) {
+	logger := helpers.LoggerFromCtx(ctx)
+
+	// digests are resolved once per image, concurrently
+	images := sortedImageNames(getAllImages(pod))
+	checks := make([]denyCheck, len(images))
+	forEachImage(ctx, images, v.workers, func(i int, image string) {
+		checks[i] = v.check(ctx, image, imagePullCredentials)
+	}, func(i int, image string) {
+		checks[i] = denyCheck{err: pkg.NewUnknownResultErr(errors.Wrap(ctx.Err(), "denied images check interrupted"))}
+	})
+
+	var blocked []ImageVerdict
+	allowedImages := map[string]struct{}{}
+	for i, image := range images {
+		check := checks[i]
+		switch {
+		case check.err != nil:
+			logger.Infow("image can't be checked against the denied images", "image", image, "err", check.err.Error())
+			status := Invalid
+			if pkg.ErrorCode(check.err) == pkg.UnknownResult {
+				status = ServiceUnavailable
+			}
+			blocked = append(blocked, newImageVerdict(image, status, check.err, imageDetailsValues{}))
+		case check.rule != "":
+			logger.Infow("image is denied", "image", image, "rule", check.rule)
+			err := withReason(ReasonDenied, pkg.NewValidationFailedErr(errors.Errorf("image is denied by rule %s", check.rule)))
+			blocked = append(blocked, newImageVerdict(image, Invalid, err, imageDetailsValues{digest: check.digest}))
+		default:
+			allowedImages[image] = struct{}{}
+		}
+	}
+	if len(blocked) == 0 {
+		return v.validator.ValidatePod(ctx, pod, ns, imagePullCredentials)
+	}
+
+	result := ValidationResult{Status: Valid}
+	if len(allowedImages) != 0 {
+		var err error
+		result, err = v.validator.ValidatePod(ctx, podWithImages(pod, allowedImages), ns, imagePullCredentials)
+		if err != nil {
+			return ValidationResult{Status: Invalid}, err
+		}
+	}
+
+	for _, verdict := range blocked {
+		result.Status = mergeValidationStatus(result.Status, verdict.Status)
+		result.InvalidImages = append(result.InvalidImages, verdict.Image)
+		result.Images = append(result.Images, verdict)
+		if verdict.Status == ServiceUnavailable {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("image %s: verification is pending: %s", verdict.Image, verdict.Message))
+		}
+	}
+	sort.Strings(result.InvalidImages)
+	sort.Slice(result.Images, func(i, j int) bool { return result.Images[i].Image < result.Images[j].Image })
+	return result, nil
+}
+
+// check returns the first rule denying the image, and the digest of the image when it's denied by the digest.
+// The error is returned when the digest of the image can't be resolved to check the digest rules
+func (v *denyingPodValidator) check(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) denyCheck {
+	for _, rule := range v.patternRules {
+		if MatchAllowedRegistry(rule, image) {
+			return denyCheck{rule: rule}
+		}
+	}
+	if len(v.digestRules) == 0 {
+		return denyCheck{}
+	}
+
+	digest, err := imageDigest(ctx, image, imagePullCredentials)
+	if err != nil {
+		return denyCheck{err: err}
+	}
+	for _, match := range v.digestRules {
+		repository := match[1]
+		if match[2] != digest {
+			continue
+		}
+		if repository == "" || normalizeImageName(repository) == normalizeImageName(repositoryName(image)) {
+			return denyCheck{rule: match[0], digest: digest}
+		}
+	}
+	return denyCheck{}
+}
+
+// imageDigest returns the digest pinned in the image name, or the digest resolved in the registry
+func imageDigest(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) (string, error) {
+	if _, digest, found := strings.Cut(image, "@"); found {
+		return digest, nil
+	}
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", withReason(ReasonInvalidReference, pkg.NewValidationFailedErr(errors.Wrap(err, "image name can't be parsed")))
+	}
+	digest, err := loggedResolveImageDigest(ctx, ref, imagePullCredentials)
+	if err != nil {
+		return "", withReason(ReasonFromError(err),
+			pkg.NewUnknownResultErr(errors.Wrap(err, "digest of the image can't be resolved for the denied images check")))
+	}
+	return digest.String(), nil
+}
@@ -0,0 +1,140 @@
+package validate_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDenyingPodValidator_ValidatePod(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+	revoked := pushRandomImage(t, registryHost, "app:revoked")
+	good := pushRandomImage(t, registryHost, "app:good")
+	revokedImage := revoked.Context().Tag("revoked").String()
+	goodImage := good.Context().Tag("good").String()
+
+	tests := []struct {
+		name                  string
+		deniedImages          []string
+		images                []string
+		expectedValidated     []string
+		expectedStatus        validate.ValidationStatus
+		expectedInvalidImages []string
+		expectedDeniedBy      map[string]string
+		expectedPending       []string
+	}{
+		{
+			name:              "no image is denied",
+			deniedImages:      []string{"registry.io/evil"},
+			images:            []string{goodImage, "registry.io/team/app:1.0"},
+			expectedValidated: []string{goodImage, "registry.io/team/app:1.0"},
+			expectedStatus:    validate.Valid,
+		},
+		{
+			name:                  "image denied by repository",
+			deniedImages:          []string{"registry.io/evil"},
+			images:                []string{"registry.io/evil/app:1.0", "registry.io/team/app:1.0"},
+			expectedValidated:     []string{"registry.io/team/app:1.0"},
+			expectedStatus:        validate.Invalid,
+			expectedInvalidImages: []string{"registry.io/evil/app:1.0"},
+			expectedDeniedBy:      map[string]string{"registry.io/evil/app:1.0": "registry.io/evil"},
+		},
+		{
+			name:                  "all images denied by pattern",
+			deniedImages:          []string{"registry.io/*/app:1.*"},
+			images:                []string{"registry.io/evil/app:1.0", "registry.io/team/app:1.2"},
+			expectedStatus:        validate.Invalid,
+			expectedInvalidImages: []string{"registry.io/evil/app:1.0", "registry.io/team/app:1.2"},
+			expectedDeniedBy: map[string]string{
+				"registry.io/evil/app:1.0": "registry.io/*/app:1.*",
+				"registry.io/team/app:1.2": "registry.io/*/app:1.*",
+			},
+		},
+		{
+			name:                  "image denied by resolved digest",
+			deniedImages:          []string{revoked.DigestStr()},
+			images:                []string{revokedImage, goodImage},
+			expectedValidated:     []string{goodImage},
+			expectedStatus:        validate.Invalid,
+			expectedInvalidImages: []string{revokedImage},
+			expectedDeniedBy:      map[string]string{revokedImage: revoked.DigestStr()},
+		},
+		{
+			name:                  "image denied by pinned digest in repository",
+			deniedImages:          []string{revoked.String()},
+			images:                []string{revokedImage + "@" + revoked.DigestStr()},
+			expectedStatus:        validate.Invalid,
+			expectedInvalidImages: []string{revokedImage + "@" + revoked.DigestStr()},
+			expectedDeniedBy:      map[string]string{revokedImage + "@" + revoked.DigestStr(): revoked.String()},
+		},
+		{
+			name:              "digest denied in other repository",
+			deniedImages:      []string{"registry.io/other@" + revoked.DigestStr()},
+			images:            []string{revokedImage},
+			expectedValidated: []string{revokedImage},
+			expectedStatus:    validate.Valid,
+		},
+		{
+			name:                  "digest of image which can't be resolved",
+			deniedImages:          []string{revoked.DigestStr()},
+			images:                []string{registryHost + "/app:missing", goodImage},
+			expectedValidated:     []string{goodImage},
+			expectedStatus:        validate.ServiceUnavailable,
+			expectedInvalidImages: []string{registryHost + "/app:missing"},
+			expectedPending:       []string{registryHost + "/app:missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			imageValidator := mocks.NewImageValidatorService(t)
+			for _, image := range tt.expectedValidated {
+				imageValidator.On("Validate", mock.Anything, image, mock.Anything).Return(nil).Once()
+			}
+			validator := validate.NewDenyingPodValidator(validate.NewPodValidator(imageValidator), tt.deniedImages)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "denied"}}
+			for _, image := range tt.images {
+				pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Image: image})
+			}
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "denied"}}
+
+			//WHEN
+			result, err := validator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+			//THEN
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedStatus, result.Status)
+			require.ElementsMatch(t, tt.expectedInvalidImages, result.InvalidImages)
+			require.Len(t, result.Images, len(tt.images))
+			require.Len(t, result.Warnings, len(tt.expectedPending))
+			for _, verdict := range result.Images {
+				if slices.Contains(tt.expectedPending, verdict.Image) {
+					require.Equal(t, validate.ServiceUnavailable, verdict.Status)
+					require.Equal(t, validate.ReasonRegistryUnavailable, verdict.Reason)
+					require.Contains(t, verdict.Message, "digest of the image can't be resolved")
+					continue
+				}
+				rule, denied := tt.expectedDeniedBy[verdict.Image]
+				if !denied {
+					require.Equal(t, validate.Valid, verdict.Status)
+					continue
+				}
+				require.Equal(t, validate.Invalid, verdict.Status)
+				require.Equal(t, validate.ReasonDenied, verdict.Reason)
+				require.Contains(t, verdict.Message, "image is denied by rule "+rule)
+			}
+		})
+	}
+}
@@ -18,20 +18,37 @@ package validate
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
+	"sort"
 	"strings"
+	"time"
 
 	cliType "github.com/docker/cli/cli/config/types"
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
+	"github.com/theupdateframework/notary/client"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
+var (
+	// notaryLookups and registryLookups collapse concurrent lookups of the same image into one upstream request
+	notaryLookups   singleflight.Group
+	registryLookups singleflight.Group
+)
+
+// defaultSharedLookupTimeout limits lookups shared by concurrent validations when the validator has no timeout
+const defaultSharedLookupTimeout = 30 * time.Second
+
 //go:generate mockery --name=ImageValidatorService
 type ImageValidatorService interface {
 	Validate(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) error
@@ -40,6 +57,9 @@ type ImageValidatorService interface {
 type ServiceConfig struct {
 	NotaryConfig      NotaryConfig
 	AllowedRegistries []string
+	RegistryMirrors   RegistryMirrors
+	// Timeout limits the upstream lookups, which are shared by concurrent validations
+	Timeout time.Duration
 }
 
 type notaryService struct {
@@ -52,6 +72,8 @@ func NewImageValidator(sc *ServiceConfig, notaryClientFactory RepoFactory) Image
 		ServiceConfig: ServiceConfig{
 			NotaryConfig:      sc.NotaryConfig,
 			AllowedRegistries: sc.AllowedRegistries,
+			RegistryMirrors:   sc.RegistryMirrors,
+			Timeout:           sc.Timeout,
 		},
 		RepoFactory: notaryClientFactory,
 	}
@@ -61,26 +83,34 @@ func (s *notaryService) Validate(ctx context.Context, image string, imagePullCre
 	logger := helpers.LoggerFromCtx(ctx).With("image", image)
 	ctx = helpers.LoggerToContext(ctx, logger)
 
+	reportImageDetails(ctx, imageDetailsValues{verifier: pkg.VerifierNotary})
 	if allowed := s.isImageAllowed(image); allowed {
 		logger.Info("image validation skipped, because it's allowed")
+		reportImageDetails(ctx, imageDetailsValues{reason: ReasonAllowed})
 		return nil
 	}
 
 	// strict validation requires image name to contain domain and a tag, and/or sha256
 	ref, err := name.ParseReference(image, name.StrictValidation)
 	if err != nil {
-		return pkg.NewValidationFailedErr(errors.Wrap(err, "image name could not be parsed"))
+		return withReason(ReasonInvalidReference, pkg.NewValidationFailedErr(errors.Wrap(err, "image name could not be parsed")))
 	}
 
-	expectedShaBytes, err := s.loggedGetNotaryImageDigestHash(ctx, ref)
+	notaryRef := ref
+	if tag, ok := pinnedTag(image, ref); ok {
+		notaryRef = tag
+	}
+	// trust data of mirrored images is published for the upstream repository
+	expectedShaBytes, err := s.loggedGetNotaryImageDigestHash(ctx, s.RegistryMirrors.TrustReference(notaryRef))
 	if err != nil {
 		return err
 	}
 
-	shaImageBytes, shaManifestBytes, err := s.loggedGetRepositoryDigestHash(ctx, ref, imagePullCredentials)
+	shaImageBytes, shaManifestBytes, err := s.loggedGetRepositoryDigestHash(ctx, s.RegistryMirrors.DigestReference(ref), imagePullCredentials)
 	if err != nil {
 		return err
 	}
+	reportImageDetails(ctx, imageDetailsValues{digest: "sha256:" + hex.EncodeToString(shaImageBytes)})
 
 	if subtle.ConstantTimeCompare(shaImageBytes, expectedShaBytes) == 1 {
 		return nil
@@ -88,66 +118,129 @@ func (s *notaryService) Validate(ctx context.Context, image string, imagePullCre
 
 	if shaManifestBytes != nil && subtle.ConstantTimeCompare(shaManifestBytes, expectedShaBytes) == 1 {
 		logger.Warn("deprecated: manifest hash was used for verification")
+		AddWarning(ctx, "deprecated: manifest hash was used for verification, sign the image digest instead")
 		return nil
 	}
 
-	return pkg.NewValidationFailedErr(errors.New("unexpected image hash value"))
+	return withReason(ReasonDigestMismatch, pkg.NewValidationFailedErr(errors.New("unexpected image hash value")))
 }
 
-func (s *notaryService) isImageAllowed(imgRepo string) bool {
-	for _, allowed := range s.AllowedRegistries {
-		// repository is in allowed list
-		if strings.HasPrefix(imgRepo, allowed) {
-			return true
-		}
+// pinnedTag returns the tag of the image pinned to the digest in the `repository:tag@digest` form,
+// notary trust data of such image is looked up by the tag, and the image is fetched by the digest
+func pinnedTag(image string, ref name.Reference) (name.Tag, bool) {
+	if _, ok := ref.(name.Digest); !ok {
+		return name.Tag{}, false
+	}
+	base, _, _ := strings.Cut(image, "@")
+	// strict validation fails when the tag is not set explicitly
+	tag, err := name.NewTag(base, name.StrictValidation)
+	if err != nil {
+		return name.Tag{}, false
 	}
-	return false
+	return tag, true
+}
+
+func (s *notaryService) isImageAllowed(imgRepo string) bool {
+	return isImageAllowed(imgRepo, s.AllowedRegistries)
+}
+
+type repositoryDigestHash struct {
+	image    []byte
+	manifest []byte
 }
 
 func (s *notaryService) loggedGetRepositoryDigestHash(ctx context.Context, ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) ([]byte, []byte, error) {
+	// credentials are part of the key, so the result is never shared with a caller which couldn't get it itself
+	credentials, _ := lookupCredentials(ref.Context(), imagePullCredentials)
+	key := strings.Join([]string{ref.Name(), credentialsKey(credentials)}, "|")
 	const message = "request to image registry"
 	closeLog := helpers.LogStartTime(ctx, message)
 	defer closeLog()
-	return s.getRepositoryDigestHash(ref, imagePullCredentials)
+	result, err := doShared(ctx, &registryLookups, key, s.Timeout, func(ctx context.Context) (repositoryDigestHash, error) {
+		defer observeDuration(registryRequestDuration, registryOperationGetDescriptor)()
+		image, manifest, err := s.getRepositoryDigestHash(ctx, ref, imagePullCredentials)
+		return repositoryDigestHash{image: image, manifest: manifest}, err
+	})
+	return result.image, result.manifest, err
 }
 
-func (s *notaryService) getRepositoryDigestHash(ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) ([]byte, []byte, error) {
-	remoteOptions := make([]remote.Option, 0)
+// doShared calls fn or waits for the result of the call with the same key which is already in flight.
+// The call is shared by concurrent validations, so it doesn't inherit the cancellation, the trace and the logger
+// of the caller which started it, it's limited only by the timeout. Every caller stops waiting when its own
+// context is done, while the call is finished for the other callers
+func doShared[T any](ctx context.Context, group *singleflight.Group, key string, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		timeout = defaultSharedLookupTimeout
+	}
+	resultChan := group.DoChan(key, func() (interface{}, error) {
+		sharedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+		sharedCtx = helpers.LoggerToContext(sharedCtx, zap.NewNop().Sugar())
+		sharedCtx, span := tracing.StartDetachedSpan(sharedCtx, "shared lookup")
+		result, err := fn(sharedCtx)
+		tracing.EndSpan(span, err)
+		return result, err
+	})
+
+	var empty T
+	select {
+	case result := <-resultChan:
+		if result.Shared {
+			helpers.LoggerFromCtx(ctx).With("key", key).Debug("result of upstream request shared between concurrent validations")
+		}
+		if result.Err != nil {
+			return empty, result.Err
+		}
+		return result.Val.(T), nil
+	case <-ctx.Done():
+		return empty, pkg.NewUnknownResultErr(errors.Wrap(ctx.Err(), "waiting for upstream request"))
+	}
+}
+
+func credentialsKey(credentials cliType.AuthConfig) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{
+		credentials.Username,
+		credentials.Password,
+		credentials.Auth,
+		credentials.RegistryToken,
+	}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
 
-	credentials, credentialsOk := imagePullCredentials[ref.Context().RegistryStr()]
+// PullCredentialsKey returns the hash of all image pull credentials, so results of validations made
+// with different credentials can be told apart without keeping the credentials themselves
+func PullCredentialsKey(imagePullCredentials map[string]cliType.AuthConfig) string {
+	keys := make([]string, 0, len(imagePullCredentials))
+	for key := range imagePullCredentials {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		values = append(values, key, credentialsKey(imagePullCredentials[key]))
+	}
+	hash := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
 
-	//try to get image info without credentials, mimicking Kuberenetes behavior
-	descriptor, err := remote.Get(ref)
+func (s *notaryService) getRepositoryDigestHash(ctx context.Context, ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig) ([]byte, []byte, error) {
+	descriptor, remoteOptions, err := getImageDescriptor(ctx, ref, imagePullCredentials, remote.WithContext(ctx))
 	if err != nil {
-		if !credentialsOk {
-			// no fitting credentials, and no public access, return error
-			return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image descriptor anonymously"))
-		} else {
-			// to to authenticate to the registry
-
-			credentials, err := parseCredentials(credentials)
-			if err != nil {
-				return nil, nil, err
-			}
-
-			if credentials != nil {
-				remoteOptions = append(remoteOptions, remote.WithAuth(credentials))
-			}
-			descriptor, err = remote.Get(ref, remoteOptions...)
-			if err != nil {
-				return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image descriptor"))
-			}
-		}
+		return nil, nil, err
 	}
 
 	if descriptor.MediaType.IsIndex() {
+		_, span := tracing.StartSpan(ctx, "registry remote.Index", attribute.String("image", ref.Name()))
 		digest, err := getIndexDigestHash(ref, remoteOptions...)
+		tracing.EndSpan(span, err)
 		if err != nil {
 			return nil, nil, err
 		}
 		return digest, nil, nil
 	} else if descriptor.MediaType.IsImage() {
+		_, span := tracing.StartSpan(ctx, "registry remote.Image", attribute.String("image", ref.Name()))
 		digest, manifest, err := getImageDigestHash(ref, remoteOptions...)
+		tracing.EndSpan(span, err)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -156,6 +249,18 @@ func (s *notaryService) getRepositoryDigestHash(ref name.Reference, imagePullCre
 	return nil, nil, pkg.NewValidationFailedErr(errors.New("not an image or image list"))
 }
 
+// getImageDescriptor returns the image descriptor together with the remote options
+// which have to be used for further requests to the same registry
+func getImageDescriptor(ctx context.Context, ref name.Reference, imagePullCredentials map[string]cliType.AuthConfig, remoteOptions ...remote.Option) (_ *remote.Descriptor, _ []remote.Option, err error) {
+	_, span := tracing.StartSpan(ctx, "registry remote.Get", attribute.String("image", ref.Name()))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	return withRegistryAuth(ref, imagePullCredentials, remoteOptions, "get image descriptor",
+		func(options ...remote.Option) (*remote.Descriptor, error) {
+			return remote.Get(ref, options...)
+		})
+}
+
 func parseCredentials(credentials cliType.AuthConfig) (authn.Authenticator, error) {
 	if credentials.Username != "" && credentials.Password != "" {
 		basicCredentials := &authn.Basic{Username: credentials.Username, Password: credentials.Password}
@@ -183,7 +288,7 @@ func parseCredentials(credentials cliType.AuthConfig) (authn.Authenticator, erro
 func getIndexDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]byte, error) {
 	i, err := remote.Index(ref, remoteOptions...)
 	if err != nil {
-		return nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image"))
+		return nil, newRegistryErr(err, "get image")
 	}
 	digest, err := i.Digest()
 	if err != nil {
@@ -199,7 +304,7 @@ func getIndexDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]b
 func getImageDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]byte, []byte, error) {
 	i, err := remote.Image(ref, remoteOptions...)
 	if err != nil {
-		return nil, nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get image"))
+		return nil, nil, newRegistryErr(err, "get image")
 	}
 
 	// Deprecated: Remove manifest hash verification after all images has been signed using the new method
@@ -228,32 +333,46 @@ func getImageDigestHash(ref name.Reference, remoteOptions ...remote.Option) ([]b
 }
 
 func (s *notaryService) loggedGetNotaryImageDigestHash(ctx context.Context, ref name.Reference) ([]byte, error) {
+	key := strings.Join([]string{s.NotaryConfig.Url, ref.Name()}, "|")
 	const message = "request to notary"
 	closeLog := helpers.LogStartTime(ctx, message)
 	defer closeLog()
-	result, err := s.getNotaryImageDigestHash(ctx, ref)
-	return result, err
+	return doShared(ctx, &notaryLookups, key, s.Timeout, func(ctx context.Context) ([]byte, error) {
+		return s.getNotaryImageDigestHash(ctx, ref)
+	})
 }
 
 func (s *notaryService) getNotaryImageDigestHash(ctx context.Context, ref name.Reference) ([]byte, error) {
 	const messageNewRepoClient = "request to notary (NewRepoClient)"
 	closeLog := helpers.LogStartTime(ctx, messageNewRepoClient)
-	c, err := s.RepoFactory.NewRepoClient(ref.Context().Name(), s.NotaryConfig)
+	observe := observeDuration(notaryRequestDuration, notaryOperationNewRepoClient)
+	_, span := tracing.StartSpan(ctx, "notary NewRepoClient", attribute.String("repository", ref.Context().Name()))
+	c, err := callWithContext(ctx, func() (NotaryRepoClient, error) {
+		return s.RepoFactory.NewRepoClient(ref.Context().Name(), s.NotaryConfig)
+	})
+	tracing.EndSpan(span, err)
+	observe()
 	closeLog()
 	if err != nil {
-		return nil, pkg.NewUnknownResultErr(err)
+		return nil, withReason(ReasonNotaryUnavailable, pkg.NewUnknownResultErr(err))
 	}
 
 	const messageGetTargetByName = "request to notary (GetTargetByName)"
 	closeLog = helpers.LogStartTime(ctx, messageGetTargetByName)
-	target, err := c.GetTargetByName(ref.Identifier())
+	observe = observeDuration(notaryRequestDuration, notaryOperationGetTargetByName)
+	_, span = tracing.StartSpan(ctx, "notary GetTargetByName", attribute.String("target", ref.Identifier()))
+	target, err := callWithContext(ctx, func() (*client.TargetWithRole, error) {
+		return c.GetTargetByName(ref.Identifier())
+	})
+	tracing.EndSpan(span, err)
+	observe()
 	closeLog()
 	if err != nil {
 		return nil, parseNotaryErr(err)
 	}
 
 	if len(target.Hashes) == 0 {
-		return nil, pkg.NewValidationFailedErr(errors.New("image hash is missing"))
+		return nil, withReason(ReasonNoTrustData, pkg.NewValidationFailedErr(errors.New("image hash is missing")))
 	}
 
 	if len(target.Hashes) > 1 {
@@ -268,13 +387,35 @@ func (s *notaryService) getNotaryImageDigestHash(ctx context.Context, ref name.R
 	return target.Hashes[key], nil
 }
 
+// callWithContext returns the result of the call, or the error when the context is done first. The notary client
+// doesn't accept the context, so the abandoned call is finished in the background, limited by the client timeout
+func callWithContext[T any](ctx context.Context, call func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+	resultChan := make(chan result, 1)
+	go func() {
+		value, err := call()
+		resultChan <- result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-resultChan:
+		return r.value, r.err
+	case <-ctx.Done():
+		var empty T
+		return empty, errors.Wrap(ctx.Err(), "notary request")
+	}
+}
+
 func parseNotaryErr(err error) error {
 	errMsg := err.Error()
 	if strings.Contains(errMsg, "does not have trust data for") {
-		return pkg.NewValidationFailedErr(err)
+		return withReason(ReasonNoTrustData, pkg.NewValidationFailedErr(err))
 	}
 	if strings.Contains(errMsg, "No valid trust data for") {
-		return pkg.NewValidationFailedErr(err)
+		return withReason(ReasonNoTrustData, pkg.NewValidationFailedErr(err))
 	}
-	return pkg.NewUnknownResultErr(err)
+	return withReason(ReasonNotaryUnavailable, pkg.NewUnknownResultErr(err))
 }
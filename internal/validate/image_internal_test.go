@@ -1,11 +1,16 @@
 package validate
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	cliType "github.com/docker/cli/cli/config/types"
 	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/singleflight"
 )
 
 func Test_parseCredentials(t *testing.T) {
@@ -70,3 +75,48 @@ func Test_parseCredentials(t *testing.T) {
 		})
 	}
 }
+
+func Test_doShared(t *testing.T) {
+	t.Run("cancelled caller doesn't cancel the shared call", func(t *testing.T) {
+		//GIVEN
+		group := &singleflight.Group{}
+		started, release := make(chan struct{}), make(chan struct{})
+		sharedCtxErr := make(chan error, 1)
+		callerCtx, cancel := context.WithCancel(context.Background())
+		callerErr := make(chan error, 1)
+
+		//WHEN
+		go func() {
+			_, err := doShared(callerCtx, group, "key", time.Minute, func(ctx context.Context) (string, error) {
+				close(started)
+				<-release
+				sharedCtxErr <- ctx.Err()
+				return "result", nil
+			})
+			callerErr <- err
+		}()
+		<-started
+		cancel()
+
+		//THEN
+		err := <-callerErr
+		require.ErrorContains(t, err, "waiting for upstream request: context canceled")
+		require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+		close(release)
+		require.NoError(t, <-sharedCtxErr)
+	})
+
+	t.Run("shared call is limited by the timeout", func(t *testing.T) {
+		//GIVEN
+		group := &singleflight.Group{}
+
+		//WHEN
+		_, err := doShared(context.Background(), group, "key", 10*time.Millisecond, func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+
+		//THEN
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -1,13 +1,18 @@
 package validate_test
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	"github.com/kyma-project/warden/internal/validate"
 	"github.com/kyma-project/warden/internal/validate/mocks"
 	"github.com/kyma-project/warden/pkg"
@@ -15,7 +20,12 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"github.com/theupdateframework/notary/client"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type image struct {
@@ -359,6 +369,69 @@ func Test_Validate_DEV(t *testing.T) {
 	require.ErrorContains(t, err, "something")
 }
 
+func Test_Validate_ConcurrentValidationsOfSameImage_ShouldShareUpstreamRequests(t *testing.T) {
+	//GIVEN
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	img := pushRandomImage(t, strings.TrimPrefix(server.URL, "http://"), "shared:v1")
+	hash, err := hex.DecodeString(strings.TrimPrefix(img.DigestStr(), "sha256:"))
+	require.NoError(t, err)
+
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", "v1").
+		Return(&client.TargetWithRole{Target: client.Target{Name: "ignored",
+			Hashes: map[string][]byte{"ignored": hash},
+			Length: 1}}, nil).
+		After(200 * time.Millisecond)
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: "shared-notary"}}
+
+	//WHEN
+	const validations = 10
+	errs := make([]error, validations)
+	wg := sync.WaitGroup{}
+	for i := 0; i < validations; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s := validate.NewImageValidator(&cfg, f)
+			errs[i] = s.Validate(context.TODO(), img.Context().Tag("v1").String(), emptyAuthData)
+		}(i)
+	}
+	wg.Wait()
+
+	//THEN
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	f.AssertNumberOfCalls(t, "NewRepoClient", 1)
+	notaryClient.AssertNumberOfCalls(t, "GetTargetByName", 1)
+}
+
+func Test_Validate_WhenContextIsDoneWhileWaitingForNotary_ShouldReturnServiceNotAvailable(t *testing.T) {
+	//GIVEN
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", trustedImage.tag).
+		Return(&client.TargetWithRole{Target: client.Target{Name: "ignored",
+			Hashes: map[string][]byte{"ignored": trustedImage.hash},
+			Length: 1}}, nil).
+		After(time.Second)
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: "slow-notary"}}
+	s := validate.NewImageValidator(&cfg, f)
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	//WHEN
+	err := s.Validate(ctx, trustedImage.image(), emptyAuthData)
+
+	//THEN
+	require.ErrorContains(t, err, "waiting for upstream request")
+	require.Equal(t, pkg.UnknownResult, pkg.ErrorCode(err))
+}
+
 func setupMockFactory() validate.RepoFactory {
 	notaryClient := &mocks.NotaryRepoClient{}
 
@@ -399,3 +472,101 @@ func setupMockFactory() validate.RepoFactory {
 
 	return f
 }
+
+func Test_ValidatePod_ShouldRecordSpansOfUpstreamRequests(t *testing.T) {
+	//GIVEN
+	exporter := tracetest.NewInMemoryExporter()
+	tracing.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	defer tracing.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	img := pushRandomImage(t, strings.TrimPrefix(server.URL, "http://"), "traced:v1")
+	hash, err := hex.DecodeString(strings.TrimPrefix(img.DigestStr(), "sha256:"))
+	require.NoError(t, err)
+
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", "v1").
+		Return(&client.TargetWithRole{Target: client.Target{Name: "ignored",
+			Hashes: map[string][]byte{"ignored": hash},
+			Length: 1}}, nil)
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: "traced-notary"}}
+	podValidator := validate.NewPodValidator(validate.NewImageValidator(&cfg, f))
+	image := img.Context().Tag("v1").String()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "traced"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "traced", Image: image}}}}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "traced"}}
+
+	//WHEN
+	result, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, validate.Valid, result.Status)
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	spansByID := map[trace.SpanID]sdktrace.ReadOnlySpan{}
+	for _, span := range exporter.GetSpans().Snapshots() {
+		spans[span.Name()] = span
+		spansByID[span.SpanContext().SpanID()] = span
+	}
+	require.Contains(t, spans, "validate image")
+	validateSpan := spans["validate image"]
+	// upstream requests are shared by concurrent validations, so they are recorded in their own traces
+	// linked to the validation which started them
+	for _, name := range []string{"notary NewRepoClient", "notary GetTargetByName", "registry remote.Get", "registry remote.Image"} {
+		require.Contains(t, spans, name)
+		sharedSpan, ok := spansByID[spans[name].Parent().SpanID()]
+		require.True(t, ok, name)
+		require.Equal(t, "shared lookup", sharedSpan.Name(), name)
+		require.False(t, sharedSpan.Parent().IsValid(), name)
+		require.Len(t, sharedSpan.Links(), 1, name)
+		require.Equal(t, validateSpan.SpanContext().SpanID(), sharedSpan.Links()[0].SpanContext.SpanID(), name)
+	}
+}
+
+func Test_Validate_ImagePinnedToDigest_ShouldUseTagForNotary(t *testing.T) {
+	//GIVEN
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	img := pushRandomImage(t, strings.TrimPrefix(server.URL, "http://"), "pinned:v1")
+
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", "v1").Return(notaryTarget(t, img.DigestStr()), nil)
+	notaryClient.On("GetTargetByName", img.DigestStr()).
+		Return(nil, fmt.Errorf("does not have trust data for %s", img.DigestStr()))
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	cfg := validate.ServiceConfig{NotaryConfig: validate.NotaryConfig{Url: "pinned-notary"}}
+	s := validate.NewImageValidator(&cfg, f)
+
+	tests := []struct {
+		name        string
+		image       string
+		expectedErr string
+	}{
+		{
+			name:  "tag and digest",
+			image: img.Context().Tag("v1").String() + "@" + img.DigestStr(),
+		},
+		{
+			name:        "digest without tag",
+			image:       img.String(),
+			expectedErr: "does not have trust data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			err := s.Validate(context.TODO(), tt.image, emptyAuthData)
+
+			//THEN
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.expectedErr)
+		})
+	}
+}
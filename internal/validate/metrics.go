@@ -0,0 +1,99 @@
+package validate
+
+import (
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	notaryOperationNewRepoClient    = "new_repo_client"
+	notaryOperationGetTargetByName  = "get_target_by_name"
+	registryOperationGetDescriptor  = "get_descriptor"
+	registryOperationHeadDescriptor = "head_descriptor"
+	unknownRegistry                 = "unknown"
+)
+
+var (
+	verificationCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "warden_verification_cache_requests_total",
+			Help: "Number of image verification cache lookups partitioned by result (hit, miss).",
+		},
+		[]string{"result"},
+	)
+	verificationCacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "warden_verification_cache_entries",
+			Help: "Number of image verification results kept in the cache.",
+		},
+	)
+	notaryRepositoryPoolEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "warden_notary_repository_pool_entries",
+			Help: "Number of notary repository clients kept in the pool.",
+		},
+	)
+	imageValidations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "warden_image_validations_total",
+			Help: "Number of image validation results partitioned by status (Valid, Invalid, ServiceUnavailable), namespace and registry.",
+		},
+		[]string{"status", "namespace", "registry"},
+	)
+	imageValidationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "warden_image_validation_duration_seconds",
+			Help:    "Duration of image validations partitioned by status (Valid, Invalid, ServiceUnavailable) and registry.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"status", "registry"},
+	)
+	notaryRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "warden_notary_request_duration_seconds",
+			Help:    "Duration of Notary requests partitioned by operation (new_repo_client, get_target_by_name).",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation"},
+	)
+	registryRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "warden_registry_request_duration_seconds",
+			Help:    "Duration of image registry descriptor requests partitioned by operation (get_descriptor, head_descriptor).",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		verificationCacheRequests,
+		verificationCacheEntries,
+		notaryRepositoryPoolEntries,
+		imageValidations,
+		imageValidationDuration,
+		notaryRequestDuration,
+		registryRequestDuration,
+	)
+}
+
+// observeDuration returns function which records the time elapsed since its creation in the histogram
+func observeDuration(histogram *prometheus.HistogramVec, labels ...string) func() {
+	startTime := time.Now()
+	return func() {
+		histogram.WithLabelValues(labels...).Observe(time.Since(startTime).Seconds())
+	}
+}
+
+// imageRegistry returns registry of the image used as the metric label
+func imageRegistry(image string) string {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return unknownRegistry
+	}
+	return ref.Context().RegistryStr()
+}
@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"context"
+	"testing"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type imageValidatorFunc func(image string) error
+
+func (f imageValidatorFunc) Validate(_ context.Context, image string, _ map[string]cliType.AuthConfig) error {
+	return f(image)
+}
+
+func TestValidatePod_RecordsMetrics(t *testing.T) {
+	//GIVEN
+	const namespace = "metrics-test"
+	validImage := "registry.example.com/valid:1.0"
+	invalidImage := "ghcr.io/org/invalid:1.0"
+	pendingImage := "pending"
+	validator := NewPodValidator(imageValidatorFunc(func(image string) error {
+		switch image {
+		case invalidImage:
+			return pkg.NewValidationFailedErr(errors.New("invalid signature"))
+		case pendingImage:
+			return pkg.NewUnknownResultErr(errors.New("notary unavailable"))
+		}
+		return nil
+	}))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "valid", Image: validImage},
+			{Name: "invalid", Image: invalidImage},
+			{Name: "pending", Image: pendingImage},
+		}},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+
+	//WHEN
+	_, err := validator.ValidatePod(context.TODO(), pod, ns, map[string]cliType.AuthConfig{})
+
+	//THEN
+	require.NoError(t, err)
+	require.Equal(t, float64(1), testutil.ToFloat64(imageValidations.WithLabelValues(string(Valid), namespace, "registry.example.com")))
+	require.Equal(t, float64(1), testutil.ToFloat64(imageValidations.WithLabelValues(string(Invalid), namespace, "ghcr.io")))
+	require.Equal(t, float64(1), testutil.ToFloat64(imageValidations.WithLabelValues(string(ServiceUnavailable), namespace, "index.docker.io")))
+}
+
+func Test_imageRegistry(t *testing.T) {
+	tests := []struct {
+		name  string
+		image string
+		want  string
+	}{
+		{name: "registry with port", image: "localhost:5000/app:1.0", want: "localhost:5000"},
+		{name: "default registry", image: "nginx", want: "index.docker.io"},
+		{name: "invalid reference", image: "Invalid::image", want: unknownRegistry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, imageRegistry(tt.image))
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package validate
+
+import (
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DigestSourceImage fetches the image digest from the registry referenced by the image
+	DigestSourceImage = ""
+	// DigestSourceMirror fetches the image digest from the mirror, also for images referencing the upstream
+	DigestSourceMirror = "mirror"
+	// DigestSourceUpstream fetches the image digest from the upstream, also for images referencing the mirror
+	DigestSourceUpstream = "upstream"
+)
+
+// RegistryMirror maps repositories of the registry mirror to the repositories of the upstream registry
+type RegistryMirror struct {
+	// Mirror is the registry host with optional repository path, e.g. `mirror.corp.io/dockerhub`
+	Mirror string
+	// Upstream is the registry host with optional repository path, e.g. `docker.io`
+	Upstream string
+	// DigestSource defines where the image digest is fetched from, see DigestSource* constants
+	DigestSource string
+}
+
+// RegistryMirrors maps images to the repositories used for the trust data and the digest lookup.
+// Trust data is always looked up for the upstream repository, because it's keyed by its name,
+// and the digest is fetched from the registry selected by the mirror digest source
+type RegistryMirrors []RegistryMirror
+
+// key returns identifier of the mirrors, used to separate cached verification results
+func (m RegistryMirrors) key() string {
+	values := make([]string, 0, len(m))
+	for _, mirror := range m {
+		values = append(values, mirror.Mirror+"->"+mirror.Upstream+"@"+mirror.DigestSource)
+	}
+	return strings.Join(values, ",")
+}
+
+// ValidateRegistryMirrors returns error when any of the mirrors can't be used
+func ValidateRegistryMirrors(mirrors RegistryMirrors) error {
+	for _, mirror := range mirrors {
+		for _, repository := range []string{mirror.Mirror, mirror.Upstream} {
+			if _, err := name.NewRegistry(strings.SplitN(repository, "/", 2)[0]); err != nil || repository == "" {
+				return errors.Errorf("invalid registry mirror %s -> %s", mirror.Mirror, mirror.Upstream)
+			}
+		}
+		switch mirror.DigestSource {
+		case DigestSourceImage, DigestSourceMirror, DigestSourceUpstream:
+		default:
+			return errors.Errorf("unsupported digest source of registry mirror %s: %s", mirror.Mirror, mirror.DigestSource)
+		}
+	}
+	return nil
+}
+
+// TrustReference returns the reference of the image in the upstream repository,
+// or the reference itself when the image isn't in any mirror
+func (m RegistryMirrors) TrustReference(ref name.Reference) name.Reference {
+	for _, mirror := range m {
+		if upstream, ok := mapReference(ref, mirror.Mirror, mirror.Upstream); ok {
+			return upstream
+		}
+	}
+	return ref
+}
+
+// DigestReference returns the reference of the image in the registry the digest has to be fetched from
+func (m RegistryMirrors) DigestReference(ref name.Reference) name.Reference {
+	for _, mirror := range m {
+		if mirror.DigestSource == DigestSourceUpstream {
+			if upstream, ok := mapReference(ref, mirror.Mirror, mirror.Upstream); ok {
+				return upstream
+			}
+		}
+		if mirror.DigestSource == DigestSourceMirror {
+			if mirrored, ok := mapReference(ref, mirror.Upstream, mirror.Mirror); ok {
+				return mirrored
+			}
+		}
+	}
+	return ref
+}
+
+// mapReference replaces the `from` repository prefix of the reference with the `to` one
+func mapReference(ref name.Reference, from, to string) (name.Reference, bool) {
+	repository := ref.Context().Name()
+	prefix := normalizeRepositoryPrefix(from)
+	if repository != prefix && !strings.HasPrefix(repository, prefix+"/") {
+		return nil, false
+	}
+	mapped := strings.TrimSuffix(to, "/") + strings.TrimPrefix(repository, prefix)
+	mappedRepository, err := name.NewRepository(mapped)
+	if err != nil {
+		return nil, false
+	}
+	if digest, ok := ref.(name.Digest); ok {
+		return mappedRepository.Digest(digest.DigestStr()), true
+	}
+	return mappedRepository.Tag(ref.Identifier()), true
+}
+
+// normalizeRepositoryPrefix returns the prefix in the form used by the repository names,
+// e.g. `index.docker.io` instead of `docker.io`
+func normalizeRepositoryPrefix(prefix string) string {
+	prefix = strings.TrimSuffix(prefix, "/")
+	host, path, _ := strings.Cut(prefix, "/")
+	registry, err := name.NewRegistry(host)
+	if err != nil {
+		return prefix
+	}
+	if path == "" {
+		return registry.Name()
+	}
+	return registry.Name() + "/" + path
+}
@@ -0,0 +1,159 @@
+package validate_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryMirrors(t *testing.T) {
+	mirrors := validate.RegistryMirrors{
+		{Mirror: "mirror.io/dockerhub", Upstream: "docker.io", DigestSource: validate.DigestSourceMirror},
+		{Mirror: "cache.io", Upstream: "upstream.io/team", DigestSource: validate.DigestSourceUpstream},
+		{Mirror: "local.io/ghcr", Upstream: "ghcr.io"},
+	}
+
+	tests := []struct {
+		name           string
+		image          string
+		expectedTrust  string
+		expectedDigest string
+	}{
+		{
+			name:           "mirrored docker hub image",
+			image:          "mirror.io/dockerhub/library/nginx:1.25",
+			expectedTrust:  "index.docker.io/library/nginx:1.25",
+			expectedDigest: "mirror.io/dockerhub/library/nginx:1.25",
+		},
+		{
+			name:           "mirrored docker hub official image without library",
+			image:          "mirror.io/dockerhub/nginx:1.25",
+			expectedTrust:  "index.docker.io/library/nginx:1.25",
+			expectedDigest: "mirror.io/dockerhub/nginx:1.25",
+		},
+		{
+			name:           "upstream image with digest from mirror",
+			image:          "nginx:1.25",
+			expectedTrust:  "index.docker.io/library/nginx:1.25",
+			expectedDigest: "mirror.io/dockerhub/library/nginx:1.25",
+		},
+		{
+			name:           "mirrored image with digest from upstream",
+			image:          "cache.io/app@sha256:" + strings.Repeat("a", 64),
+			expectedTrust:  "upstream.io/team/app@sha256:" + strings.Repeat("a", 64),
+			expectedDigest: "upstream.io/team/app@sha256:" + strings.Repeat("a", 64),
+		},
+		{
+			name:           "mirrored image with digest from image registry",
+			image:          "local.io/ghcr/org/app:v1",
+			expectedTrust:  "ghcr.io/org/app:v1",
+			expectedDigest: "local.io/ghcr/org/app:v1",
+		},
+		{
+			name:           "repository only sharing the mirror prefix",
+			image:          "mirror.io/dockerhub-old/nginx:1.25",
+			expectedTrust:  "mirror.io/dockerhub-old/nginx:1.25",
+			expectedDigest: "mirror.io/dockerhub-old/nginx:1.25",
+		},
+		{
+			name:           "image which isn't mirrored",
+			image:          "other.io/app:v1",
+			expectedTrust:  "other.io/app:v1",
+			expectedDigest: "other.io/app:v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			ref, err := name.ParseReference(tt.image)
+			require.NoError(t, err)
+
+			//WHEN
+			trustRef := mirrors.TrustReference(ref)
+			digestRef := mirrors.DigestReference(ref)
+
+			//THEN
+			require.Equal(t, tt.expectedTrust, trustRef.Name())
+			require.Equal(t, tt.expectedDigest, digestRef.Name())
+		})
+	}
+}
+
+func TestValidateRegistryMirrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		mirrors     validate.RegistryMirrors
+		expectedErr string
+	}{
+		{
+			name: "valid mirrors",
+			mirrors: validate.RegistryMirrors{
+				{Mirror: "mirror.io:5000/dockerhub", Upstream: "docker.io", DigestSource: validate.DigestSourceMirror},
+				{Mirror: "cache.io", Upstream: "ghcr.io"},
+			},
+		},
+		{
+			name:        "missing upstream",
+			mirrors:     validate.RegistryMirrors{{Mirror: "mirror.io"}},
+			expectedErr: "invalid registry mirror mirror.io -> ",
+		},
+		{
+			name:        "invalid mirror host",
+			mirrors:     validate.RegistryMirrors{{Mirror: "mirror io", Upstream: "docker.io"}},
+			expectedErr: "invalid registry mirror mirror io -> docker.io",
+		},
+		{
+			name:        "unsupported digest source",
+			mirrors:     validate.RegistryMirrors{{Mirror: "mirror.io", Upstream: "docker.io", DigestSource: "notary"}},
+			expectedErr: "unsupported digest source of registry mirror mirror.io: notary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			err := validate.ValidateRegistryMirrors(tt.mirrors)
+
+			//THEN
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func Test_Validate_MirroredImage_ShouldUseUpstreamTrustData(t *testing.T) {
+	//GIVEN
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	mirrorHost := strings.TrimPrefix(server.URL, "http://")
+	img := pushRandomImage(t, mirrorHost, "upstream/app:v1")
+
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", "v1").Return(notaryTarget(t, img.DigestStr()), nil)
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", "upstream.example.com/team/app", mock.Anything).Return(notaryClient, nil)
+	cfg := validate.ServiceConfig{
+		NotaryConfig: validate.NotaryConfig{Url: "mirror-notary"},
+		RegistryMirrors: validate.RegistryMirrors{
+			{Mirror: mirrorHost + "/upstream", Upstream: "upstream.example.com/team"},
+		},
+	}
+	s := validate.NewImageValidator(&cfg, f)
+
+	//WHEN
+	err := s.Validate(context.TODO(), mirrorHost+"/upstream/app:v1", emptyAuthData)
+
+	//THEN
+	require.NoError(t, err)
+	f.AssertExpectations(t)
+}
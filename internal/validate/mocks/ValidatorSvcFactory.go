@@ -15,6 +15,96 @@ type ValidatorSvcFactory struct {
 	mock.Mock
 }
 
+// NewCosignValidatorSvc provides a mock function with given fields: publicKeys, allowedRegistries, timeout
+func (_m *ValidatorSvcFactory) NewCosignValidatorSvc(publicKeys string, allowedRegistries string, timeout time.Duration) (validate.PodValidator, error) {
+	ret := _m.Called(publicKeys, allowedRegistries, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewCosignValidatorSvc")
+	}
+
+	var r0 validate.PodValidator
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) (validate.PodValidator, error)); ok {
+		return rf(publicKeys, allowedRegistries, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) validate.PodValidator); ok {
+		r0 = rf(publicKeys, allowedRegistries, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(validate.PodValidator)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string, time.Duration) error); ok {
+		r1 = rf(publicKeys, allowedRegistries, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewNotationValidatorSvc provides a mock function with given fields: allowedRegistries, timeout
+func (_m *ValidatorSvcFactory) NewNotationValidatorSvc(allowedRegistries string, timeout time.Duration) (validate.PodValidator, error) {
+	ret := _m.Called(allowedRegistries, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewNotationValidatorSvc")
+	}
+
+	var r0 validate.PodValidator
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, time.Duration) (validate.PodValidator, error)); ok {
+		return rf(allowedRegistries, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(string, time.Duration) validate.PodValidator); ok {
+		r0 = rf(allowedRegistries, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(validate.PodValidator)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string, time.Duration) error); ok {
+		r1 = rf(allowedRegistries, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewNotationValidatorSvcWithConfig provides a mock function with given fields: notationConfig, allowedRegistries, timeout
+func (_m *ValidatorSvcFactory) NewNotationValidatorSvcWithConfig(notationConfig *validate.NotationConfig, allowedRegistries string, timeout time.Duration) (validate.PodValidator, error) {
+	ret := _m.Called(notationConfig, allowedRegistries, timeout)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NewNotationValidatorSvcWithConfig")
+	}
+
+	var r0 validate.PodValidator
+	var r1 error
+	if rf, ok := ret.Get(0).(func(*validate.NotationConfig, string, time.Duration) (validate.PodValidator, error)); ok {
+		return rf(notationConfig, allowedRegistries, timeout)
+	}
+	if rf, ok := ret.Get(0).(func(*validate.NotationConfig, string, time.Duration) validate.PodValidator); ok {
+		r0 = rf(notationConfig, allowedRegistries, timeout)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(validate.PodValidator)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(*validate.NotationConfig, string, time.Duration) error); ok {
+		r1 = rf(notationConfig, allowedRegistries, timeout)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewValidatorSvc provides a mock function with given fields: notaryURL, notaryAllowedRegistries, notaryTimeout
 func (_m *ValidatorSvcFactory) NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) validate.PodValidator {
 	ret := _m.Called(notaryURL, notaryAllowedRegistries, notaryTimeout)
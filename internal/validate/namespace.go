@@ -1,6 +1,7 @@
 package validate
 
 import (
+	"github.com/kyma-project/warden/internal/helpers"
 	"github.com/kyma-project/warden/pkg"
 	corev1 "k8s.io/api/core/v1"
 )
@@ -31,3 +32,27 @@ func IsChangedSupportedValidationLabelValue(oldValue, newValue string) bool {
 	}
 	return oldValue != newValue
 }
+
+// StrictModeForNS returns the strict mode applied to the validation result in the namespace: the system strict mode
+// in namespaces with the system validation, the namespace annotation in namespaces with the user validation,
+// and both are overridden by the strict mode of image policies
+func StrictModeForNS(ns *corev1.Namespace, systemStrictMode bool, result ValidationResult) (bool, error) {
+	strictMode := systemStrictMode
+	if IsUserValidationForNS(ns) {
+		var err error
+		strictMode, err = helpers.GetUserValidationStrictMode(ns)
+		if err != nil {
+			return false, err
+		}
+	}
+	if result.StrictMode != nil {
+		strictMode = *result.StrictMode
+	}
+	return strictMode, nil
+}
+
+// IsRejected returns true when the pod with the validation status is rejected by the admission
+// in the enforce mode, pods whose images couldn't be validated are rejected only in the strict mode
+func IsRejected(status ValidationStatus, strictMode bool) bool {
+	return status == Invalid || (status == ServiceUnavailable && strictMode)
+}
@@ -56,15 +56,30 @@ type NotaryRepoFactory struct {
 }
 
 func (f NotaryRepoFactory) NewRepoClient(img string, c NotaryConfig) (NotaryRepoClient, error) {
-	base := &http.Transport{
+	base := newNotaryTransport(f.Timeout)
+	// connections are not reused by the repository clients created for a single lookup
+	base.DisableKeepAlives = true
+
+	cm, err := pingNotary(base, c.Url, f.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewFileCachedRepository(NotaryDefaultTrustDir, data.GUN(img), c.Url, newNotaryAuthTransport(base, cm, img), nil, trustpinning.TrustPinConfig{})
+}
+
+func newNotaryTransport(timeout time.Duration) *http.Transport {
+	return &http.Transport{
 		Proxy:               http.ProxyFromEnvironment,
 		TLSHandshakeTimeout: 10 * time.Second,
 		DialContext: (&net.Dialer{
-			Timeout:   f.Timeout,
-			KeepAlive: f.Timeout,
+			Timeout:   timeout,
+			KeepAlive: timeout,
 		}).DialContext,
-		DisableKeepAlives: true,
 	}
+}
+
+// newNotaryAuthTransport returns transport authorizing requests with the token pulling given repository
+func newNotaryAuthTransport(base http.RoundTripper, cm challenge.Manager, img string) http.RoundTripper {
 	th := auth.NewTokenHandlerWithOptions(auth.TokenHandlerOptions{
 		Transport: base,
 		Scopes: []auth.Scope{
@@ -74,13 +89,17 @@ func (f NotaryRepoFactory) NewRepoClient(img string, c NotaryConfig) (NotaryRepo
 			},
 		},
 	})
+	return transport.NewTransport(base, auth.NewAuthorizer(cm, th))
+}
 
+// pingNotary returns challenges of the notary server used to authorize requests
+func pingNotary(base http.RoundTripper, url string, timeout time.Duration) (challenge.Manager, error) {
 	// challenge manager expects to connect to /v2/ endpoint to obtain the challenges:
 	// https://github.com/notaryproject/notary/blob/master/vendor/github.com/docker/distribution/registry/client/auth/session.go#L75
-	u := c.Url + "/v2/"
+	u := url + "/v2/"
 	pingClient := &http.Client{
 		Transport: base,
-		Timeout:   f.Timeout,
+		Timeout:   timeout,
 	}
 	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
@@ -104,6 +123,5 @@ func (f NotaryRepoFactory) NewRepoClient(img string, c NotaryConfig) (NotaryRepo
 	if err = cm.AddResponse(resp); err != nil {
 		return nil, err
 	}
-	modifier := auth.NewAuthorizer(cm, th)
-	return client.NewFileCachedRepository(NotaryDefaultTrustDir, data.GUN(img), c.Url, transport.NewTransport(base, modifier), nil, trustpinning.TrustPinConfig{})
+	return cm, nil
 }
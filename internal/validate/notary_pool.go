@@ -0,0 +1,377 @@
+package validate
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/registry/client/auth/challenge"
+	"github.com/theupdateframework/notary/client"
+	"github.com/theupdateframework/notary/cryptoservice"
+	store "github.com/theupdateframework/notary/storage"
+	"github.com/theupdateframework/notary/trustpinning"
+	"github.com/theupdateframework/notary/tuf/data"
+)
+
+// notaryChallengeTTL defines how long challenges of the notary server are kept before the server is pinged again
+const notaryChallengeTTL = 10 * time.Minute
+
+// trustDirPattern matches directories with TUF metadata of pooled repositories
+var trustDirPattern = regexp.MustCompile(`^[0-9a-f]{64}-[0-9]+$`)
+
+type NotaryRepoPoolConfig struct {
+	// MaxSize is the maximum number of kept repositories, the least recently used ones are evicted first.
+	// Repositories aren't kept when it's zero
+	MaxSize int
+	// TrustDir is the directory where TUF metadata is kept, the metadata is kept in memory when it's empty
+	TrustDir string
+	// RefreshInterval defines how often TUF metadata of a repository is refreshed from the notary server,
+	// the metadata is refreshed on each lookup when it's zero
+	RefreshInterval time.Duration
+}
+
+// NotaryRepoPool keeps notary repository clients with their TUF metadata, keyed by notary URL and GUN.
+// Connections and challenges of the notary server are shared by all repositories of the server
+// and are kept as long as any repository of the server is kept.
+// It's safe for concurrent use and is meant to be shared by all validators of the process.
+type NotaryRepoPool struct {
+	config    NotaryRepoPoolConfig
+	now       func() time.Time
+	mu        sync.Mutex
+	endpoints map[string]*notaryEndpoint
+	entries   map[string]*list.Element
+	lru       *list.List
+	// dirs is the sequence number of the last created metadata directory,
+	// each repository gets its own directory so an evicted repository never shares it with a newer one
+	dirs int
+	// newRepository creates the repository client, it's replaced in tests
+	newRepository func(gun data.GUN, url string, rt http.RoundTripper, cache store.MetadataStore) (client.Repository, error)
+}
+
+// notaryEndpoint keeps the transport and challenges of one notary server
+type notaryEndpoint struct {
+	key       string
+	url       string
+	timeout   time.Duration
+	transport *http.Transport
+	now       func() time.Time
+	// repositories is the number of pooled repositories of the server, it's guarded by the pool mutex
+	repositories int
+	mu           sync.Mutex
+	challenge    challenge.Manager
+	pingedAt     time.Time
+}
+
+type pooledRepoEntry struct {
+	key         string
+	endpointKey string
+	repo        *pooledRepository
+}
+
+// NewNotaryRepoPool returns the pool, metadata directories left in the TrustDir by previous runs are removed
+func NewNotaryRepoPool(cfg NotaryRepoPoolConfig) *NotaryRepoPool {
+	removeTrustDirs(cfg.TrustDir)
+	return &NotaryRepoPool{
+		config:        cfg,
+		now:           time.Now,
+		endpoints:     map[string]*notaryEndpoint{},
+		entries:       map[string]*list.Element{},
+		lru:           list.New(),
+		newRepository: newReadOnlyRepository,
+	}
+}
+
+func removeTrustDirs(trustDir string) {
+	if trustDir == "" {
+		return
+	}
+	dirs, err := os.ReadDir(trustDir)
+	if err != nil {
+		return
+	}
+	for _, dir := range dirs {
+		if dir.IsDir() && trustDirPattern.MatchString(dir.Name()) {
+			_ = os.RemoveAll(filepath.Join(trustDir, dir.Name()))
+		}
+	}
+}
+
+// Factory returns RepoFactory which takes the repositories from the pool,
+// the timeout is used for connections to the notary server
+func (p *NotaryRepoPool) Factory(timeout time.Duration) RepoFactory {
+	return pooledRepoFactory{pool: p, timeout: timeout}
+}
+
+// Len returns number of kept repositories
+func (p *NotaryRepoPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+type pooledRepoFactory struct {
+	pool    *NotaryRepoPool
+	timeout time.Duration
+}
+
+func (f pooledRepoFactory) NewRepoClient(img string, c NotaryConfig) (NotaryRepoClient, error) {
+	return f.pool.get(img, c.Url, f.timeout)
+}
+
+func (p *NotaryRepoPool) get(img, url string, timeout time.Duration) (NotaryRepoClient, error) {
+	key := fmt.Sprintf("%s|%s|%s", url, timeout, img)
+	p.mu.Lock()
+	if element, ok := p.entries[key]; ok {
+		p.lru.MoveToFront(element)
+		p.mu.Unlock()
+		return element.Value.(*pooledRepoEntry).repo, nil
+	}
+	endpoint := p.endpoint(url, timeout)
+	pooled := p.config.MaxSize > 0
+	var dir string
+	// repositories which aren't pooled are never removed, so their metadata is kept only in memory
+	if p.config.TrustDir != "" && pooled {
+		p.dirs++
+		hash := sha256.Sum256([]byte(key))
+		dir = filepath.Join(p.config.TrustDir, hex.EncodeToString(hash[:])+"-"+strconv.Itoa(p.dirs))
+	}
+	p.mu.Unlock()
+
+	repo, err := p.newPooledRepository(img, url, dir, endpoint)
+	if err != nil {
+		if dir != "" {
+			_ = os.RemoveAll(dir)
+		}
+		p.mu.Lock()
+		p.dropUnusedEndpoint(endpoint.key)
+		p.mu.Unlock()
+		return nil, err
+	}
+	entry := &pooledRepoEntry{key: key, endpointKey: endpoint.key, repo: repo}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// concurrent lookup of the same repository could have added it in the meantime
+	if element, ok := p.entries[key]; ok {
+		entry.repo.evict()
+		p.dropUnusedEndpoint(endpoint.key)
+		p.lru.MoveToFront(element)
+		return element.Value.(*pooledRepoEntry).repo, nil
+	}
+	if !pooled {
+		p.dropUnusedEndpoint(endpoint.key)
+		return entry.repo, nil
+	}
+	// the endpoint could have been released by eviction of its last repository in the meantime
+	if _, ok := p.endpoints[endpoint.key]; !ok {
+		p.endpoints[endpoint.key] = endpoint
+	}
+	p.endpoints[endpoint.key].repositories++
+	p.entries[key] = p.lru.PushFront(entry)
+	for p.lru.Len() > p.config.MaxSize {
+		p.remove(p.lru.Back())
+	}
+	notaryRepositoryPoolEntries.Set(float64(p.lru.Len()))
+	return entry.repo, nil
+}
+
+func (p *NotaryRepoPool) newPooledRepository(img, url, dir string, endpoint *notaryEndpoint) (*pooledRepository, error) {
+	if _, err := endpoint.ping(); err != nil {
+		return nil, err
+	}
+	var cache store.MetadataStore = store.NewMemoryStore(nil)
+	if dir != "" {
+		var err error
+		if cache, err = store.NewFileStore(filepath.Join(dir, "metadata"), "json"); err != nil {
+			return nil, err
+		}
+	}
+	repo, err := p.newRepository(data.GUN(img), url, endpoint.authTransport(img), cache)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledRepository{Repository: repo, refreshInterval: p.config.RefreshInterval, now: p.now, dir: dir}, nil
+}
+
+func (p *NotaryRepoPool) endpoint(url string, timeout time.Duration) *notaryEndpoint {
+	key := fmt.Sprintf("%s|%s", url, timeout)
+	endpoint, ok := p.endpoints[key]
+	if !ok {
+		endpoint = &notaryEndpoint{key: key, url: url, timeout: timeout, transport: newNotaryTransport(timeout), now: p.now}
+		p.endpoints[key] = endpoint
+	}
+	return endpoint
+}
+
+// dropUnusedEndpoint removes the endpoint when none of the pooled repositories uses it
+func (p *NotaryRepoPool) dropUnusedEndpoint(key string) {
+	if endpoint, ok := p.endpoints[key]; ok && endpoint.repositories == 0 {
+		endpoint.transport.CloseIdleConnections()
+		delete(p.endpoints, key)
+	}
+}
+
+func (p *NotaryRepoPool) remove(element *list.Element) {
+	entry := element.Value.(*pooledRepoEntry)
+	p.lru.Remove(element)
+	delete(p.entries, entry.key)
+	if endpoint, ok := p.endpoints[entry.endpointKey]; ok {
+		endpoint.repositories--
+		p.dropUnusedEndpoint(entry.endpointKey)
+	}
+	entry.repo.evict()
+	notaryRepositoryPoolEntries.Set(float64(p.lru.Len()))
+}
+
+// ping returns challenges of the notary server, they are kept for notaryChallengeTTL after the successful ping
+// or until the server rejects a request authorized with them
+func (e *notaryEndpoint) ping() (challenge.Manager, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.challenge != nil && e.now().Sub(e.pingedAt) < notaryChallengeTTL {
+		return e.challenge, nil
+	}
+	cm, err := pingNotary(e.transport, e.url, e.timeout)
+	if err != nil {
+		return nil, err
+	}
+	e.challenge = cm
+	e.pingedAt = e.now()
+	return cm, nil
+}
+
+// GetChallenges returns current challenges of the notary server, so the pooled repositories don't keep stale ones
+func (e *notaryEndpoint) GetChallenges(endpoint url.URL) ([]challenge.Challenge, error) {
+	cm, err := e.ping()
+	if err != nil {
+		return nil, err
+	}
+	return cm.GetChallenges(endpoint)
+}
+
+func (e *notaryEndpoint) AddResponse(resp *http.Response) error {
+	cm, err := e.ping()
+	if err != nil {
+		return err
+	}
+	return cm.AddResponse(resp)
+}
+
+// resetChallenge drops the kept challenges, the server is pinged again by the next request
+func (e *notaryEndpoint) resetChallenge() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.challenge = nil
+}
+
+// authTransport returns transport authorizing requests of the repository with the current challenges of the server
+func (e *notaryEndpoint) authTransport(img string) http.RoundTripper {
+	return &challengeResetTransport{base: newNotaryAuthTransport(e.transport, e, img), endpoint: e}
+}
+
+// challengeResetTransport drops challenges of the notary server when it rejects the authorized request
+type challengeResetTransport struct {
+	base     http.RoundTripper
+	endpoint *notaryEndpoint
+}
+
+func (t *challengeResetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusUnauthorized {
+		t.endpoint.resetChallenge()
+	}
+	return resp, err
+}
+
+func newReadOnlyRepository(gun data.GUN, url string, rt http.RoundTripper, cache store.MetadataStore) (client.Repository, error) {
+	remoteStore, err := store.NewNotaryServerStore(url, gun, rt)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewRepository(gun, url, remoteStore, cache, trustpinning.TrustPinConfig{}, cryptoservice.NewCryptoService(), nil)
+}
+
+// pooledRepository serializes lookups of the notary repository client, which isn't safe for concurrent use,
+// and keeps the found targets until the TUF metadata is refreshed
+type pooledRepository struct {
+	client.Repository
+	refreshInterval time.Duration
+	now             func() time.Time
+	mu              sync.Mutex
+	refreshedAt     time.Time
+	targets         map[string]*client.TargetWithRole
+	// dir with TUF metadata is removed once the repository is evicted and none of its lookups is in progress
+	dir     string
+	usersMu sync.Mutex
+	users   int
+	evicted bool
+}
+
+func (r *pooledRepository) acquire() {
+	r.usersMu.Lock()
+	defer r.usersMu.Unlock()
+	r.users++
+}
+
+func (r *pooledRepository) release() {
+	r.usersMu.Lock()
+	defer r.usersMu.Unlock()
+	r.users--
+	r.removeDir()
+}
+
+// evict marks the repository as removed from the pool, callers which already took it can still use it
+func (r *pooledRepository) evict() {
+	r.usersMu.Lock()
+	defer r.usersMu.Unlock()
+	r.evicted = true
+	r.removeDir()
+}
+
+func (r *pooledRepository) removeDir() {
+	if r.evicted && r.users == 0 && r.dir != "" {
+		_ = os.RemoveAll(r.dir)
+	}
+}
+
+func (r *pooledRepository) GetTargetByName(name string, roles ...data.RoleName) (*client.TargetWithRole, error) {
+	r.acquire()
+	defer r.release()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := targetKey(name, roles)
+	if r.now().Sub(r.refreshedAt) >= r.refreshInterval {
+		r.targets = map[string]*client.TargetWithRole{}
+	} else if target, ok := r.targets[key]; ok {
+		return target, nil
+	}
+
+	target, err := r.Repository.GetTargetByName(name, roles...)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.targets) == 0 {
+		r.refreshedAt = r.now()
+	}
+	r.targets[key] = target
+	return target, nil
+}
+
+func targetKey(name string, roles []data.RoleName) string {
+	key := []string{name}
+	for _, role := range roles {
+		key = append(key, role.String())
+	}
+	return strings.Join(key, "|")
+}
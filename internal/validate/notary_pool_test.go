@@ -0,0 +1,278 @@
+package validate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/theupdateframework/notary/client"
+	store "github.com/theupdateframework/notary/storage"
+	"github.com/theupdateframework/notary/tuf/data"
+)
+
+type fakeRepository struct {
+	client.Repository
+	lookups int
+}
+
+func (r *fakeRepository) GetTargetByName(name string, _ ...data.RoleName) (*client.TargetWithRole, error) {
+	r.lookups++
+	return &client.TargetWithRole{Target: client.Target{Name: name}}, nil
+}
+
+// blockingRepository finishes lookups when they are released
+type blockingRepository struct {
+	client.Repository
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *blockingRepository) GetTargetByName(name string, _ ...data.RoleName) (*client.TargetWithRole, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return &client.TargetWithRole{Target: client.Target{Name: name}}, nil
+}
+
+func newTestNotaryRepoPool(t *testing.T, cfg NotaryRepoPoolConfig) (*NotaryRepoPool, string, *atomic.Int32) {
+	pings := &atomic.Int32{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	pool := NewNotaryRepoPool(cfg)
+	pool.newRepository = func(_ data.GUN, _ string, _ http.RoundTripper, _ store.MetadataStore) (client.Repository, error) {
+		return &fakeRepository{}, nil
+	}
+	return pool, server.URL, pings
+}
+
+func TestNotaryRepoPool_NewRepoClient(t *testing.T) {
+	t.Run("reuse repositories and server challenges", func(t *testing.T) {
+		//GIVEN
+		pool, url, pings := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 10})
+		factory := pool.Factory(time.Second)
+
+		//WHEN
+		first, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		second, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		other, err := factory.NewRepoClient("registry.io/other", NotaryConfig{Url: url})
+		require.NoError(t, err)
+
+		//THEN
+		require.Same(t, first, second)
+		require.NotSame(t, first, other)
+		require.Equal(t, int32(1), pings.Load())
+		require.Equal(t, 2, pool.Len())
+	})
+	t.Run("evict least recently used repository with its metadata", func(t *testing.T) {
+		//GIVEN
+		trustDir := t.TempDir()
+		pool, url, _ := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 1, TrustDir: trustDir})
+		factory := pool.Factory(time.Second)
+
+		//WHEN
+		first, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		_, err = factory.NewRepoClient("registry.io/other", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		again, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+
+		//THEN
+		require.NotSame(t, first, again)
+		require.Equal(t, 1, pool.Len())
+		dirs, err := os.ReadDir(trustDir)
+		require.NoError(t, err)
+		require.Len(t, dirs, 1)
+	})
+	t.Run("keep metadata of evicted repository until its lookups finish", func(t *testing.T) {
+		//GIVEN
+		trustDir := t.TempDir()
+		pool, url, _ := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 1, TrustDir: trustDir})
+		repo := &blockingRepository{started: make(chan struct{}), release: make(chan struct{})}
+		pool.newRepository = func(_ data.GUN, _ string, _ http.RoundTripper, _ store.MetadataStore) (client.Repository, error) {
+			return repo, nil
+		}
+		factory := pool.Factory(time.Second)
+		first, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		done := make(chan error)
+		go func() {
+			_, err := first.GetTargetByName("v1")
+			done <- err
+		}()
+		<-repo.started
+
+		//WHEN
+		_, err = factory.NewRepoClient("registry.io/other", NotaryConfig{Url: url})
+		require.NoError(t, err)
+
+		//THEN
+		dirs, err := os.ReadDir(trustDir)
+		require.NoError(t, err)
+		require.Len(t, dirs, 2)
+		close(repo.release)
+		require.NoError(t, <-done)
+		dirs, err = os.ReadDir(trustDir)
+		require.NoError(t, err)
+		require.Len(t, dirs, 1)
+	})
+	t.Run("remove metadata left by previous runs", func(t *testing.T) {
+		//GIVEN
+		trustDir := t.TempDir()
+		leftover := filepath.Join(trustDir, strings.Repeat("a", 64)+"-1")
+		require.NoError(t, os.Mkdir(leftover, 0o700))
+		unrelated := filepath.Join(trustDir, "unrelated")
+		require.NoError(t, os.Mkdir(unrelated, 0o700))
+
+		//WHEN
+		NewNotaryRepoPool(NotaryRepoPoolConfig{MaxSize: 1, TrustDir: trustDir})
+
+		//THEN
+		require.NoDirExists(t, leftover)
+		require.DirExists(t, unrelated)
+	})
+	t.Run("remove servers without pooled repositories", func(t *testing.T) {
+		//GIVEN
+		pool, url, _ := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 1})
+		_, otherURL, _ := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 1})
+
+		//WHEN
+		_, err := pool.Factory(time.Second).NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		_, err = pool.Factory(2*time.Second).NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		_, err = pool.Factory(time.Second).NewRepoClient("registry.io/app", NotaryConfig{Url: otherURL})
+		require.NoError(t, err)
+
+		//THEN
+		require.Len(t, pool.endpoints, 1)
+		require.Contains(t, pool.endpoints, otherURL+"|1s")
+	})
+	t.Run("ping server again when challenges expire", func(t *testing.T) {
+		//GIVEN
+		pool, url, pings := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 10})
+		now := time.Now()
+		pool.now = func() time.Time { return now }
+		factory := pool.Factory(time.Second)
+
+		//WHEN
+		_, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+		require.NoError(t, err)
+		now = now.Add(notaryChallengeTTL)
+		_, err = factory.NewRepoClient("registry.io/other", NotaryConfig{Url: url})
+		require.NoError(t, err)
+
+		//THEN
+		require.Equal(t, int32(2), pings.Load())
+	})
+	t.Run("ping server again when it rejects authorized request", func(t *testing.T) {
+		//GIVEN
+		pings := &atomic.Int32{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/" {
+				pings.Add(1)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		pool := NewNotaryRepoPool(NotaryRepoPoolConfig{MaxSize: 10})
+		var rt http.RoundTripper
+		pool.newRepository = func(_ data.GUN, _ string, transport http.RoundTripper, _ store.MetadataStore) (client.Repository, error) {
+			rt = transport
+			return &fakeRepository{}, nil
+		}
+		factory := pool.Factory(time.Second)
+		_, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: server.URL})
+		require.NoError(t, err)
+
+		//WHEN
+		resp, err := (&http.Client{Transport: rt}).Get(server.URL + "/v2/registry.io/app/_trust/tuf/root.json")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		_, err = factory.NewRepoClient("registry.io/other", NotaryConfig{Url: server.URL})
+		require.NoError(t, err)
+
+		//THEN
+		require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		require.Equal(t, int32(2), pings.Load())
+	})
+	t.Run("don't keep metadata of repositories which aren't pooled", func(t *testing.T) {
+		//GIVEN
+		trustDir := t.TempDir()
+		pool, url, _ := newTestNotaryRepoPool(t, NotaryRepoPoolConfig{MaxSize: 0, TrustDir: trustDir})
+		factory := pool.Factory(time.Second)
+
+		//WHEN
+		_, err := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: url})
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, 0, pool.Len())
+		dirs, err := os.ReadDir(trustDir)
+		require.NoError(t, err)
+		require.Empty(t, dirs)
+	})
+	t.Run("don't keep challenges of unavailable server", func(t *testing.T) {
+		//GIVEN
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		pool := NewNotaryRepoPool(NotaryRepoPoolConfig{MaxSize: 10})
+		factory := pool.Factory(time.Second)
+
+		//WHEN
+		_, firstErr := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: server.URL})
+		_, secondErr := factory.NewRepoClient("registry.io/app", NotaryConfig{Url: server.URL})
+
+		//THEN
+		require.ErrorContains(t, firstErr, "couldn't correctly connect to notary, status code: 503")
+		require.NoError(t, secondErr)
+		require.Equal(t, 2, calls)
+	})
+}
+
+func TestPooledRepository_GetTargetByName(t *testing.T) {
+	//GIVEN
+	now := time.Now()
+	repo := &fakeRepository{}
+	pooled := &pooledRepository{
+		Repository:      repo,
+		refreshInterval: time.Minute,
+		now:             func() time.Time { return now },
+	}
+
+	//WHEN
+	_, err := pooled.GetTargetByName("v1")
+	require.NoError(t, err)
+	_, err = pooled.GetTargetByName("v1")
+	require.NoError(t, err)
+	_, err = pooled.GetTargetByName("v2")
+	require.NoError(t, err)
+	now = now.Add(time.Minute)
+	target, err := pooled.GetTargetByName("v1")
+	require.NoError(t, err)
+
+	//THEN
+	require.Equal(t, "v1", target.Name)
+	require.Equal(t, 3, repo.lookups)
+}
@@ -0,0 +1,777 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	NotationSignatureArtifactType = "application/vnd.cncf.notary.signature"
+	NotationJWSMediaType          = "application/jose+json"
+	NotationCOSEMediaType         = "application/cose"
+
+	notationPayloadContentType = "application/vnd.cncf.notary.payload.v1+json"
+	coseSign1Tag               = 18
+	coseHeaderAlgorithm        = 1
+	coseHeaderCritical         = 2
+	coseHeaderContentType      = 3
+	coseHeaderX5Chain          = 33
+
+	notationHeaderSigningScheme           = "io.cncf.notary.signingScheme"
+	notationHeaderSigningTime             = "io.cncf.notary.signingTime"
+	notationHeaderAuthenticSigningTime    = "io.cncf.notary.authenticSigningTime"
+	notationHeaderExpiry                  = "io.cncf.notary.expiry"
+	notationSigningSchemeX509             = "notary.x509"
+	notationSigningSchemeSigningAuthority = "notary.x509.signingAuthority"
+)
+
+// notationCriticalHeaders are the critical headers understood by the verification,
+// signatures with other critical headers are rejected
+var notationCriticalHeaders = map[string]struct{}{
+	notationHeaderSigningScheme:        {},
+	notationHeaderAuthenticSigningTime: {},
+	notationHeaderExpiry:               {},
+}
+
+const (
+	NotationLevelStrict     = "strict"
+	NotationLevelPermissive = "permissive"
+	NotationLevelAudit      = "audit"
+	NotationLevelSkip       = "skip"
+)
+
+// NotationTrustPolicyDocument is a notation-style trust policy, see:
+// https://github.com/notaryproject/specifications/blob/main/specs/trust-store-trust-policy.md
+type NotationTrustPolicyDocument struct {
+	Version       string                `json:"version"`
+	TrustPolicies []NotationTrustPolicy `json:"trustPolicies"`
+}
+
+type NotationTrustPolicy struct {
+	Name                  string                        `json:"name"`
+	RegistryScopes        []string                      `json:"registryScopes"`
+	SignatureVerification NotationSignatureVerification `json:"signatureVerification"`
+	TrustStores           []string                      `json:"trustStores"`
+	TrustedIdentities     []string                      `json:"trustedIdentities"`
+}
+
+type NotationSignatureVerification struct {
+	Level string `json:"level"`
+}
+
+type NotationConfig struct {
+	TrustPolicy NotationTrustPolicyDocument
+	// TrustStores contains certificates by trust store reference, e.g. "ca:kyma"
+	TrustStores map[string][]*x509.Certificate
+	Timeout     time.Duration
+}
+
+// Key returns identifier of the trust policy and trust stores, used to separate cached verification results
+func (nc *NotationConfig) Key() string {
+	values := []string{}
+	if policy, err := json.Marshal(nc.TrustPolicy); err == nil {
+		values = append(values, string(policy))
+	}
+	for storeRef, certs := range nc.TrustStores {
+		for _, cert := range certs {
+			values = append(values, storeRef+":"+string(cert.Raw))
+		}
+	}
+	sort.Strings(values)
+	return TrustConfigKey(values...)
+}
+
+type notationService struct {
+	NotationConfig
+	AllowedRegistries []string
+}
+
+type notationPayload struct {
+	TargetArtifact v1.Descriptor `json:"targetArtifact"`
+}
+
+type jwsEnvelope struct {
+	Payload   string `json:"payload"`
+	Protected string `json:"protected"`
+	Header    struct {
+		X5C []string `json:"x5c"`
+	} `json:"header"`
+	Signature string `json:"signature"`
+}
+
+type jwsProtectedHeader struct {
+	Algorithm            string     `json:"alg"`
+	ContentType          string     `json:"cty"`
+	Critical             []string   `json:"crit"`
+	SigningScheme        string     `json:"io.cncf.notary.signingScheme"`
+	SigningTime          *time.Time `json:"io.cncf.notary.signingTime"`
+	AuthenticSigningTime *time.Time `json:"io.cncf.notary.authenticSigningTime"`
+	Expiry               *time.Time `json:"io.cncf.notary.expiry"`
+}
+
+// notationEnvelope is the content of the signature envelope with the verified signature
+type notationEnvelope struct {
+	payload     []byte
+	chain       []*x509.Certificate
+	signingTime time.Time
+	// expiry is zero when the signature doesn't expire
+	expiry time.Time
+}
+
+type coseSign1 struct {
+	_           struct{} `cbor:",toarray"`
+	Protected   []byte
+	Unprotected map[interface{}]interface{}
+	Payload     []byte
+	Signature   []byte
+}
+
+func NewNotationValidator(nc *NotationConfig, allowedRegistries []string) ImageValidatorService {
+	return &notationService{
+		NotationConfig:    *nc,
+		AllowedRegistries: allowedRegistries,
+	}
+}
+
+// NewNotationConfig parses the trust policy document and the PEM encoded trust stores
+// keyed by the trust store reference (<type>:<name>)
+func NewNotationConfig(trustPolicy string, trustStores map[string]string, timeout time.Duration) (*NotationConfig, error) {
+	var document NotationTrustPolicyDocument
+	if err := json.Unmarshal([]byte(trustPolicy), &document); err != nil {
+		return nil, errors.Wrap(err, "while parsing trust policy")
+	}
+
+	stores := map[string][]*x509.Certificate{}
+	for storeRef, certificates := range trustStores {
+		certs, err := parseCertificates(certificates)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing trust store %s", storeRef)
+		}
+		stores[storeRef] = certs
+	}
+
+	for _, policy := range document.TrustPolicies {
+		if err := validateNotationTrustPolicy(policy, stores); err != nil {
+			return nil, errors.Wrapf(err, "invalid trust policy %s", policy.Name)
+		}
+	}
+
+	return &NotationConfig{
+		TrustPolicy: document,
+		TrustStores: stores,
+		Timeout:     timeout,
+	}, nil
+}
+
+func validateNotationTrustPolicy(policy NotationTrustPolicy, stores map[string][]*x509.Certificate) error {
+	switch policy.SignatureVerification.Level {
+	case NotationLevelStrict, NotationLevelPermissive, NotationLevelAudit:
+	case NotationLevelSkip:
+		return nil
+	default:
+		return errors.Errorf("unsupported signature verification level: %s", policy.SignatureVerification.Level)
+	}
+	if len(policy.RegistryScopes) == 0 {
+		return errors.New("registry scopes are not set")
+	}
+	if len(policy.TrustStores) == 0 {
+		return errors.New("trust stores are not set")
+	}
+	for _, storeRef := range policy.TrustStores {
+		if _, ok := stores[storeRef]; !ok {
+			return errors.Errorf("trust store %s is not defined", storeRef)
+		}
+	}
+	if len(policy.TrustedIdentities) == 0 {
+		return errors.New("trusted identities are not set")
+	}
+	return nil
+}
+
+func parseCertificates(certificates string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(certificates)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, errors.Errorf("unexpected PEM block type: %s", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "while parsing certificate")
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certs, nil
+}
+
+func (s *notationService) Validate(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) error {
+	logger := helpers.LoggerFromCtx(ctx).With("image", image)
+	ctx = helpers.LoggerToContext(ctx, logger)
+
+	reportImageDetails(ctx, imageDetailsValues{verifier: pkg.VerifierNotation})
+	if allowed := isImageAllowed(image, s.AllowedRegistries); allowed {
+		logger.Info("image validation skipped, because it's allowed")
+		reportImageDetails(ctx, imageDetailsValues{reason: ReasonAllowed})
+		return nil
+	}
+
+	// strict validation requires image name to contain domain and a tag, and/or sha256
+	ref, err := name.ParseReference(image, name.StrictValidation)
+	if err != nil {
+		return withReason(ReasonInvalidReference, pkg.NewValidationFailedErr(errors.Wrap(err, "image name could not be parsed")))
+	}
+
+	policy, ok := s.applicablePolicy(ref.Context().Name())
+	if !ok {
+		return withReason(ReasonNoTrustPolicy, pkg.NewValidationFailedErr(errors.Errorf("no applicable trust policy for %s", ref.Context().Name())))
+	}
+	logger = logger.With("trust-policy", policy.Name)
+	if policy.SignatureVerification.Level == NotationLevelSkip {
+		logger.Info("image validation skipped by trust policy")
+		reportImageDetails(ctx, imageDetailsValues{reason: ReasonAllowed})
+		return nil
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	const message = "request to image registry (notation)"
+	closeLog := helpers.LogStartTime(ctx, message)
+	defer closeLog()
+
+	descriptor, remoteOptions, err := getImageDescriptor(ctx, ref, imagePullCredentials, remote.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	reportImageDetails(ctx, imageDetailsValues{digest: descriptor.Digest.String()})
+
+	err = s.verifySignatures(logger, ref.Context(), descriptor.Descriptor, policy, remoteOptions...)
+	if err != nil && pkg.ErrorCode(err) == pkg.ValidationError && policy.SignatureVerification.Level == NotationLevelAudit {
+		logger.Warnw("image validation failed, but trust policy is in audit mode", "reason", err.Error())
+		reportImageDetails(ctx, imageDetailsValues{reason: ReasonAllowed})
+		return nil
+	}
+	return err
+}
+
+// applicablePolicy returns the policy with the repository in the registry scopes or the wildcard policy
+func (s *notationService) applicablePolicy(repository string) (NotationTrustPolicy, bool) {
+	var wildcard *NotationTrustPolicy
+	for i, policy := range s.TrustPolicy.TrustPolicies {
+		for _, scope := range policy.RegistryScopes {
+			if scope == repository {
+				return policy, true
+			}
+			if scope == "*" && wildcard == nil {
+				wildcard = &s.TrustPolicy.TrustPolicies[i]
+			}
+		}
+	}
+	if wildcard != nil {
+		return *wildcard, true
+	}
+	return NotationTrustPolicy{}, false
+}
+
+func (s *notationService) verifySignatures(logger *zap.SugaredLogger, repo name.Repository, target v1.Descriptor, policy NotationTrustPolicy, remoteOptions ...remote.Option) error {
+	digest := repo.Digest(target.Digest.String())
+	referrers, err := remote.Referrers(digest, append(remoteOptions, remote.WithFilter("artifactType", NotationSignatureArtifactType))...)
+	if err != nil {
+		return newRegistryErr(err, "get notation signatures")
+	}
+	index, err := referrers.IndexManifest()
+	if err != nil {
+		return pkg.NewUnknownResultErr(errors.Wrap(err, "notation signatures index"))
+	}
+
+	reason := ReasonSignatureNotFound
+	verifyErr := errors.Errorf("no notation signatures found for %s", digest.DigestStr())
+	for _, desc := range index.Manifests {
+		if desc.ArtifactType != NotationSignatureArtifactType {
+			continue
+		}
+		mediaType, envelope, err := getSignatureEnvelope(digest.Context().Digest(desc.Digest.String()), remoteOptions...)
+		if err != nil {
+			return err
+		}
+		reason = ReasonSignatureInvalid
+		if verifyErr = s.verifyEnvelope(logger, mediaType, envelope, target, policy); verifyErr == nil {
+			return nil
+		}
+	}
+	return withReason(reason, pkg.NewValidationFailedErr(verifyErr))
+}
+
+func getSignatureEnvelope(ref name.Digest, remoteOptions ...remote.Option) (types.MediaType, []byte, error) {
+	sigImage, err := remote.Image(ref, remoteOptions...)
+	if err != nil {
+		return "", nil, pkg.NewUnknownResultErr(errors.Wrap(err, "get notation signature"))
+	}
+	manifest, err := sigImage.Manifest()
+	if err != nil {
+		return "", nil, pkg.NewUnknownResultErr(errors.Wrap(err, "notation signature manifest"))
+	}
+	if len(manifest.Layers) != 1 {
+		return "", nil, pkg.NewValidationFailedErr(errors.New("notation signature must have exactly one layer"))
+	}
+	envelope, err := getLayerPayload(sigImage, manifest.Layers[0])
+	if err != nil {
+		return "", nil, err
+	}
+	return manifest.Layers[0].MediaType, envelope, nil
+}
+
+func (s *notationService) verifyEnvelope(logger *zap.SugaredLogger, mediaType types.MediaType, envelope []byte, target v1.Descriptor, policy NotationTrustPolicy) error {
+	var signed *notationEnvelope
+	var err error
+	switch mediaType {
+	case NotationJWSMediaType:
+		signed, err = verifyJWSEnvelope(envelope)
+	case NotationCOSEMediaType:
+		signed, err = verifyCOSEEnvelope(envelope)
+	default:
+		err = errors.Errorf("unsupported signature envelope type: %s", mediaType)
+	}
+	if err != nil {
+		return err
+	}
+
+	var p notationPayload
+	if err := json.Unmarshal(signed.payload, &p); err != nil {
+		return errors.Wrap(err, "cannot unmarshal signature payload")
+	}
+	if p.TargetArtifact.Digest != target.Digest {
+		return errors.New("unexpected image hash value")
+	}
+	if p.TargetArtifact.MediaType != target.MediaType {
+		return errors.Errorf("unexpected image media type: %s", p.TargetArtifact.MediaType)
+	}
+	if p.TargetArtifact.Size != target.Size {
+		return errors.Errorf("unexpected image size: %d", p.TargetArtifact.Size)
+	}
+
+	if err := s.verifyCertificateChain(logger, signed.chain, policy); err != nil {
+		return err
+	}
+	if err := verifyTrustedIdentity(signed.chain[0], policy.TrustedIdentities); err != nil {
+		return err
+	}
+	return verifySigningTimes(logger, signed, policy)
+}
+
+// verifySigningTimes checks that the signature isn't expired and that it was signed when the signing certificate was valid.
+// It's enforced only by the strict verification level, other levels log the failed checks
+func verifySigningTimes(logger *zap.SugaredLogger, signed *notationEnvelope, policy NotationTrustPolicy) error {
+	now := time.Now()
+	cert := signed.chain[0]
+	var err error
+	switch {
+	case !signed.expiry.IsZero() && !now.Before(signed.expiry):
+		err = errors.Errorf("signature expired at %s", signed.expiry.Format(time.RFC3339))
+	case signed.signingTime.After(now):
+		err = errors.Errorf("signing time %s is in the future", signed.signingTime.Format(time.RFC3339))
+	case signed.signingTime.Before(cert.NotBefore) || signed.signingTime.After(cert.NotAfter):
+		err = errors.Errorf("signing time %s is outside of the signing certificate validity", signed.signingTime.Format(time.RFC3339))
+	}
+	if err == nil || policy.SignatureVerification.Level == NotationLevelStrict {
+		return err
+	}
+	logger.Warnw("signature accepted, because the trust policy doesn't enforce its expiry", "reason", err.Error(),
+		"level", policy.SignatureVerification.Level)
+	return nil
+}
+
+func (s *notationService) verifyCertificateChain(logger *zap.SugaredLogger, chain []*x509.Certificate, policy NotationTrustPolicy) error {
+	roots := x509.NewCertPool()
+	for _, storeRef := range policy.TrustStores {
+		for _, cert := range s.TrustStores[storeRef] {
+			roots.AddCert(cert)
+		}
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	_, err := chain[0].Verify(opts)
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired &&
+		policy.SignatureVerification.Level != NotationLevelStrict {
+		// expiry is only enforced by the strict verification level, the chain has to be valid when the signing certificate expired
+		opts.CurrentTime = chain[0].NotAfter
+		if _, err = chain[0].Verify(opts); err == nil {
+			logger.Warnw("signature accepted, because the trust policy doesn't enforce expiry of its certificate",
+				"reason", invalidErr.Error(), "level", policy.SignatureVerification.Level)
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, "certificate chain is not trusted")
+	}
+	return nil
+}
+
+func verifyTrustedIdentity(cert *x509.Certificate, trustedIdentities []string) error {
+	for _, identity := range trustedIdentities {
+		if identity == "*" {
+			return nil
+		}
+		subject, ok := strings.CutPrefix(identity, "x509.subject:")
+		if ok && subjectMatches(cert.Subject, subject) {
+			return nil
+		}
+	}
+	return errors.Errorf("signing identity %s is not trusted", cert.Subject.String())
+}
+
+func subjectMatches(name pkix.Name, subject string) bool {
+	attributes := map[string][]string{
+		"C":  name.Country,
+		"ST": name.Province,
+		"L":  name.Locality,
+		"O":  name.Organization,
+		"OU": name.OrganizationalUnit,
+		"CN": {name.CommonName},
+	}
+	for _, attribute := range strings.Split(subject, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(attribute), "=")
+		if !ok {
+			return false
+		}
+		values, ok := attributes[strings.TrimSpace(key)]
+		if !ok || !containsString(values, strings.TrimSpace(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func verifyJWSEnvelope(envelope []byte) (*notationEnvelope, error) {
+	var jws jwsEnvelope
+	if err := json.Unmarshal(envelope, &jws); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal JWS envelope")
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(jws.Protected)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode JWS protected header")
+	}
+	var header jwsProtectedHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal JWS protected header")
+	}
+	if header.ContentType != notationPayloadContentType {
+		return nil, errors.Errorf("unexpected payload content type: %s", header.ContentType)
+	}
+	var rawHeaders map[string]json.RawMessage
+	if err := json.Unmarshal(rawHeader, &rawHeaders); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal JWS protected header")
+	}
+	if err := verifyCriticalHeaders(header.Critical, func(name string) bool {
+		_, ok := rawHeaders[name]
+		return ok
+	}); err != nil {
+		return nil, err
+	}
+	signingTime := header.SigningTime
+	if header.SigningScheme == notationSigningSchemeSigningAuthority {
+		signingTime = header.AuthenticSigningTime
+	}
+	signed, err := newNotationEnvelope(header.SigningScheme, signingTime, header.Expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	if signed.chain, err = parseCertificateChain(jws.Header.X5C, base64.StdEncoding.DecodeString); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(jws.Signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot decode JWS signature")
+	}
+	signingInput := []byte(jws.Protected + "." + jws.Payload)
+	if err := verifyNotationSignature(header.Algorithm, signed.chain[0].PublicKey, signingInput, signature); err != nil {
+		return nil, err
+	}
+
+	if signed.payload, err = base64.RawURLEncoding.DecodeString(jws.Payload); err != nil {
+		return nil, errors.Wrap(err, "cannot decode JWS payload")
+	}
+	return signed, nil
+}
+
+func verifyCOSEEnvelope(envelope []byte) (*notationEnvelope, error) {
+	var tag cbor.RawTag
+	if err := cbor.Unmarshal(envelope, &tag); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal COSE envelope")
+	}
+	if tag.Number != coseSign1Tag {
+		return nil, errors.Errorf("unexpected COSE tag: %d", tag.Number)
+	}
+	var msg coseSign1
+	if err := cbor.Unmarshal(tag.Content, &msg); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal COSE_Sign1 message")
+	}
+
+	var protected map[interface{}]interface{}
+	if err := cbor.Unmarshal(msg.Protected, &protected); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal COSE protected header")
+	}
+	algorithm, err := coseAlgorithm(protected[uint64(coseHeaderAlgorithm)])
+	if err != nil {
+		return nil, err
+	}
+	if contentType, _ := protected[uint64(coseHeaderContentType)].(string); contentType != notationPayloadContentType {
+		return nil, errors.Errorf("unexpected payload content type: %v", protected[uint64(coseHeaderContentType)])
+	}
+	critical, err := coseCriticalHeaders(protected[uint64(coseHeaderCritical)])
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyCriticalHeaders(critical, func(name string) bool {
+		_, ok := protected[name]
+		return ok
+	}); err != nil {
+		return nil, err
+	}
+	signingScheme, _ := protected[notationHeaderSigningScheme].(string)
+	signingTimeHeader := notationHeaderSigningTime
+	if signingScheme == notationSigningSchemeSigningAuthority {
+		signingTimeHeader = notationHeaderAuthenticSigningTime
+	}
+	signingTime, err := coseTime(protected, signingTimeHeader)
+	if err != nil {
+		return nil, err
+	}
+	expiry, err := coseTime(protected, notationHeaderExpiry)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := newNotationEnvelope(signingScheme, signingTime, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawChain []string
+	switch x5chain := msg.Unprotected[uint64(coseHeaderX5Chain)].(type) {
+	case []byte:
+		rawChain = []string{string(x5chain)}
+	case []interface{}:
+		for _, cert := range x5chain {
+			if raw, ok := cert.([]byte); ok {
+				rawChain = append(rawChain, string(raw))
+			}
+		}
+	}
+	if signed.chain, err = parseCertificateChain(rawChain, func(s string) ([]byte, error) { return []byte(s), nil }); err != nil {
+		return nil, err
+	}
+
+	signingInput, err := cbor.Marshal([]interface{}{"Signature1", msg.Protected, []byte{}, msg.Payload})
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot build COSE signing input")
+	}
+	if err := verifyNotationSignature(algorithm, signed.chain[0].PublicKey, signingInput, msg.Signature); err != nil {
+		return nil, err
+	}
+	signed.payload = msg.Payload
+	return signed, nil
+}
+
+// newNotationEnvelope checks the signed attributes required by the notary project signature specification, see:
+// https://github.com/notaryproject/specifications/blob/main/specs/signature-specification.md#signed-attributes
+func newNotationEnvelope(signingScheme string, signingTime, expiry *time.Time) (*notationEnvelope, error) {
+	if signingScheme != notationSigningSchemeX509 && signingScheme != notationSigningSchemeSigningAuthority {
+		return nil, errors.Errorf("unsupported signing scheme: %s", signingScheme)
+	}
+	if signingTime == nil {
+		return nil, errors.New("signing time is missing")
+	}
+	signed := &notationEnvelope{signingTime: *signingTime}
+	if expiry != nil {
+		signed.expiry = *expiry
+	}
+	return signed, nil
+}
+
+// verifyCriticalHeaders returns error when any of the critical headers isn't understood or is missing
+func verifyCriticalHeaders(critical []string, present func(name string) bool) error {
+	if !containsString(critical, notationHeaderSigningScheme) {
+		return errors.Errorf("%s header must be marked as critical", notationHeaderSigningScheme)
+	}
+	for _, header := range critical {
+		if _, ok := notationCriticalHeaders[header]; !ok {
+			return errors.Errorf("unsupported critical header: %s", header)
+		}
+		if !present(header) {
+			return errors.Errorf("critical header %s is missing", header)
+		}
+	}
+	return nil
+}
+
+// coseCriticalHeaders returns labels of the critical headers, headers with integer labels aren't understood
+func coseCriticalHeaders(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	labels, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("COSE critical headers must be an array")
+	}
+	critical := make([]string, 0, len(labels))
+	for _, label := range labels {
+		name, ok := label.(string)
+		if !ok {
+			return nil, errors.Errorf("unsupported critical header: %v", label)
+		}
+		critical = append(critical, name)
+	}
+	return critical, nil
+}
+
+// coseTime returns the time of the protected header, which is encoded as CBOR epoch-based date/time
+func coseTime(protected map[interface{}]interface{}, header string) (*time.Time, error) {
+	value, ok := protected[header]
+	if !ok {
+		return nil, nil
+	}
+	t, ok := value.(time.Time)
+	if !ok {
+		return nil, errors.Errorf("%s header must be a date/time", header)
+	}
+	return &t, nil
+}
+
+func coseAlgorithm(value interface{}) (string, error) {
+	alg, ok := value.(int64)
+	if !ok {
+		return "", errors.New("COSE algorithm is missing")
+	}
+	switch alg {
+	case -37:
+		return "PS256", nil
+	case -38:
+		return "PS384", nil
+	case -39:
+		return "PS512", nil
+	case -7:
+		return "ES256", nil
+	case -35:
+		return "ES384", nil
+	case -36:
+		return "ES512", nil
+	}
+	return "", errors.Errorf("unsupported COSE algorithm: %d", alg)
+}
+
+func parseCertificateChain(rawChain []string, decode func(string) ([]byte, error)) ([]*x509.Certificate, error) {
+	if len(rawChain) == 0 {
+		return nil, errors.New("certificate chain is missing")
+	}
+	chain := make([]*x509.Certificate, 0, len(rawChain))
+	for _, raw := range rawChain {
+		der, err := decode(raw)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot decode certificate")
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "while parsing certificate")
+		}
+		chain = append(chain, cert)
+	}
+	return chain, nil
+}
+
+// verifyNotationSignature verifies signatures for algorithms supported by the notary project, see:
+// https://github.com/notaryproject/specifications/blob/main/specs/signature-specification.md#algorithm-selection
+func verifyNotationSignature(algorithm string, key crypto.PublicKey, signingInput, signature []byte) error {
+	var hash crypto.Hash
+	switch algorithm {
+	case "PS256", "ES256":
+		hash = crypto.SHA256
+	case "PS384", "ES384":
+		hash = crypto.SHA384
+	case "PS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return errors.Errorf("unsupported signature algorithm: %s", algorithm)
+	}
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "PS") {
+			break
+		}
+		if err := rsa.VerifyPSS(k, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}); err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "ES") {
+			break
+		}
+		// signature is encoded as concatenated R and S values
+		keySize := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*keySize {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:keySize])
+		s := new(big.Int).SetBytes(signature[keySize:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.Errorf("signature algorithm %s does not match the certificate key", algorithm)
+}
@@ -0,0 +1,515 @@
+package validate_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/stretchr/testify/require"
+)
+
+type notationSigner struct {
+	ca      *x509.Certificate
+	leaf    *x509.Certificate
+	leafKey *ecdsa.PrivateKey
+}
+
+func Test_NotationValidate(t *testing.T) {
+	server := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	defer server.Close()
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+
+	signer := newNotationSigner(t, "warden")
+	otherSigner := newNotationSigner(t, "other")
+
+	jwsImage := pushRandomImage(t, registryHost, "jws:v1")
+	pushNotationSignature(t, jwsImage, validate.NotationJWSMediaType, signer.signJWS(t, imageDescriptor(t, jwsImage), nil))
+
+	coseImage := pushRandomImage(t, registryHost, "cose:v1")
+	pushNotationSignature(t, coseImage, validate.NotationCOSEMediaType, signer.signCOSE(t, imageDescriptor(t, coseImage), nil))
+
+	unsignedImage := pushRandomImage(t, registryHost, "unsigned:v1")
+
+	untrustedImage := pushRandomImage(t, registryHost, "untrusted:v1")
+	pushNotationSignature(t, untrustedImage, validate.NotationJWSMediaType, otherSigner.signJWS(t, imageDescriptor(t, untrustedImage), nil))
+
+	differentDigestImage := pushRandomImage(t, registryHost, "different-digest:v1")
+	pushNotationSignature(t, differentDigestImage, validate.NotationJWSMediaType, signer.signJWS(t, imageDescriptor(t, jwsImage), nil))
+
+	newConfig := func(t *testing.T, level string, trustedIdentity string) *validate.NotationConfig {
+		policy := fmt.Sprintf(`{
+			"version": "1.0",
+			"trustPolicies": [{
+				"name": "default",
+				"registryScopes": ["*"],
+				"signatureVerification": {"level": "%s"},
+				"trustStores": ["ca:warden"],
+				"trustedIdentities": ["%s"]
+			}]
+		}`, level, trustedIdentity)
+		cfg, err := validate.NewNotationConfig(policy, map[string]string{"ca:warden": encodeCertificate(signer.ca)}, time.Second*5)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("image with JWS signature should pass", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "*"), nil)
+
+		err := s.Validate(context.TODO(), jwsImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("image with COSE signature should pass", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "*"), nil)
+
+		err := s.Validate(context.TODO(), coseImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("image signed by trusted identity should pass", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "x509.subject: O=Kyma, CN=warden"), nil)
+
+		err := s.Validate(context.TODO(), jwsImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("image signed by not trusted identity should return validation error", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "x509.subject: O=Kyma, CN=other"), nil)
+
+		err := s.Validate(context.TODO(), jwsImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "is not trusted")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("unsigned image should return validation error", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "*"), nil)
+
+		err := s.Validate(context.TODO(), unsignedImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "no notation signatures found")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("image signed by unknown CA should return validation error", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "*"), nil)
+
+		err := s.Validate(context.TODO(), untrustedImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "certificate chain is not trusted")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("signature for different digest should return validation error", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "*"), nil)
+
+		err := s.Validate(context.TODO(), differentDigestImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "unexpected image hash value")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("unsigned image should pass in audit mode", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelAudit, "*"), nil)
+
+		err := s.Validate(context.TODO(), unsignedImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("unsigned image should pass when verification is skipped", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelSkip, "*"), nil)
+
+		err := s.Validate(context.TODO(), unsignedImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	t.Run("image without applicable trust policy should return validation error", func(t *testing.T) {
+		cfg := newConfig(t, validate.NotationLevelStrict, "*")
+		cfg.TrustPolicy.TrustPolicies[0].RegistryScopes = []string{"other.registry/repo"}
+		s := validate.NewNotationValidator(cfg, nil)
+
+		err := s.Validate(context.TODO(), jwsImage.String(), emptyAuthData)
+
+		require.ErrorContains(t, err, "no applicable trust policy")
+		require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+	})
+
+	t.Run("unsigned image from allowed registry should pass", func(t *testing.T) {
+		s := validate.NewNotationValidator(newConfig(t, validate.NotationLevelStrict, "*"), []string{registryHost})
+
+		err := s.Validate(context.TODO(), unsignedImage.String(), emptyAuthData)
+
+		require.NoError(t, err)
+	})
+
+	expiredJWS := map[string]interface{}{
+		"crit":                  []string{"io.cncf.notary.signingScheme", "io.cncf.notary.expiry"},
+		"io.cncf.notary.expiry": time.Now().Add(-time.Minute).Format(time.RFC3339),
+	}
+	signedEnvelopeTests := []struct {
+		name          string
+		level         string
+		mediaType     types.MediaType
+		envelope      func(target v1.Descriptor) []byte
+		expectedError string
+	}{
+		{
+			name:      "signature for different media type should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				target.MediaType = types.OCIImageIndex
+				return signer.signJWS(t, target, nil)
+			},
+			expectedError: "unexpected image media type",
+		},
+		{
+			name:      "signature for different size should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationCOSEMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				target.Size++
+				return signer.signCOSE(t, target, nil)
+			},
+			expectedError: "unexpected image size",
+		},
+		{
+			name:      "signature with unknown critical header should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signJWS(t, target, map[string]interface{}{
+					"crit":              []string{"io.cncf.notary.signingScheme", "io.example.policy"},
+					"io.example.policy": "enforced",
+				})
+			},
+			expectedError: "unsupported critical header: io.example.policy",
+		},
+		{
+			name:      "COSE signature with unknown critical header should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationCOSEMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signCOSE(t, target, map[interface{}]interface{}{
+					2:  []interface{}{"io.cncf.notary.signingScheme", 99},
+					99: "enforced",
+				})
+			},
+			expectedError: "unsupported critical header: 99",
+		},
+		{
+			name:      "signature with signing authority scheme should pass",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signJWS(t, target, map[string]interface{}{
+					"crit":                                []string{"io.cncf.notary.signingScheme", "io.cncf.notary.authenticSigningTime"},
+					"io.cncf.notary.signingScheme":        "notary.x509.signingAuthority",
+					"io.cncf.notary.signingTime":          nil,
+					"io.cncf.notary.authenticSigningTime": time.Now().Format(time.RFC3339),
+				})
+			},
+		},
+		{
+			name:      "signature without signing time should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signJWS(t, target, map[string]interface{}{"io.cncf.notary.signingTime": nil})
+			},
+			expectedError: "signing time is missing",
+		},
+		{
+			name:      "COSE signature with unexpected content type should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationCOSEMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signCOSE(t, target, map[interface{}]interface{}{3: "application/json"})
+			},
+			expectedError: "unexpected payload content type: application/json",
+		},
+		{
+			name:      "expired signature should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signJWS(t, target, expiredJWS)
+			},
+			expectedError: "signature expired",
+		},
+		{
+			name:      "expired COSE signature should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationCOSEMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signCOSE(t, target, map[interface{}]interface{}{
+					2:                       []interface{}{"io.cncf.notary.signingScheme", "io.cncf.notary.expiry"},
+					"io.cncf.notary.expiry": cbor.Tag{Number: 1, Content: time.Now().Add(-time.Minute).Unix()},
+				})
+			},
+			expectedError: "signature expired",
+		},
+		{
+			name:      "expired signature should pass in permissive mode",
+			level:     validate.NotationLevelPermissive,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signJWS(t, target, expiredJWS)
+			},
+		},
+		{
+			name:      "signature signed before the certificate validity should return validation error",
+			level:     validate.NotationLevelStrict,
+			mediaType: validate.NotationJWSMediaType,
+			envelope: func(target v1.Descriptor) []byte {
+				return signer.signJWS(t, target, map[string]interface{}{
+					"io.cncf.notary.signingTime": time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+				})
+			},
+			expectedError: "is outside of the signing certificate validity",
+		},
+	}
+	for i, tt := range signedEnvelopeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			//GIVEN
+			image := pushRandomImage(t, registryHost, fmt.Sprintf("signed-envelope:v%d", i))
+			pushNotationSignature(t, image, tt.mediaType, tt.envelope(imageDescriptor(t, image)))
+			s := validate.NewNotationValidator(newConfig(t, tt.level, "*"), nil)
+
+			//WHEN
+			err := s.Validate(context.TODO(), image.String(), emptyAuthData)
+
+			//THEN
+			if tt.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.expectedError)
+			require.Equal(t, pkg.ValidationError, pkg.ErrorCode(err))
+		})
+	}
+}
+
+func Test_NewNotationConfig(t *testing.T) {
+	signer := newNotationSigner(t, "warden")
+	stores := map[string]string{"ca:warden": encodeCertificate(signer.ca)}
+
+	tests := []struct {
+		name        string
+		policy      string
+		stores      map[string]string
+		expectedErr string
+	}{
+		{
+			name:   "valid trust policy",
+			policy: `{"version":"1.0","trustPolicies":[{"name":"default","registryScopes":["*"],"signatureVerification":{"level":"strict"},"trustStores":["ca:warden"],"trustedIdentities":["*"]}]}`,
+			stores: stores,
+		},
+		{
+			name:   "skip level does not require trust stores",
+			policy: `{"version":"1.0","trustPolicies":[{"name":"default","registryScopes":["*"],"signatureVerification":{"level":"skip"}}]}`,
+		},
+		{
+			name:        "malformed trust policy",
+			policy:      `{`,
+			expectedErr: "while parsing trust policy",
+		},
+		{
+			name:        "unsupported level",
+			policy:      `{"version":"1.0","trustPolicies":[{"name":"default","registryScopes":["*"],"signatureVerification":{"level":"lenient"},"trustStores":["ca:warden"],"trustedIdentities":["*"]}]}`,
+			stores:      stores,
+			expectedErr: "unsupported signature verification level",
+		},
+		{
+			name:        "undefined trust store",
+			policy:      `{"version":"1.0","trustPolicies":[{"name":"default","registryScopes":["*"],"signatureVerification":{"level":"strict"},"trustStores":["ca:other"],"trustedIdentities":["*"]}]}`,
+			stores:      stores,
+			expectedErr: "trust store ca:other is not defined",
+		},
+		{
+			name:        "malformed trust store",
+			policy:      `{"version":"1.0","trustPolicies":[]}`,
+			stores:      map[string]string{"ca:warden": "not a certificate"},
+			expectedErr: "no certificates found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := validate.NewNotationConfig(tt.policy, tt.stores, time.Second)
+
+			if tt.expectedErr != "" {
+				require.ErrorContains(t, err, tt.expectedErr)
+				require.Nil(t, cfg)
+				return
+			}
+			require.NoError(t, err)
+			require.NotNil(t, cfg)
+		})
+	}
+}
+
+func newNotationSigner(t *testing.T, commonName string) *notationSigner {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Kyma"}, CommonName: commonName + "-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{Organization: []string{"Kyma"}, CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	return &notationSigner{ca: ca, leaf: leaf, leafKey: leafKey}
+}
+
+func (s *notationSigner) payload(t *testing.T, target v1.Descriptor) []byte {
+	payload, err := json.Marshal(map[string]interface{}{"targetArtifact": target})
+	require.NoError(t, err)
+	return payload
+}
+
+// sign returns ECDSA signature encoded as concatenated R and S values
+func (s *notationSigner) sign(t *testing.T, signingInput []byte) []byte {
+	hash := sha256.Sum256(signingInput)
+	r, sig, err := ecdsa.Sign(rand.Reader, s.leafKey, hash[:])
+	require.NoError(t, err)
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	sig.FillBytes(signature[32:])
+	return signature
+}
+
+// signJWS returns JWS envelope with the default protected headers overridden by the given ones, nil values remove the headers
+func (s *notationSigner) signJWS(t *testing.T, target v1.Descriptor, headers map[string]interface{}) []byte {
+	protectedHeaders := map[string]interface{}{
+		"alg":                          "ES256",
+		"cty":                          "application/vnd.cncf.notary.payload.v1+json",
+		"crit":                         []string{"io.cncf.notary.signingScheme"},
+		"io.cncf.notary.signingScheme": "notary.x509",
+		"io.cncf.notary.signingTime":   time.Now().Format(time.RFC3339),
+	}
+	for key, value := range headers {
+		protectedHeaders[key] = value
+		if value == nil {
+			delete(protectedHeaders, key)
+		}
+	}
+	rawHeaders, err := json.Marshal(protectedHeaders)
+	require.NoError(t, err)
+	protected := base64.RawURLEncoding.EncodeToString(rawHeaders)
+	payload := base64.RawURLEncoding.EncodeToString(s.payload(t, target))
+	envelope, err := json.Marshal(map[string]interface{}{
+		"payload":   payload,
+		"protected": protected,
+		"header": map[string]interface{}{
+			"x5c": []string{base64.StdEncoding.EncodeToString(s.leaf.Raw), base64.StdEncoding.EncodeToString(s.ca.Raw)},
+		},
+		"signature": base64.RawURLEncoding.EncodeToString(s.sign(t, []byte(protected+"."+payload))),
+	})
+	require.NoError(t, err)
+	return envelope
+}
+
+// signCOSE returns COSE_Sign1 envelope with the default protected headers overridden by the given ones
+func (s *notationSigner) signCOSE(t *testing.T, target v1.Descriptor, headers map[interface{}]interface{}) []byte {
+	protectedHeaders := map[interface{}]interface{}{
+		1:                              -7,
+		2:                              []interface{}{"io.cncf.notary.signingScheme"},
+		3:                              "application/vnd.cncf.notary.payload.v1+json",
+		"io.cncf.notary.signingScheme": "notary.x509",
+		"io.cncf.notary.signingTime":   cbor.Tag{Number: 1, Content: time.Now().Unix()},
+	}
+	for key, value := range headers {
+		protectedHeaders[key] = value
+	}
+	protected, err := cbor.Marshal(protectedHeaders)
+	require.NoError(t, err)
+	payload := s.payload(t, target)
+	signingInput, err := cbor.Marshal([]interface{}{"Signature1", protected, []byte{}, payload})
+	require.NoError(t, err)
+
+	content, err := cbor.Marshal([]interface{}{
+		protected,
+		map[int]interface{}{33: [][]byte{s.leaf.Raw, s.ca.Raw}},
+		payload,
+		s.sign(t, signingInput),
+	})
+	require.NoError(t, err)
+	envelope, err := cbor.Marshal(cbor.RawTag{Number: 18, Content: content})
+	require.NoError(t, err)
+	return envelope
+}
+
+func imageDescriptor(t *testing.T, image name.Digest) v1.Descriptor {
+	desc, err := remote.Head(image)
+	require.NoError(t, err)
+	return *desc
+}
+
+func pushNotationSignature(t *testing.T, image name.Digest, mediaType types.MediaType, envelope []byte) {
+	desc, err := remote.Head(image)
+	require.NoError(t, err)
+
+	sigImage := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), validate.NotationSignatureArtifactType)
+	sigImage, err = mutate.Append(sigImage, mutate.Addendum{Layer: static.NewLayer(envelope, mediaType)})
+	require.NoError(t, err)
+	sigImage = mutate.Subject(sigImage, *desc).(v1.Image)
+
+	digest, err := sigImage.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(image.Context().Digest(digest.String()), sigImage))
+}
+
+func encodeCertificate(cert *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+}
@@ -1,11 +1,61 @@
 package validate
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
 
 const (
 	allowedRegistriesSeparator = ","
+
+	// allowedRegistryGlobPrefix marks the entry matched as the glob pattern
+	allowedRegistryGlobPrefix = "glob:"
+	// allowedRegistryRegexPrefix marks the entry matched as the regular expression
+	allowedRegistryRegexPrefix = "regex:"
+
+	defaultDomain       = "docker.io"
+	legacyDefaultDomain = "index.docker.io"
+	officialRepoPrefix  = "library/"
+
+	// maxCompiledExpressions limits the number of cached regular expressions
+	maxCompiledExpressions = 1024
+)
+
+var (
+	// compiledExpressions caches regular expressions of the allowed registries, denied images and image policies
+	// entries, so they are compiled once and not on every match. Entries come from the configuration, policies
+	// and namespace annotations, the cache is cleared when it's full to keep it bounded
+	compiledExpressions   = map[string]compiledExpression{}
+	compiledExpressionsMu sync.RWMutex
 )
 
+type compiledExpression struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// compileExpression returns the compiled regular expression, or the error when it's invalid
+func compileExpression(expr string) (*regexp.Regexp, error) {
+	compiledExpressionsMu.RLock()
+	compiled, ok := compiledExpressions[expr]
+	compiledExpressionsMu.RUnlock()
+	if ok {
+		return compiled.re, compiled.err
+	}
+
+	re, err := regexp.Compile(expr)
+	compiledExpressionsMu.Lock()
+	defer compiledExpressionsMu.Unlock()
+	if len(compiledExpressions) >= maxCompiledExpressions {
+		compiledExpressions = map[string]compiledExpression{}
+	}
+	compiledExpressions[expr] = compiledExpression{re: re, err: err}
+	return re, err
+}
+
 func ParseAllowedRegistries(registries string) []string {
 	var registriesList []string
 	for _, registry := range strings.Split(registries, allowedRegistriesSeparator) {
@@ -17,3 +67,133 @@ func ParseAllowedRegistries(registries string) []string {
 
 	return registriesList
 }
+
+// ValidateAllowedRegistries returns error when any of the allowed registries entries can't be matched
+func ValidateAllowedRegistries(allowedRegistries []string) error {
+	for _, allowed := range allowedRegistries {
+		if err := validateImageRule(allowed); err != nil {
+			return errors.Wrapf(err, "invalid allowed registry %s", allowed)
+		}
+	}
+	return nil
+}
+
+func validateImageRule(rule string) error {
+	expr, ok := strings.CutPrefix(rule, allowedRegistryRegexPrefix)
+	if !ok {
+		return nil
+	}
+	_, err := compileExpression(anchoredExpr(expr))
+	return err
+}
+
+// anchoredExpr makes the regular expression match the whole image reference, so the entry can't be matched
+// by a longer name containing it, e.g. `registry.io/app` by `evil.io/registry.io/app-x`
+func anchoredExpr(expr string) string {
+	return "^(?:" + expr + ")$"
+}
+
+func isImageAllowed(imgRepo string, allowedRegistries []string) bool {
+	for _, allowed := range allowedRegistries {
+		if MatchAllowedRegistry(allowed, imgRepo) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchAllowedRegistry matches the image against the allowed registries entry. Both are normalized first,
+// so the `nginx` is the same as `docker.io/library/nginx`. The entry is one of:
+//   - `regex:<expression>` - the regular expression matched against the whole normalized image reference
+//   - `glob:<pattern>`, or pattern containing `*` or `?` - the glob pattern (see MatchImagePattern) matched
+//     against the repository, or against the whole reference when the pattern contains the tag or the digest
+//   - prefix - matches the registry, the repository or the path ending on the path segment boundary,
+//     so `registry.io/team` doesn't match `registry.io/team-evil`. Prefix ending with `-`, `_` or `.`
+//     can't end the name and it matches any image name starting with it
+func MatchAllowedRegistry(allowed, image string) bool {
+	if expr, ok := strings.CutPrefix(allowed, allowedRegistryRegexPrefix); ok {
+		// invalid expressions are rejected by ValidateAllowedRegistries, so they never match here
+		re, err := compileExpression(anchoredExpr(expr))
+		return err == nil && re.MatchString(normalizeImageName(image))
+	}
+
+	pattern, isGlob := strings.CutPrefix(allowed, allowedRegistryGlobPrefix)
+	if isGlob || strings.ContainsAny(pattern, "*?") {
+		return matchNormalizedImagePattern(pattern, image)
+	}
+
+	prefix := normalizeImageName(allowed)
+	normalized := normalizeImageName(image)
+	if !strings.HasPrefix(normalized, prefix) {
+		return false
+	}
+	if len(normalized) == len(prefix) || strings.ContainsAny(prefix[len(prefix)-1:], "/:@-_.") {
+		return true
+	}
+	return strings.ContainsAny(normalized[len(prefix):len(prefix)+1], "/:@")
+}
+
+// matchNormalizedImagePattern matches the normalized image against the glob pattern (see MatchImagePattern),
+// the pattern is matched against the repository, or against the whole reference when it contains the tag or the digest
+func matchNormalizedImagePattern(pattern, image string) bool {
+	// pattern starting with the wildcard can match any registry so it's not normalized
+	if !strings.HasPrefix(pattern, "*") && !strings.HasPrefix(pattern, "?") {
+		pattern = normalizeImageName(pattern)
+	}
+	normalized := normalizeImageName(image)
+	if !hasTagOrDigest(pattern) {
+		normalized = repositoryName(normalized)
+	}
+	return MatchImagePattern(pattern, normalized)
+}
+
+// normalizeImageName adds the default registry and the `library` namespace of official images
+// to the image name, the first path segment is the registry when it contains `.` or `:`, or it's the `localhost`
+func normalizeImageName(image string) string {
+	domain, remainder, found := strings.Cut(image, "/")
+	if !found {
+		// the registry alone, e.g. `registry.io` or `localhost:5000`
+		if isRegistry(domain) {
+			return strings.ToLower(domain)
+		}
+		return defaultDomain + "/" + officialRepoPrefix + image
+	}
+	if !isDomain(domain) {
+		return defaultDomain + "/" + image
+	}
+	domain = strings.ToLower(domain)
+	if domain == legacyDefaultDomain {
+		domain = defaultDomain
+	}
+	if domain == defaultDomain && !strings.Contains(repositoryName(remainder), "/") {
+		remainder = officialRepoPrefix + remainder
+	}
+	return domain + "/" + remainder
+}
+
+func isDomain(segment string) bool {
+	return strings.ContainsAny(segment, ".:") || segment == "localhost"
+}
+
+// isRegistry distinguishes the registry with optional port from the image name with the tag, e.g. `nginx:1.0`
+func isRegistry(segment string) bool {
+	host, port, hasPort := strings.Cut(segment, ":")
+	if hasPort && (port == "" || strings.Trim(port, "0123456789") != "") {
+		return false
+	}
+	return !strings.Contains(host, "@") && (strings.Contains(host, ".") || host == "localhost")
+}
+
+// repositoryName returns the image name without the tag and the digest
+func repositoryName(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	lastSlash := strings.LastIndex(image, "/")
+	if colon := strings.LastIndex(image, ":"); colon > lastSlash {
+		image = image[:colon]
+	}
+	return image
+}
+
+func hasTagOrDigest(image string) bool {
+	return repositoryName(image) != image
+}
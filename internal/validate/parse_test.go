@@ -3,6 +3,8 @@ package validate
 import (
 	"reflect"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseAllowedRegistries(t *testing.T) {
@@ -51,3 +53,81 @@ func TestParseAllowedRegistries(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchAllowedRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed string
+		image   string
+		want    bool
+	}{
+		{name: "registry", allowed: "registry.io", image: "registry.io/team/app:1.0", want: true},
+		{name: "registry with port", allowed: "localhost:5000", image: "localhost:5000/app", want: true},
+		{name: "registry with different port", allowed: "localhost:5000", image: "localhost:5001/app", want: false},
+		{name: "registry is not domain prefix", allowed: "registry.io", image: "registry.io.evil.com/app:1.0", want: false},
+		{name: "repository path", allowed: "registry.io/team", image: "registry.io/team/app:1.0", want: true},
+		{name: "repository path is segment aware", allowed: "registry.io/team", image: "registry.io/team-evil/app:1.0", want: false},
+		{name: "repository with tag", allowed: "registry.io/team/app", image: "registry.io/team/app:1.0", want: true},
+		{name: "repository with digest", allowed: "registry.io/team/app", image: "registry.io/team/app@sha256:5b0bcabd1ed22e9fb1310cf6c2dec7cdef19f0ad69efa1f392e94a4333501270", want: true},
+		{name: "prefix ending with separator", allowed: "registry.io/team-", image: "registry.io/team-evil/app:1.0", want: true},
+		{name: "prefix ending with slash", allowed: "registry.io/team/", image: "registry.io/team/app", want: true},
+		{name: "official image", allowed: "nginx", image: "docker.io/library/nginx:1.25", want: true},
+		{name: "official image with legacy domain", allowed: "docker.io/library/nginx", image: "index.docker.io/library/nginx", want: true},
+		{name: "official image short name", allowed: "docker.io/library/nginx", image: "nginx:1.25", want: true},
+		{name: "docker hub user image", allowed: "docker.io/user/", image: "user/app:1.0", want: true},
+		{name: "official image is not user image", allowed: "nginx", image: "nginx-evil/app", want: false},
+		{name: "glob matches repository with any tag", allowed: "registry.io/team/app*", image: "registry.io/team/app:1.0", want: true},
+		{name: "explicit glob matches single repository", allowed: "glob:registry.io/team/app", image: "registry.io/team/app:1.0", want: true},
+		{name: "explicit glob doesn't match other repository", allowed: "glob:registry.io/team/app", image: "registry.io/team/app/sub:1.0", want: false},
+		{name: "glob with single segment wildcard", allowed: "registry.io/*/app", image: "registry.io/team/app:1.0", want: true},
+		{name: "glob wildcard doesn't match path separator", allowed: "registry.io/*", image: "registry.io/team/app:1.0", want: false},
+		{name: "glob with double wildcard", allowed: "registry.io/**", image: "registry.io/team/app:1.0", want: true},
+		{name: "glob with tag", allowed: "registry.io/team/app:v1.*", image: "registry.io/team/app:v1.2", want: true},
+		{name: "glob with different tag", allowed: "registry.io/team/app:v1.*", image: "registry.io/team/app:v2.0", want: false},
+		{name: "glob with wildcard registry", allowed: "*.registry.io/app", image: "eu.registry.io/app:1.0", want: true},
+		{name: "glob matching all images", allowed: "**", image: "registry.io/team/app:1.0", want: true},
+		{name: "glob of official image", allowed: "nginx:1.*", image: "docker.io/library/nginx:1.25", want: true},
+		{name: "regex", allowed: `regex:^registry\.io/team/(app|sidecar):v[0-9]+$`, image: "registry.io/team/sidecar:v2", want: true},
+		{name: "regex not matching", allowed: `regex:^registry\.io/team/(app|sidecar):v[0-9]+$`, image: "registry.io/team/app:latest", want: false},
+		{name: "regex matches normalized image", allowed: `regex:docker\.io/library/.*`, image: "alpine", want: true},
+		{name: "regex matches the whole image", allowed: `regex:registry\.io/app`, image: "evil.io/registry.io/app-x", want: false},
+		{name: "regex doesn't match the image prefix", allowed: `regex:registry\.io/app|other\.io/app`, image: "registry.io/app-x", want: false},
+		{name: "invalid regex", allowed: "regex:registry.io/(team", image: "registry.io/(team", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			//WHEN
+			got := MatchAllowedRegistry(tt.allowed, tt.image)
+
+			//THEN
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateAllowedRegistries(t *testing.T) {
+	require.NoError(t, ValidateAllowedRegistries([]string{"registry.io", "glob:registry.io/app", `regex:^registry\.io/`}))
+	require.ErrorContains(t, ValidateAllowedRegistries([]string{"registry.io", "regex:registry.io/(team"}),
+		"invalid allowed registry regex:registry.io/(team")
+}
+
+func TestCompileExpression(t *testing.T) {
+	t.Run("expression is compiled once", func(t *testing.T) {
+		//WHEN
+		first, err := compileExpression(`^registry\.io/app$`)
+		require.NoError(t, err)
+		second, err := compileExpression(`^registry\.io/app$`)
+		require.NoError(t, err)
+
+		//THEN
+		require.Same(t, first, second)
+	})
+
+	t.Run("invalid expression returns error", func(t *testing.T) {
+		//WHEN
+		_, err := compileExpression("registry.io/(team")
+
+		//THEN
+		require.Error(t, err)
+	})
+}
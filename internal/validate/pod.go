@@ -2,12 +2,18 @@ package validate
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	cliType "github.com/docker/cli/cli/config/types"
 	"github.com/kyma-project/warden/internal/helpers"
+	"github.com/kyma-project/warden/internal/logging/tracing"
 	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	corev1 "k8s.io/api/core/v1"
 )
 
@@ -16,6 +22,12 @@ type ValidationStatus string
 type ValidationResult struct {
 	Status        ValidationStatus
 	InvalidImages []string
+	// StrictMode overrides the namespace and system strict mode when it's set by the image policy
+	StrictMode *bool
+	// Warnings describe images which were admitted with reservations, e.g. their verification is pending
+	Warnings []string
+	// Images contains verdicts of all validated images, sorted by the image name
+	Images []ImageVerdict
 }
 
 const (
@@ -28,22 +40,59 @@ const (
 //go:generate mockery --name ValidatorSvcFactory
 type ValidatorSvcFactory interface {
 	NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) PodValidator
+	NewCosignValidatorSvc(publicKeys string, allowedRegistries string, timeout time.Duration) (PodValidator, error)
+	NewNotationValidatorSvc(allowedRegistries string, timeout time.Duration) (PodValidator, error)
+	NewNotationValidatorSvcWithConfig(notationConfig *NotationConfig, allowedRegistries string, timeout time.Duration) (PodValidator, error)
 }
 
 var _ ValidatorSvcFactory = &validatorSvcFactory{}
 
+type ValidatorSvcFactoryConfig struct {
+	PredefinedAllowedRegistries []string
+	// NotationConfig contains trust policy and trust stores used by the notation verifier
+	NotationConfig *NotationConfig
+	// Cache is shared by all created validators, verification results are not cached when it's nil
+	Cache *VerificationCache
+	// DeniedImages are rejected by all created validators before the verification
+	DeniedImages []string
+	// RegistryMirrors map mirrored images to the upstream repositories holding their notary trust data
+	RegistryMirrors RegistryMirrors
+	// NotaryRepoPool is shared by all created notary validators, a new repository client is created
+	// for each lookup when it's nil
+	NotaryRepoPool *NotaryRepoPool
+}
+
 type validatorSvcFactory struct {
 	predefinedAllowedRegistries []string
+	notationConfig              *NotationConfig
+	cache                       *VerificationCache
+	deniedImages                []string
+	registryMirrors             RegistryMirrors
+	notaryRepoPool              *NotaryRepoPool
 }
 
 func NewValidatorSvcFactory(predefinedAllowedRegistries ...string) ValidatorSvcFactory {
+	return NewValidatorSvcFactoryWithConfig(ValidatorSvcFactoryConfig{
+		PredefinedAllowedRegistries: predefinedAllowedRegistries,
+	})
+}
+
+func NewValidatorSvcFactoryWithConfig(cfg ValidatorSvcFactoryConfig) ValidatorSvcFactory {
 	return &validatorSvcFactory{
-		predefinedAllowedRegistries: predefinedAllowedRegistries,
+		predefinedAllowedRegistries: cfg.PredefinedAllowedRegistries,
+		notationConfig:              cfg.NotationConfig,
+		cache:                       cfg.Cache,
+		deniedImages:                cfg.DeniedImages,
+		registryMirrors:             cfg.RegistryMirrors,
+		notaryRepoPool:              cfg.NotaryRepoPool,
 	}
 }
 
 func (f validatorSvcFactory) NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) PodValidator {
-	repoFactory := NotaryRepoFactory{Timeout: notaryTimeout}
+	var repoFactory RepoFactory = NotaryRepoFactory{Timeout: notaryTimeout}
+	if f.notaryRepoPool != nil {
+		repoFactory = f.notaryRepoPool.Factory(notaryTimeout)
+	}
 	allowedRegistries := append(
 		ParseAllowedRegistries(notaryAllowedRegistries),
 		f.predefinedAllowedRegistries...)
@@ -51,17 +100,129 @@ func (f validatorSvcFactory) NewValidatorSvc(notaryURL string, notaryAllowedRegi
 	validatorSvcConfig := ServiceConfig{
 		NotaryConfig:      NotaryConfig{Url: notaryURL},
 		AllowedRegistries: allowedRegistries,
+		RegistryMirrors:   f.registryMirrors,
+		Timeout:           notaryTimeout,
 	}
 	podValidatorSvc := NewImageValidator(&validatorSvcConfig, repoFactory)
-	validatorSvc := NewPodValidator(podValidatorSvc)
+	podValidatorSvc = f.withCache(podValidatorSvc, allowedRegistries, pkg.VerifierNotary, notaryURL)
+	validatorSvc := NewDenyingPodValidator(NewPodValidator(podValidatorSvc), f.deniedImages)
 	return validatorSvc
 }
 
+func (f validatorSvcFactory) NewCosignValidatorSvc(publicKeys string, allowedRegistries string, timeout time.Duration) (PodValidator, error) {
+	keys, err := ParseCosignPublicKeys(publicKeys)
+	if err != nil {
+		return nil, errors.Wrap(err, "while parsing cosign public keys")
+	}
+
+	cosignConfig := CosignConfig{
+		PublicKeys: keys,
+		Timeout:    timeout,
+	}
+	registries := append(
+		ParseAllowedRegistries(allowedRegistries),
+		f.predefinedAllowedRegistries...)
+	imageValidatorSvc := NewCosignValidator(&cosignConfig, registries)
+	imageValidatorSvc = f.withCache(imageValidatorSvc, registries, pkg.VerifierCosign, publicKeys)
+	return NewDenyingPodValidator(NewPodValidator(imageValidatorSvc), f.deniedImages), nil
+}
+
+func (f validatorSvcFactory) NewNotationValidatorSvc(allowedRegistries string, timeout time.Duration) (PodValidator, error) {
+	return f.NewNotationValidatorSvcWithConfig(f.notationConfig, allowedRegistries, timeout)
+}
+
+// NewNotationValidatorSvcWithConfig creates notation validator with given trust policy and trust stores
+// instead of the ones from the factory configuration
+func (f validatorSvcFactory) NewNotationValidatorSvcWithConfig(cfg *NotationConfig, allowedRegistries string, timeout time.Duration) (PodValidator, error) {
+	if cfg == nil {
+		return nil, errors.New("notation trust policy is not configured")
+	}
+
+	notationConfig := *cfg
+	notationConfig.Timeout = timeout
+	registries := append(
+		ParseAllowedRegistries(allowedRegistries),
+		f.predefinedAllowedRegistries...)
+	imageValidatorSvc := NewNotationValidator(&notationConfig, registries)
+	imageValidatorSvc = f.withCache(imageValidatorSvc, registries, pkg.VerifierNotation, notationConfig.Key())
+	return NewDenyingPodValidator(NewPodValidator(imageValidatorSvc), f.deniedImages), nil
+}
+
+func (f validatorSvcFactory) withCache(validator ImageValidatorService, allowedRegistries []string, trustConfig ...string) ImageValidatorService {
+	if f.cache == nil {
+		return validator
+	}
+	// all inputs affecting the verdict are a part of the key, so results cached before the configuration
+	// is reloaded aren't reused with the changed configuration
+	keyValues := append([]string{}, trustConfig...)
+	keyValues = append(keyValues,
+		"allowedRegistries="+strings.Join(allowedRegistries, allowedRegistriesSeparator),
+		"registryMirrors="+f.registryMirrors.key())
+	return NewCachedImageValidator(validator, f.cache, TrustConfigKey(keyValues...), allowedRegistries)
+}
+
+type SystemValidationConfig struct {
+	Verifier          string
+	NotaryURL         string
+	NotaryTimeout     time.Duration
+	AllowedRegistries string
+	CosignPublicKeys  string
+	CosignTimeout     time.Duration
+	NotationTimeout   time.Duration
+}
+
+func NewSystemValidationSvc(cfg SystemValidationConfig, validatorFactory ValidatorSvcFactory) (PodValidator, error) {
+	switch cfg.Verifier {
+	case pkg.VerifierCosign:
+		return validatorFactory.NewCosignValidatorSvc(cfg.CosignPublicKeys, cfg.AllowedRegistries, cfg.CosignTimeout)
+	case pkg.VerifierNotation:
+		return validatorFactory.NewNotationValidatorSvc(cfg.AllowedRegistries, cfg.NotationTimeout)
+	case pkg.VerifierNotary, "":
+		return validatorFactory.NewValidatorSvc(cfg.NotaryURL, cfg.AllowedRegistries, cfg.NotaryTimeout), nil
+	}
+	return nil, errors.Errorf("unsupported verifier: %s", cfg.Verifier)
+}
+
 func NewUserValidationSvc(ns *corev1.Namespace, validatorFactory ValidatorSvcFactory) (PodValidator, error) {
+	verifier, err := helpers.GetUserValidationVerifier(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	if verifier == pkg.VerifierCosign {
+		cosignConfig, err := helpers.GetUserValidationCosignConfig(ns)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateUserAllowedRegistries(cosignConfig.AllowedRegistries); err != nil {
+			return nil, err
+		}
+		return validatorFactory.NewCosignValidatorSvc(
+			cosignConfig.PublicKeys,
+			cosignConfig.AllowedRegistries,
+			cosignConfig.Timeout)
+	}
+
+	if verifier == pkg.VerifierNotation {
+		notationConfig, err := helpers.GetUserValidationNotationConfig(ns)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateUserAllowedRegistries(notationConfig.AllowedRegistries); err != nil {
+			return nil, err
+		}
+		return validatorFactory.NewNotationValidatorSvc(
+			notationConfig.AllowedRegistries,
+			notationConfig.Timeout)
+	}
+
 	userValidationConfig, errGetUserValidation := helpers.GetUserValidationNotaryConfig(ns)
 	if errGetUserValidation != nil {
 		return nil, errGetUserValidation
 	}
+	if err := validateUserAllowedRegistries(userValidationConfig.AllowedRegistries); err != nil {
+		return nil, err
+	}
 	validationSvc := validatorFactory.NewValidatorSvc(
 		userValidationConfig.NotaryURL,
 		userValidationConfig.AllowedRegistries,
@@ -69,6 +230,15 @@ func NewUserValidationSvc(ns *corev1.Namespace, validatorFactory ValidatorSvcFac
 	return validationSvc, nil
 }
 
+// validateUserAllowedRegistries rejects the namespace allowed registries with invalid entries,
+// so the invalid regular expression fails the validation instead of never matching
+func validateUserAllowedRegistries(allowedRegistries string) error {
+	if err := ValidateAllowedRegistries(ParseAllowedRegistries(allowedRegistries)); err != nil {
+		return errors.Wrapf(err, "invalid %s annotation", pkg.NamespaceAllowedRegistriesAnnotation)
+	}
+	return nil
+}
+
 //go:generate mockery --name PodValidator
 type PodValidator interface {
 	ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error)
@@ -76,21 +246,41 @@ type PodValidator interface {
 
 var _ PodValidator = &podValidator{}
 
+// DefaultImageValidationWorkers is the number of images of one pod validated concurrently
+const DefaultImageValidationWorkers = 5
+
 type podValidator struct {
 	Validator ImageValidatorService
+	workers   int
 }
 
 func NewPodValidator(imageValidator ImageValidatorService) PodValidator {
+	return NewPodValidatorWithWorkers(imageValidator, DefaultImageValidationWorkers)
+}
+
+func NewPodValidatorWithWorkers(imageValidator ImageValidatorService, workers int) PodValidator {
+	if workers < 1 {
+		workers = 1
+	}
 	return &podValidator{
-		imageValidator,
+		Validator: imageValidator,
+		workers:   workers,
 	}
 }
 
+type imageValidationResult struct {
+	image    string
+	status   ValidationStatus
+	err      error
+	warnings []string
+	verdict  ImageVerdict
+}
+
 func (a *podValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error) {
 	logger := helpers.LoggerFromCtx(ctx)
 
 	if ns.Name != pod.Namespace {
-		return ValidationResult{Invalid, nil}, errors.New("pod namespace mismatch with given namespace")
+		return ValidationResult{Status: Invalid}, errors.New("pod namespace mismatch with given namespace")
 	}
 
 	images := getAllImages(pod)
@@ -98,18 +288,101 @@ func (a *podValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *cor
 	admitResult := Valid
 
 	invalidImages := []string{}
+	var warnings []string
+	var verdicts []ImageVerdict
 
-	for s := range images {
-		result, err := a.validateImage(ctx, s, imagePullCredentials)
+	for _, result := range a.validateImages(ctx, images, imagePullCredentials) {
+		verdicts = append(verdicts, result.verdict)
+		imageValidations.WithLabelValues(string(result.status), ns.Name, imageRegistry(result.image)).Inc()
+		for _, warning := range result.warnings {
+			warnings = append(warnings, fmt.Sprintf("image %s: %s", result.image, warning))
+		}
+		if result.status != Valid {
+			admitResult = mergeValidationStatus(admitResult, result.status)
+			invalidImages = append(invalidImages, result.image)
+			logger.With("image", result.image).Info(result.err.Error())
+		}
+		if result.status == ServiceUnavailable {
+			warnings = append(warnings, fmt.Sprintf("image %s: verification is pending: %s", result.image, result.err.Error()))
+		}
+	}
+
+	return ValidationResult{Status: admitResult, InvalidImages: invalidImages, Warnings: warnings, Images: verdicts}, nil
+}
+
+// validateImages validates images concurrently using bounded number of workers,
+// results are returned in the order of sorted image names.
+// Images which weren't validated before the context was done are reported as ServiceUnavailable
+func (a *podValidator) validateImages(ctx context.Context, images map[string]struct{}, imagePullCredentials map[string]cliType.AuthConfig) []imageValidationResult {
+	sortedImages := sortedImageNames(images)
+	results := make([]imageValidationResult, len(sortedImages))
+	forEachImage(ctx, sortedImages, a.workers, func(i int, image string) {
+		imageCtx, span := tracing.StartSpan(contextWithImageDetails(ContextWithWarnings(ctx)), "validate image", attribute.String("image", image))
+		startTime := time.Now()
+		status, err := a.validateImage(imageCtx, image, imagePullCredentials)
+		imageValidationDuration.WithLabelValues(string(status), imageRegistry(image)).Observe(time.Since(startTime).Seconds())
+		span.SetAttributes(attribute.String("status", string(status)))
+		tracing.EndSpan(span, err)
+		results[i] = imageValidationResult{
+			image:    image,
+			status:   status,
+			err:      err,
+			warnings: WarningsFromContext(imageCtx),
+			verdict:  newImageVerdict(image, status, err, imageDetailsFromContext(imageCtx)),
+		}
+	}, func(i int, image string) {
+		err := pkg.NewUnknownResultErr(errors.Wrap(ctx.Err(), "image validation interrupted"))
+		results[i] = imageValidationResult{
+			image:   image,
+			status:  ServiceUnavailable,
+			err:     err,
+			verdict: newImageVerdict(image, ServiceUnavailable, err, imageDetailsValues{}),
+		}
+	})
+	return results
+}
 
-		if result != Valid {
-			admitResult = result
-			invalidImages = append(invalidImages, s)
-			logger.With("image", s).Info(err.Error())
+// forEachImage calls process for each image using bounded number of workers and waits until all are processed,
+// interrupted is called for images which weren't processed before the context was done
+func forEachImage(ctx context.Context, images []string, workers int, process, interrupted func(i int, image string)) {
+	workersPool := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+	for i, image := range images {
+		select {
+		case workersPool <- struct{}{}:
+		case <-ctx.Done():
+			interrupted(i, image)
+			continue
 		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-workersPool }()
+			process(i, image)
+		}()
 	}
+	wg.Wait()
+}
+
+func sortedImageNames(images map[string]struct{}) []string {
+	sortedImages := make([]string, 0, len(images))
+	for image := range images {
+		sortedImages = append(sortedImages, image)
+	}
+	sort.Strings(sortedImages)
+	return sortedImages
+}
 
-	return ValidationResult{admitResult, invalidImages}, nil
+// mergeValidationStatus returns more severe status, the precedence is Invalid > ServiceUnavailable > Valid
+func mergeValidationStatus(current, next ValidationStatus) ValidationStatus {
+	if current == Invalid || next == Invalid {
+		return Invalid
+	}
+	if current == ServiceUnavailable || next == ServiceUnavailable {
+		return ServiceUnavailable
+	}
+	return current
 }
 
 func (a *podValidator) validateImage(ctx context.Context, image string, imagePullCredentials map[string]cliType.AuthConfig) (ValidationStatus, error) {
@@ -129,5 +402,8 @@ func getAllImages(pod *corev1.Pod) map[string]struct{} {
 	for _, c := range append(pod.Spec.Containers, pod.Spec.InitContainers...) {
 		images[c.Image] = struct{}{}
 	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		images[c.Image] = struct{}{}
+	}
 	return images
 }
@@ -3,6 +3,7 @@ package validate_test
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -141,6 +142,18 @@ func TestValidatePod(t *testing.T) {
 			expectedStatus:       validate.Invalid,
 			expectedFailedImages: []string{invalidImage},
 		},
+		{
+			name: "pod has invalid image in ephemeralContainers",
+			pod: &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{validContainer},
+					EphemeralContainers: []v1.EphemeralContainer{
+						{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger", Image: invalidImage}},
+					},
+				}},
+			expectedStatus:       validate.Invalid,
+			expectedFailedImages: []string{invalidImage},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -169,6 +182,98 @@ func TestValidatePod(t *testing.T) {
 	}
 }
 
+func TestValidatePod_Parallel(t *testing.T) {
+	testNs := "test-namespace"
+	ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: testNs}}
+
+	t.Run("invalid status takes precedence over service unavailable", func(t *testing.T) {
+		//GIVEN
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "a", Image: "a-unavailable"},
+				{Name: "b", Image: "b-invalid"},
+				{Name: "c", Image: "c-valid"},
+			}}}
+		validatorSvcMock := mocks.NewImageValidatorService(t)
+		validatorSvcMock.On("Validate", mock.Anything, "a-unavailable", mock.Anything).Return(pkg.NewUnknownResultErr(errors.New("unavailable")))
+		validatorSvcMock.On("Validate", mock.Anything, "b-invalid", mock.Anything).Return(pkg.NewValidationFailedErr(errors.New("invalid")))
+		validatorSvcMock.On("Validate", mock.Anything, "c-valid", mock.Anything).Return(nil)
+		podValidator := validate.NewPodValidator(validatorSvcMock)
+
+		//WHEN
+		result, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Invalid, result.Status)
+		require.Equal(t, []string{"a-unavailable", "b-invalid"}, result.InvalidImages)
+	})
+
+	t.Run("number of concurrent validations is bounded", func(t *testing.T) {
+		//GIVEN
+		containers := []v1.Container{}
+		for i := 0; i < 10; i++ {
+			containers = append(containers, v1.Container{Name: fmt.Sprintf("c%d", i), Image: fmt.Sprintf("image%d", i)})
+		}
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+			Spec: v1.PodSpec{Containers: containers}}
+
+		var running, maxRunning int32
+		validatorSvcMock := mocks.NewImageValidatorService(t)
+		validatorSvcMock.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(_ mock.Arguments) {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					observed := atomic.LoadInt32(&maxRunning)
+					if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			}).
+			Return(nil)
+		podValidator := validate.NewPodValidatorWithWorkers(validatorSvcMock, 3)
+
+		//WHEN
+		result, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Valid, result.Status)
+		validatorSvcMock.AssertNumberOfCalls(t, "Validate", 10)
+		require.LessOrEqual(t, atomic.LoadInt32(&maxRunning), int32(3))
+		require.Greater(t, atomic.LoadInt32(&maxRunning), int32(1))
+	})
+
+	t.Run("images not validated before context is done are unavailable", func(t *testing.T) {
+		//GIVEN
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: testNs},
+			Spec: v1.PodSpec{Containers: []v1.Container{
+				{Name: "a", Image: "a-slow"},
+				{Name: "b", Image: "b-not-validated"},
+			}}}
+		ctx, cancel := context.WithCancel(context.TODO())
+		validatorSvcMock := mocks.NewImageValidatorService(t)
+		validatorSvcMock.On("Validate", mock.Anything, "a-slow", mock.Anything).
+			Run(func(_ mock.Arguments) {
+				cancel()
+				// keep the only worker busy, so the next image waits for it
+				time.Sleep(50 * time.Millisecond)
+			}).
+			Return(nil)
+		podValidator := validate.NewPodValidatorWithWorkers(validatorSvcMock, 1)
+
+		//WHEN
+		result, err := podValidator.ValidatePod(ctx, pod, ns, emptyAuthData)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.ServiceUnavailable, result.Status)
+		require.Equal(t, []string{"b-not-validated"}, result.InvalidImages)
+	})
+}
+
 func TestNewValidatorSvc(t *testing.T) {
 	t.Run("create new validator svc", func(t *testing.T) {
 		validatorSvc := validate.NewValidatorSvcFactory().
@@ -179,3 +284,20 @@ func TestNewValidatorSvc(t *testing.T) {
 		require.Equal(t, validate.Valid, result.Status)
 	})
 }
+
+func TestNewUserValidationSvc(t *testing.T) {
+	t.Run("invalid allowed registries annotation fails", func(t *testing.T) {
+		//GIVEN
+		ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			pkg.NamespaceNotaryURLAnnotation:         "https://notary.io",
+			pkg.NamespaceAllowedRegistriesAnnotation: "registry.io,regex:registry.io/(team",
+		}}}
+
+		//WHEN
+		validatorSvc, err := validate.NewUserValidationSvc(ns, validate.NewValidatorSvcFactory())
+
+		//THEN
+		require.ErrorContains(t, err, "invalid allowed registry regex:registry.io/(team")
+		require.Nil(t, validatorSvc)
+	})
+}
@@ -0,0 +1,171 @@
+package validate
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	"github.com/kyma-project/warden/internal/helpers"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PolicyRule assigns the validator to images matching the patterns
+type PolicyRule struct {
+	Name string
+	// Images contains glob patterns, the rule matches all images when it's empty
+	Images     []string
+	Validator  PodValidator
+	StrictMode *bool
+}
+
+// Matches returns true when the image matches one of the rule patterns. The image and the patterns are normalized
+// like the allowed registries (see MatchAllowedRegistry), so `nginx` is the same as `index.docker.io/library/nginx`
+func (r PolicyRule) Matches(image string) bool {
+	if len(r.Images) == 0 {
+		return true
+	}
+	for _, pattern := range r.Images {
+		if matchNormalizedImagePattern(pattern, image) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchImagePattern matches the image against the glob pattern,
+// the `*` matches any sequence of characters except `/` and the `**` matches any sequence of characters
+func MatchImagePattern(pattern, image string) bool {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		}
+	}
+	expr.WriteString("$")
+	re, err := compileExpression(expr.String())
+	return err == nil && re.MatchString(image)
+}
+
+var _ PodValidator = &policyPodValidator{}
+
+type policyPodValidator struct {
+	rules    []PolicyRule
+	fallback func() (PodValidator, error)
+}
+
+// NewPolicyPodValidator creates validator which validates images with the first matching rule,
+// images not matching any rule are validated with the validator returned by the fallback,
+// the fallback is called only when it's needed
+func NewPolicyPodValidator(rules []PolicyRule, fallback func() (PodValidator, error)) PodValidator {
+	return &policyPodValidator{
+		rules:    rules,
+		fallback: fallback,
+	}
+}
+
+func (v *policyPodValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error) {
+	logger := helpers.LoggerFromCtx(ctx)
+
+	imagesByRule := make([]map[string]struct{}, len(v.rules))
+	unmatchedImages := map[string]struct{}{}
+	for image := range getAllImages(pod) {
+		matched := false
+		for i, rule := range v.rules {
+			if rule.Matches(image) {
+				if imagesByRule[i] == nil {
+					imagesByRule[i] = map[string]struct{}{}
+				}
+				imagesByRule[i][image] = struct{}{}
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatchedImages[image] = struct{}{}
+		}
+	}
+
+	result := ValidationResult{Status: Valid}
+	invalidImages := []string{}
+	merge := func(next ValidationResult) {
+		result.Status = mergeValidationStatus(result.Status, next.Status)
+		invalidImages = append(invalidImages, next.InvalidImages...)
+		result.Warnings = append(result.Warnings, next.Warnings...)
+		result.Images = append(result.Images, next.Images...)
+	}
+
+	for i, rule := range v.rules {
+		if len(imagesByRule[i]) == 0 {
+			continue
+		}
+		logger.Debugw("validating images with image policy", "policy", rule.Name)
+		ruleResult, err := rule.Validator.ValidatePod(ctx, podWithImages(pod, imagesByRule[i]), ns, imagePullCredentials)
+		if err != nil {
+			return ValidationResult{Status: Invalid}, err
+		}
+		merge(ruleResult)
+		result.StrictMode = mergeStrictMode(result.StrictMode, rule.StrictMode)
+	}
+
+	if len(unmatchedImages) != 0 {
+		fallback, err := v.fallback()
+		if err != nil {
+			return ValidationResult{Status: Invalid}, err
+		}
+		fallbackResult, err := fallback.ValidatePod(ctx, podWithImages(pod, unmatchedImages), ns, imagePullCredentials)
+		if err != nil {
+			return ValidationResult{Status: Invalid}, err
+		}
+		merge(fallbackResult)
+	}
+
+	sort.Strings(invalidImages)
+	sort.Strings(result.Warnings)
+	sort.Slice(result.Images, func(i, j int) bool { return result.Images[i].Image < result.Images[j].Image })
+	result.InvalidImages = invalidImages
+	return result, nil
+}
+
+// mergeStrictMode returns strict mode enabled by any of the policies
+func mergeStrictMode(current, next *bool) *bool {
+	if next == nil || (current != nil && *current) {
+		return current
+	}
+	return next
+}
+
+// podWithImages returns copy of the pod containing only containers with given images
+func podWithImages(pod *corev1.Pod, images map[string]struct{}) *corev1.Pod {
+	out := pod.DeepCopy()
+	filter := func(containers []corev1.Container) []corev1.Container {
+		var filtered []corev1.Container
+		for _, c := range containers {
+			if _, ok := images[c.Image]; ok {
+				filtered = append(filtered, c)
+			}
+		}
+		return filtered
+	}
+	out.Spec.Containers = filter(out.Spec.Containers)
+	out.Spec.InitContainers = filter(out.Spec.InitContainers)
+
+	var ephemeralContainers []corev1.EphemeralContainer
+	for _, c := range out.Spec.EphemeralContainers {
+		if _, ok := images[c.Image]; ok {
+			ephemeralContainers = append(ephemeralContainers, c)
+		}
+	}
+	out.Spec.EphemeralContainers = ephemeralContainers
+	return out
+}
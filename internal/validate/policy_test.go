@@ -0,0 +1,146 @@
+package validate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestMatchImagePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		image   string
+		want    bool
+	}{
+		{name: "exact image", pattern: "europe-docker.pkg.dev/kyma/app:v1", image: "europe-docker.pkg.dev/kyma/app:v1", want: true},
+		{name: "star matches single path segment", pattern: "europe-docker.pkg.dev/kyma/*", image: "europe-docker.pkg.dev/kyma/app:v1", want: true},
+		{name: "star doesn't match nested path", pattern: "europe-docker.pkg.dev/kyma/*", image: "europe-docker.pkg.dev/kyma/prod/app:v1", want: false},
+		{name: "double star matches nested path", pattern: "europe-docker.pkg.dev/kyma/**", image: "europe-docker.pkg.dev/kyma/prod/app:v1", want: true},
+		{name: "question mark matches single character", pattern: "ghcr.io/app:v?", image: "ghcr.io/app:v2", want: true},
+		{name: "dot is not a wildcard", pattern: "ghcr.io/app", image: "ghcrxio/app", want: false},
+		{name: "different registry", pattern: "ghcr.io/**", image: "docker.io/app:v1", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, validate.MatchImagePattern(tt.pattern, tt.image))
+		})
+	}
+}
+
+func TestPolicyRule_Matches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		image   string
+		want    bool
+	}{
+		{name: "official image with registry", pattern: "index.docker.io/library/nginx", image: "nginx", want: true},
+		{name: "official image without registry", pattern: "nginx", image: "docker.io/library/nginx:1.25", want: true},
+		{name: "pattern with tag", pattern: "ghcr.io/app:v?", image: "ghcr.io/app:v2", want: true},
+		{name: "star matches repository", pattern: "ghcr.io/kyma/*", image: "ghcr.io/kyma/app:v1", want: true},
+		{name: "star doesn't match nested repository", pattern: "ghcr.io/kyma/*", image: "ghcr.io/kyma/prod/app:v1", want: false},
+		{name: "double star matches any image", pattern: "**", image: "nginx", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := validate.PolicyRule{Images: []string{tt.pattern}}
+			require.Equal(t, tt.want, rule.Matches(tt.image))
+		})
+	}
+}
+
+func TestPolicyPodValidator(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "ghcr.io/kyma/init:v1"}},
+			Containers: []corev1.Container{
+				{Name: "app", Image: "ghcr.io/kyma/app:v1"},
+				{Name: "sidecar", Image: "docker.io/sidecar:v1"},
+			},
+		},
+	}
+
+	t.Run("images are validated with the matching policy and the fallback", func(t *testing.T) {
+		//GIVEN
+		policyImageValidator := mocks.NewImageValidatorService(t)
+		policyImageValidator.On("Validate", mock.Anything, "ghcr.io/kyma/app:v1", mock.Anything).Return(nil).Once()
+		policyImageValidator.On("Validate", mock.Anything, "ghcr.io/kyma/init:v1", mock.Anything).Return(nil).Once()
+		fallbackImageValidator := mocks.NewImageValidatorService(t)
+		fallbackImageValidator.On("Validate", mock.Anything, "docker.io/sidecar:v1", mock.Anything).
+			Return(pkg.NewValidationFailedErr(errors.New("unsigned"))).Once()
+
+		validator := validate.NewPolicyPodValidator([]validate.PolicyRule{{
+			Name:      "kyma",
+			Images:    []string{"ghcr.io/kyma/**"},
+			Validator: validate.NewPodValidator(policyImageValidator),
+		}}, func() (validate.PodValidator, error) {
+			return validate.NewPodValidator(fallbackImageValidator), nil
+		})
+
+		//WHEN
+		result, err := validator.ValidatePod(context.TODO(), pod, ns, nil)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.Invalid, result.Status)
+		require.Equal(t, []string{"docker.io/sidecar:v1"}, result.InvalidImages)
+		require.Nil(t, result.StrictMode)
+	})
+
+	t.Run("first matching policy is used", func(t *testing.T) {
+		//GIVEN
+		firstImageValidator := mocks.NewImageValidatorService(t)
+		firstImageValidator.On("Validate", mock.Anything, mock.Anything, mock.Anything).
+			Return(pkg.NewUnknownResultErr(errors.New("notary is not available"))).Times(3)
+		secondImageValidator := mocks.NewImageValidatorService(t)
+
+		validator := validate.NewPolicyPodValidator([]validate.PolicyRule{
+			{
+				Name:       "first",
+				Validator:  validate.NewPodValidator(firstImageValidator),
+				StrictMode: ptr.To(true),
+			},
+			{
+				Name:       "second",
+				Validator:  validate.NewPodValidator(secondImageValidator),
+				StrictMode: ptr.To(false),
+			},
+		}, func() (validate.PodValidator, error) {
+			return nil, errors.New("fallback should not be used")
+		})
+
+		//WHEN
+		result, err := validator.ValidatePod(context.TODO(), pod, ns, nil)
+
+		//THEN
+		require.NoError(t, err)
+		require.Equal(t, validate.ServiceUnavailable, result.Status)
+		require.Equal(t, []string{"docker.io/sidecar:v1", "ghcr.io/kyma/app:v1", "ghcr.io/kyma/init:v1"}, result.InvalidImages)
+		require.Equal(t, ptr.To(true), result.StrictMode)
+	})
+
+	t.Run("fallback error is returned", func(t *testing.T) {
+		//GIVEN
+		validator := validate.NewPolicyPodValidator(nil, func() (validate.PodValidator, error) {
+			return nil, errors.New("invalid namespace configuration")
+		})
+
+		//WHEN
+		_, err := validator.ValidatePod(context.TODO(), pod, ns, nil)
+
+		//THEN
+		require.ErrorContains(t, err, "invalid namespace configuration")
+	})
+}
@@ -0,0 +1,79 @@
+package validate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cliType "github.com/docker/cli/cli/config/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+var (
+	_ PodValidator        = &ReloadablePodValidator{}
+	_ ValidatorSvcFactory = &ReloadableValidatorSvcFactory{}
+)
+
+// ReloadablePodValidator delegates validation to the validator which can be replaced while it's in use,
+// validations in progress are finished by the replaced validator
+type ReloadablePodValidator struct {
+	mu        sync.RWMutex
+	validator PodValidator
+}
+
+func NewReloadablePodValidator(validator PodValidator) *ReloadablePodValidator {
+	return &ReloadablePodValidator{validator: validator}
+}
+
+// Reload replaces the validator used by the next validations
+func (r *ReloadablePodValidator) Reload(validator PodValidator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validator = validator
+}
+
+func (r *ReloadablePodValidator) ValidatePod(ctx context.Context, pod *corev1.Pod, ns *corev1.Namespace, imagePullCredentials map[string]cliType.AuthConfig) (ValidationResult, error) {
+	r.mu.RLock()
+	validator := r.validator
+	r.mu.RUnlock()
+	return validator.ValidatePod(ctx, pod, ns, imagePullCredentials)
+}
+
+// ReloadableValidatorSvcFactory delegates creation of validators to the factory which can be replaced while it's in use
+type ReloadableValidatorSvcFactory struct {
+	mu      sync.RWMutex
+	factory ValidatorSvcFactory
+}
+
+func NewReloadableValidatorSvcFactory(factory ValidatorSvcFactory) *ReloadableValidatorSvcFactory {
+	return &ReloadableValidatorSvcFactory{factory: factory}
+}
+
+// Reload replaces the factory used to create the next validators
+func (r *ReloadableValidatorSvcFactory) Reload(factory ValidatorSvcFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factory = factory
+}
+
+func (r *ReloadableValidatorSvcFactory) current() ValidatorSvcFactory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.factory
+}
+
+func (r *ReloadableValidatorSvcFactory) NewValidatorSvc(notaryURL string, notaryAllowedRegistries string, notaryTimeout time.Duration) PodValidator {
+	return r.current().NewValidatorSvc(notaryURL, notaryAllowedRegistries, notaryTimeout)
+}
+
+func (r *ReloadableValidatorSvcFactory) NewCosignValidatorSvc(publicKeys string, allowedRegistries string, timeout time.Duration) (PodValidator, error) {
+	return r.current().NewCosignValidatorSvc(publicKeys, allowedRegistries, timeout)
+}
+
+func (r *ReloadableValidatorSvcFactory) NewNotationValidatorSvc(allowedRegistries string, timeout time.Duration) (PodValidator, error) {
+	return r.current().NewNotationValidatorSvc(allowedRegistries, timeout)
+}
+
+func (r *ReloadableValidatorSvcFactory) NewNotationValidatorSvcWithConfig(notationConfig *NotationConfig, allowedRegistries string, timeout time.Duration) (PodValidator, error) {
+	return r.current().NewNotationValidatorSvcWithConfig(notationConfig, allowedRegistries, timeout)
+}
@@ -0,0 +1,51 @@
+package validate_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestReloadablePodValidator_ValidatePod(t *testing.T) {
+	//GIVEN
+	current := mocks.NewPodValidator(t)
+	current.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Invalid}, nil).Once()
+	reloaded := mocks.NewPodValidator(t)
+	reloaded.On("ValidatePod", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(validate.ValidationResult{Status: validate.Valid}, nil).Once()
+	validator := validate.NewReloadablePodValidator(current)
+
+	//WHEN
+	before, err := validator.ValidatePod(context.TODO(), &corev1.Pod{}, &corev1.Namespace{}, nil)
+	require.NoError(t, err)
+	validator.Reload(reloaded)
+	after, err := validator.ValidatePod(context.TODO(), &corev1.Pod{}, &corev1.Namespace{}, nil)
+	require.NoError(t, err)
+
+	//THEN
+	require.Equal(t, validate.Invalid, before.Status)
+	require.Equal(t, validate.Valid, after.Status)
+}
+
+func TestReloadableValidatorSvcFactory_NewValidatorSvc(t *testing.T) {
+	//GIVEN
+	validator := mocks.NewPodValidator(t)
+	current := mocks.NewValidatorSvcFactory(t)
+	reloaded := mocks.NewValidatorSvcFactory(t)
+	reloaded.On("NewValidatorSvc", "https://notary.io", "registry.io", time.Second).Return(validator).Once()
+	factory := validate.NewReloadableValidatorSvcFactory(current)
+
+	//WHEN
+	factory.Reload(reloaded)
+	created := factory.NewValidatorSvc("https://notary.io", "registry.io", time.Second)
+
+	//THEN
+	require.Same(t, validator, created)
+}
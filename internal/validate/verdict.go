@@ -0,0 +1,213 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+)
+
+// Reason is the machine-readable code explaining the image validation status
+type Reason string
+
+const (
+	// ReasonVerified means the image signature or trust data matches the image digest
+	ReasonVerified Reason = "Verified"
+	// ReasonAllowed means the image wasn't verified, because it's allowed by the registry list or the trust policy
+	ReasonAllowed Reason = "Allowed"
+	// ReasonDenied means the image matches the denied images rule, it's rejected without the verification
+	ReasonDenied Reason = "Denied"
+	// ReasonInvalidReference means the image name couldn't be parsed
+	ReasonInvalidReference Reason = "InvalidReference"
+	// ReasonNoTrustData means there is no trust data for the image in Notary
+	ReasonNoTrustData Reason = "NoTrustData"
+	// ReasonDigestMismatch means the trusted digest differs from the image digest in the registry
+	ReasonDigestMismatch Reason = "DigestMismatch"
+	// ReasonSignatureNotFound means there are no signatures of the image in the registry
+	ReasonSignatureNotFound Reason = "SignatureNotFound"
+	// ReasonSignatureInvalid means none of the image signatures could be verified
+	ReasonSignatureInvalid Reason = "SignatureInvalid"
+	// ReasonNoTrustPolicy means there is no trust policy applicable to the image
+	ReasonNoTrustPolicy Reason = "NoTrustPolicy"
+	// ReasonRegistryAuthFailed means the image registry rejected the credentials, or they couldn't be parsed
+	ReasonRegistryAuthFailed Reason = "RegistryAuthFailed"
+	// ReasonRegistryUnavailable means the image couldn't be fetched from the image registry
+	ReasonRegistryUnavailable Reason = "RegistryUnavailable"
+	// ReasonNotaryUnavailable means the trust data couldn't be fetched from Notary
+	ReasonNotaryUnavailable Reason = "NotaryUnavailable"
+	// ReasonValidationFailed is used for failed validations without more specific reason
+	ReasonValidationFailed Reason = "ValidationFailed"
+	// ReasonServiceUnavailable is used for unknown validation results without more specific reason
+	ReasonServiceUnavailable Reason = "ServiceUnavailable"
+)
+
+// ImageVerdict is the result of the validation of a single image
+type ImageVerdict struct {
+	Image    string           `json:"image"`
+	Status   ValidationStatus `json:"status"`
+	Reason   Reason           `json:"reason"`
+	Message  string           `json:"message,omitempty"`
+	Digest   string           `json:"digest,omitempty"`
+	Verifier string           `json:"verifier,omitempty"`
+}
+
+// ValidationReport is the validation result persisted in the pod annotation
+type ValidationReport struct {
+	Status ValidationStatus `json:"status"`
+	Images []ImageVerdict   `json:"images"`
+}
+
+// Report returns the JSON encoded validation report of the result
+func (r ValidationResult) Report() (string, error) {
+	images := append([]ImageVerdict{}, r.Images...)
+	sort.Slice(images, func(i, j int) bool { return images[i].Image < images[j].Image })
+	report, err := json.Marshal(ValidationReport{Status: r.Status, Images: images})
+	if err != nil {
+		return "", errors.Wrap(err, "while encoding validation report")
+	}
+	return string(report), nil
+}
+
+// SameReports returns true when the JSON encoded validation reports differ only in the image messages, which contain
+// volatile details, e.g. timeouts or request IDs, so the report isn't updated on each validation with the same result
+func SameReports(report, other string) bool {
+	if report == other {
+		return true
+	}
+	var decoded, otherDecoded ValidationReport
+	if json.Unmarshal([]byte(report), &decoded) != nil || json.Unmarshal([]byte(other), &otherDecoded) != nil {
+		return false
+	}
+	if decoded.Status != otherDecoded.Status || len(decoded.Images) != len(otherDecoded.Images) {
+		return false
+	}
+	for i := range decoded.Images {
+		image, otherImage := decoded.Images[i], otherDecoded.Images[i]
+		image.Message, otherImage.Message = "", ""
+		if image != otherImage {
+			return false
+		}
+	}
+	return true
+}
+
+type reasonError struct {
+	reason Reason
+	err    error
+}
+
+func (e reasonError) Error() string {
+	return e.err.Error()
+}
+
+func (e reasonError) Unwrap() error {
+	return e.err
+}
+
+// withReason attaches the reason to the error without changing its message or code
+func withReason(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return reasonError{reason: reason, err: err}
+}
+
+// newRegistryErr returns unknown result error of the failed image registry request with the reason
+// distinguishing rejected credentials from other failures
+func newRegistryErr(err error, message string) error {
+	reason := ReasonRegistryUnavailable
+	if isRegistryAuthErr(err) {
+		reason = ReasonRegistryAuthFailed
+	}
+	return withReason(reason, pkg.NewUnknownResultErr(errors.Wrap(err, message)))
+}
+
+// isRegistryAuthErr returns true when the image registry rejected the credentials
+func isRegistryAuthErr(err error) bool {
+	var transportErr *transport.Error
+	return errors.As(err, &transportErr) &&
+		(transportErr.StatusCode == http.StatusUnauthorized || transportErr.StatusCode == http.StatusForbidden)
+}
+
+// ReasonFromError returns the reason attached to the error, or the generic reason for the error code
+func ReasonFromError(err error) Reason {
+	var withReason reasonError
+	if errors.As(err, &withReason) {
+		return withReason.reason
+	}
+	if pkg.ErrorCode(err) == pkg.ValidationError {
+		return ReasonValidationFailed
+	}
+	return ReasonServiceUnavailable
+}
+
+type imageDetailsKey struct{}
+
+type imageDetailsValues struct {
+	digest   string
+	verifier string
+	reason   Reason
+}
+
+// imageDetails are reported by image validators alongside the validation result
+type imageDetails struct {
+	mu     sync.Mutex
+	values imageDetailsValues
+}
+
+func contextWithImageDetails(ctx context.Context) context.Context {
+	return context.WithValue(ctx, imageDetailsKey{}, &imageDetails{})
+}
+
+// reportImageDetails sets the non-empty values in the details collected by the context
+func reportImageDetails(ctx context.Context, values imageDetailsValues) {
+	details, ok := ctx.Value(imageDetailsKey{}).(*imageDetails)
+	if !ok {
+		return
+	}
+	details.mu.Lock()
+	defer details.mu.Unlock()
+	if values.digest != "" {
+		details.values.digest = values.digest
+	}
+	if values.verifier != "" {
+		details.values.verifier = values.verifier
+	}
+	if values.reason != "" {
+		details.values.reason = values.reason
+	}
+}
+
+func imageDetailsFromContext(ctx context.Context) imageDetailsValues {
+	details, ok := ctx.Value(imageDetailsKey{}).(*imageDetails)
+	if !ok {
+		return imageDetailsValues{}
+	}
+	details.mu.Lock()
+	defer details.mu.Unlock()
+	return details.values
+}
+
+// newImageVerdict builds the verdict from the validation status, error and the details reported by the validator
+func newImageVerdict(image string, status ValidationStatus, err error, details imageDetailsValues) ImageVerdict {
+	verdict := ImageVerdict{
+		Image:    image,
+		Status:   status,
+		Reason:   details.reason,
+		Digest:   details.digest,
+		Verifier: details.verifier,
+	}
+	if err != nil {
+		verdict.Reason = ReasonFromError(err)
+		verdict.Message = err.Error()
+	}
+	if verdict.Reason == "" {
+		verdict.Reason = ReasonVerified
+	}
+	return verdict
+}
@@ -0,0 +1,188 @@
+package validate_test
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/kyma-project/warden/internal/validate"
+	"github.com/kyma-project/warden/internal/validate/mocks"
+	"github.com/kyma-project/warden/pkg"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/theupdateframework/notary/client"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidatePod_ImageVerdicts(t *testing.T) {
+	//GIVEN
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	registryHost := strings.TrimPrefix(server.URL, "http://")
+	trusted := pushRandomImage(t, registryHost, "verdicts:trusted")
+	mismatched := pushRandomImage(t, registryHost, "verdicts:mismatched")
+
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer authServer.Close()
+	privateImage := fmt.Sprintf("%s/verdicts:private", strings.TrimPrefix(authServer.URL, "http://"))
+
+	notaryClient := &mocks.NotaryRepoClient{}
+	notaryClient.On("GetTargetByName", "trusted").Return(notaryTarget(t, trusted.DigestStr()), nil)
+	notaryClient.On("GetTargetByName", "mismatched").Return(notaryTarget(t, trusted.DigestStr()), nil)
+	notaryClient.On("GetTargetByName", "private").Return(notaryTarget(t, trusted.DigestStr()), nil)
+	notaryClient.On("GetTargetByName", "untrusted").
+		Return(nil, errors.New("does not have trust data for untrusted"))
+	notaryClient.On("GetTargetByName", "unavailable").
+		Return(nil, errors.New("connection refused"))
+	f := &mocks.RepoFactory{}
+	f.On("NewRepoClient", mock.Anything, mock.Anything).Return(notaryClient, nil)
+	cfg := validate.ServiceConfig{
+		NotaryConfig:      validate.NotaryConfig{Url: "verdicts-notary"},
+		AllowedRegistries: []string{"allowed.io/"},
+	}
+	podValidator := validate.NewPodValidator(validate.NewImageValidator(&cfg, f))
+
+	tests := []struct {
+		image    string
+		expected validate.ImageVerdict
+	}{
+		{
+			image:    trusted.Context().Tag("trusted").String(),
+			expected: validate.ImageVerdict{Status: validate.Valid, Reason: validate.ReasonVerified, Digest: trusted.DigestStr()},
+		},
+		{
+			image:    "allowed.io/app:1.0",
+			expected: validate.ImageVerdict{Status: validate.Valid, Reason: validate.ReasonAllowed},
+		},
+		{
+			image:    "Invalid::image",
+			expected: validate.ImageVerdict{Status: validate.Invalid, Reason: validate.ReasonInvalidReference},
+		},
+		{
+			image:    mismatched.Context().Tag("mismatched").String(),
+			expected: validate.ImageVerdict{Status: validate.Invalid, Reason: validate.ReasonDigestMismatch, Digest: mismatched.DigestStr()},
+		},
+		{
+			image:    trusted.Context().Tag("untrusted").String(),
+			expected: validate.ImageVerdict{Status: validate.Invalid, Reason: validate.ReasonNoTrustData},
+		},
+		{
+			image:    trusted.Context().Tag("unavailable").String(),
+			expected: validate.ImageVerdict{Status: validate.ServiceUnavailable, Reason: validate.ReasonNotaryUnavailable},
+		},
+		{
+			image:    privateImage,
+			expected: validate.ImageVerdict{Status: validate.ServiceUnavailable, Reason: validate.ReasonRegistryAuthFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.expected.Reason), func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "verdicts"},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "container", Image: tt.image}}}}
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "verdicts"}}
+
+			//WHEN
+			result, err := podValidator.ValidatePod(context.TODO(), pod, ns, emptyAuthData)
+
+			//THEN
+			require.NoError(t, err)
+			require.Len(t, result.Images, 1)
+			verdict := result.Images[0]
+			require.Equal(t, tt.image, verdict.Image)
+			require.Equal(t, tt.expected.Status, verdict.Status)
+			require.Equal(t, tt.expected.Reason, verdict.Reason)
+			require.Equal(t, tt.expected.Digest, verdict.Digest)
+			require.Equal(t, pkg.VerifierNotary, verdict.Verifier)
+			if verdict.Status == validate.Valid {
+				require.Empty(t, verdict.Message)
+			} else {
+				require.NotEmpty(t, verdict.Message)
+			}
+		})
+	}
+}
+
+func TestValidationResult_Report(t *testing.T) {
+	//GIVEN
+	result := validate.ValidationResult{
+		Status: validate.Invalid,
+		Images: []validate.ImageVerdict{
+			{Image: "registry.io/b:1.0", Status: validate.Invalid, Reason: validate.ReasonNoTrustData, Message: "no trust data", Verifier: "notary"},
+			{Image: "registry.io/a:1.0", Status: validate.Valid, Reason: validate.ReasonVerified, Digest: "sha256:abc", Verifier: "notary"},
+		},
+	}
+
+	//WHEN
+	report, err := result.Report()
+
+	//THEN
+	require.NoError(t, err)
+	require.JSONEq(t, `{"status":"Invalid","images":[
+		{"image":"registry.io/a:1.0","status":"Valid","reason":"Verified","digest":"sha256:abc","verifier":"notary"},
+		{"image":"registry.io/b:1.0","status":"Invalid","reason":"NoTrustData","message":"no trust data","verifier":"notary"}]}`, report)
+}
+
+func TestSameReports(t *testing.T) {
+	report := `{"status":"ServiceUnavailable","images":[
+		{"image":"registry.io/a:1.0","status":"ServiceUnavailable","reason":"NotaryUnavailable","message":"request 1 timed out"}]}`
+	tests := []struct {
+		name     string
+		other    string
+		expected bool
+	}{
+		{
+			name: "different message",
+			other: `{"status":"ServiceUnavailable","images":[
+				{"image":"registry.io/a:1.0","status":"ServiceUnavailable","reason":"NotaryUnavailable","message":"request 2 timed out"}]}`,
+			expected: true,
+		},
+		{
+			name: "different reason",
+			other: `{"status":"ServiceUnavailable","images":[
+				{"image":"registry.io/a:1.0","status":"ServiceUnavailable","reason":"RegistryUnavailable","message":"request 1 timed out"}]}`,
+			expected: false,
+		},
+		{
+			name:     "different images",
+			other:    `{"status":"ServiceUnavailable","images":[]}`,
+			expected: false,
+		},
+		{
+			name:     "missing report",
+			other:    "",
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, validate.SameReports(report, tt.other))
+		})
+	}
+}
+
+func TestReasonFromError(t *testing.T) {
+	t.Run("validation error without reason", func(t *testing.T) {
+		require.Equal(t, validate.ReasonValidationFailed, validate.ReasonFromError(pkg.NewValidationFailedErr(errors.New("failed"))))
+	})
+	t.Run("unknown result error without reason", func(t *testing.T) {
+		require.Equal(t, validate.ReasonServiceUnavailable, validate.ReasonFromError(pkg.NewUnknownResultErr(errors.New("unknown"))))
+	})
+}
+
+func notaryTarget(t *testing.T, digest string) *client.TargetWithRole {
+	hash, err := hex.DecodeString(strings.TrimPrefix(digest, "sha256:"))
+	require.NoError(t, err)
+	return &client.TargetWithRole{Target: client.Target{Name: "ignored",
+		Hashes: map[string][]byte{"ignored": hash},
+		Length: 1}}
+}
@@ -0,0 +1,41 @@
+package validate
+
+import (
+	"context"
+	"sync"
+)
+
+type warningsKey struct{}
+
+type warningCollector struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+// ContextWithWarnings returns context collecting warnings reported by image validators
+func ContextWithWarnings(ctx context.Context) context.Context {
+	return context.WithValue(ctx, warningsKey{}, &warningCollector{})
+}
+
+// AddWarning reports warning of the image validation, which doesn't change its result,
+// it's dropped when the context doesn't collect warnings
+func AddWarning(ctx context.Context, warning string) {
+	collector, ok := ctx.Value(warningsKey{}).(*warningCollector)
+	if !ok {
+		return
+	}
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.warnings = append(collector.warnings, warning)
+}
+
+// WarningsFromContext returns warnings collected by the context
+func WarningsFromContext(ctx context.Context) []string {
+	collector, ok := ctx.Value(warningsKey{}).(*warningCollector)
+	if !ok {
+		return nil
+	}
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	return append([]string{}, collector.warnings...)
+}
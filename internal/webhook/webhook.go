@@ -11,6 +11,8 @@ import (
 	"github.com/kyma-project/warden/pkg"
 	"github.com/pkg/errors"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -26,11 +28,17 @@ const (
 
 	DefaultingWebhookName = "defaulting.webhook.warden.kyma-project.io"
 	ValidationWebhookName = "validation.webhook.warden.kyma-project.io"
+	WorkloadsWebhookName  = "workloads.validation.webhook.warden.kyma-project.io"
 
 	ValidationWebhookTimeout = 1
 	MutationWebhookTimeout   = 10
+	// WorkloadsWebhookTimeout is longer than ValidationWebhookTimeout, because workload images are verified during the request
+	WorkloadsWebhookTimeout = 10
 
 	PodValidationPath = "/validation/pods"
+
+	// podEphemeralContainersResource is updated by `kubectl debug`, its images have to be validated as well
+	podEphemeralContainersResource = "pods/ephemeralcontainers"
 )
 
 func EnsureWebhookConfigurationFor(ctx context.Context, client ctlrclient.Client, config WebhookConfig, wt WebHookType) error {
@@ -116,7 +124,7 @@ func getFunctionMutatingWebhookCfg(config WebhookConfig) admissionregistrationv1
 						corev1.GroupName,
 					},
 					APIVersions: []string{corev1.SchemeGroupVersion.Version},
-					Resources:   []string{string(corev1.ResourcePods)},
+					Resources:   []string{string(corev1.ResourcePods), podEphemeralContainersResource},
 					Scope:       &scope,
 				},
 				Operations: []admissionregistrationv1.OperationType{
@@ -179,7 +187,7 @@ func createValidatingWebhookConfiguration(config WebhookConfig) *admissionregist
 								corev1.GroupName,
 							},
 							APIVersions: []string{corev1.SchemeGroupVersion.Version},
-							Resources:   []string{string(corev1.ResourcePods)},
+							Resources:   []string{string(corev1.ResourcePods), podEphemeralContainersResource},
 							Scope:       &scope,
 						},
 						Operations: []admissionregistrationv1.OperationType{
@@ -205,6 +213,73 @@ func createValidatingWebhookConfiguration(config WebhookConfig) *admissionregist
 					},
 				},
 			},
+			getWorkloadsValidatingWebhookCfg(config),
+		},
+	}
+}
+
+func getWorkloadsValidatingWebhookCfg(config WebhookConfig) admissionregistrationv1.ValidatingWebhook {
+	failurePolicy := admissionregistrationv1.Ignore
+	matchPolicy := admissionregistrationv1.Equivalent
+	scope := admissionregistrationv1.NamespacedScope
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	operations := []admissionregistrationv1.OperationType{
+		admissionregistrationv1.Create,
+		admissionregistrationv1.Update,
+	}
+
+	return admissionregistrationv1.ValidatingWebhook{
+		Name: WorkloadsWebhookName,
+		AdmissionReviewVersions: []string{
+			"v1beta1",
+			"v1",
+		},
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			CABundle: config.CABundel,
+			Service: &admissionregistrationv1.ServiceReference{
+				Namespace: config.ServiceNamespace,
+				Name:      config.ServiceName,
+				Path:      ptr.To[string](admission.WorkloadValidationPath),
+				Port:      ptr.To[int32](443),
+			},
+		},
+		FailurePolicy: &failurePolicy,
+		MatchPolicy:   &matchPolicy,
+		Rules: []admissionregistrationv1.RuleWithOperations{
+			{
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{appsv1.GroupName},
+					APIVersions: []string{appsv1.SchemeGroupVersion.Version},
+					Resources:   []string{"deployments", "statefulsets", "daemonsets", "replicasets"},
+					Scope:       &scope,
+				},
+				Operations: operations,
+			},
+			{
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{batchv1.GroupName},
+					APIVersions: []string{batchv1.SchemeGroupVersion.Version},
+					Resources:   []string{"jobs", "cronjobs"},
+					Scope:       &scope,
+				},
+				Operations: operations,
+			},
+		},
+		SideEffects:    &sideEffects,
+		TimeoutSeconds: ptr.To[int32](WorkloadsWebhookTimeout),
+		NamespaceSelector: &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				// match system and user values in pkg.NamespaceValidationLabel
+				{
+					Key:      pkg.NamespaceValidationLabel,
+					Operator: metav1.LabelSelectorOpIn,
+					Values: []string{
+						pkg.NamespaceValidationEnabled,
+						pkg.NamespaceValidationSystem,
+						pkg.NamespaceValidationUser,
+					},
+				},
+			},
 		},
 	}
 }
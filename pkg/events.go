@@ -0,0 +1,19 @@
+package pkg
+
+// Reasons of Kubernetes events emitted by Warden
+const (
+	// EventReasonValidationSucceeded is reported on the pod when all its images were verified
+	EventReasonValidationSucceeded = "ValidationSucceeded"
+	// EventReasonImageSignatureInvalid is reported on the pod when any of its images didn't pass the verification
+	EventReasonImageSignatureInvalid = "ImageSignatureInvalid"
+	// EventReasonNotaryUnavailable is reported on the pod when its images couldn't be verified because the verification service is unavailable
+	EventReasonNotaryUnavailable = "NotaryUnavailable"
+	// EventReasonValidationLost is reported on the pod when its images didn't pass the revalidation after they were verified successfully
+	EventReasonValidationLost = "ValidationLost"
+	// EventReasonPodEvictionFailed is reported on the pod when it lost its valid status, but it couldn't be evicted
+	EventReasonPodEvictionFailed = "PodEvictionFailed"
+	// EventReasonPodsValidationScheduled is reported on the namespace when its pods were labeled for the validation
+	EventReasonPodsValidationScheduled = "PodsValidationScheduled"
+	// EventReasonPodsLabelingFailed is reported on the namespace when some of its pods couldn't be labeled for the validation
+	EventReasonPodsLabelingFailed = "PodsLabelingFailed"
+)
@@ -10,6 +10,45 @@ const (
 	NamespaceAllowedRegistriesAnnotation = "namespaces.warden.kyma-project.io/allowed-registries"
 	NamespaceNotaryTimeoutAnnotation     = "namespaces.warden.kyma-project.io/notary-timeout"
 	NamespaceStrictModeAnnotation        = "namespaces.warden.kyma-project.io/strict-mode"
+	NamespaceVerifierAnnotation          = "namespaces.warden.kyma-project.io/verifier"
+	NamespaceCosignPublicKeysAnnotation  = "namespaces.warden.kyma-project.io/cosign-public-keys"
+	NamespaceEnforcementModeAnnotation   = "namespaces.warden.kyma-project.io/enforcement-mode"
+	NamespaceDigestPinningAnnotation     = "namespaces.warden.kyma-project.io/digest-pinning"
+	NamespaceDeniedImagesAnnotation      = "namespaces.warden.kyma-project.io/denied-images"
+)
+
+const (
+	// EnforcementModeEnforce rejects pods with images which didn't pass the validation
+	EnforcementModeEnforce = "enforce"
+	// EnforcementModeAudit admits all pods, images which would be rejected are reported in admission warnings
+	EnforcementModeAudit = "audit"
+)
+
+const (
+	// DigestPinningDisabled keeps pod images as they are
+	DigestPinningDisabled = "disabled"
+	// DigestPinningDigest replaces verified image tags with their digests
+	DigestPinningDigest = "digest"
+	// DigestPinningTagDigest adds digests to verified image tags
+	DigestPinningTagDigest = "tag-digest"
+)
+
+const (
+	// RevalidationActionNone only labels pods which fail the revalidation as failed
+	RevalidationActionNone = "none"
+	// RevalidationActionAnnotate annotates pods which fail the revalidation with the time they lost the valid status
+	RevalidationActionAnnotate = "annotate"
+	// RevalidationActionEvict evicts pods which fail the revalidation, so they are recreated and validated by the admission
+	RevalidationActionEvict = "evict"
+)
+
+const (
+	// VerifierNotary verifies images using Notary v1 (TUF) trust data
+	VerifierNotary = "notary"
+	// VerifierCosign verifies images using cosign signatures stored in the image registry
+	VerifierCosign = "cosign"
+	// VerifierNotation verifies images using Notary Project (notation) signatures stored in the image registry
+	VerifierNotation = "notation"
 )
 
 const (
@@ -22,3 +61,9 @@ const (
 	// This value will go through
 	ValidationStatusFailed = "failed"
 )
+
+// PodValidationLostAnnotation contains the time when the pod failed the revalidation after it was validated successfully
+const PodValidationLostAnnotation = "pods.warden.kyma-project.io/validation-lost"
+
+// PodValidationReportAnnotation contains JSON encoded validation verdicts of all pod images
+const PodValidationReportAnnotation = "pods.warden.kyma-project.io/validation-report"